@@ -0,0 +1,29 @@
+package app
+
+import (
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+)
+
+// NewCache returns nil when caching is disabled
+func NewCache(cacheConfig config.CacheConfig) cache.Cache {
+	switch cacheConfig.Backend {
+	case "memory":
+		return cache.NewLRUCache(cacheConfig.Size)
+	case "redis":
+		redisCache := cache.NewRedisCache(cacheConfig.RedisAddress, cacheConfig.RedisPassword, cacheConfig.RedisDB, cacheConfig.RedisPoolSize)
+		redisCache.DialTimeout = cacheConfig.RedisDialTimeout
+		return redisCache
+	default:
+		return nil
+	}
+}
+
+// NewIdempotencyStore shares Redis with the category cache, so keys are seen by
+// every instance, otherwise the keys are kept in memory
+func NewIdempotencyStore(cacheConfig config.CacheConfig, idempotencyConfig config.IdempotencyConfig) cache.Cache {
+	if cacheConfig.Backend == "redis" {
+		return NewCache(cacheConfig)
+	}
+	return cache.NewLRUCache(idempotencyConfig.Size)
+}
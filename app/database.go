@@ -3,32 +3,83 @@ package app
 import (
 	"database/sql"
 	"fmt"
-	"os"
-	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
 )
 
-func NewDB() (*sql.DB, error) {
-
-	// get all env variables
-	dbUsername := os.Getenv("DB_USERNAME")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
+func NewDB(databaseConfig config.DatabaseConfig) (*sql.DB, error) {
 
 	// connect to the database
-	dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v", dbUsername, dbPassword, dbHost, dbPort, dbName)
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = databaseConfig.Username
+	mysqlConfig.Passwd = databaseConfig.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = fmt.Sprintf("%v:%v", databaseConfig.Host, databaseConfig.Port)
+	mysqlConfig.DBName = databaseConfig.Name
+	mysqlConfig.Timeout = databaseConfig.DialTimeout
+	mysqlConfig.ReadTimeout = databaseConfig.ReadTimeout
+	mysqlConfig.WriteTimeout = databaseConfig.WriteTimeout
+	mysqlConfig.ParseTime = true
+
+	return openDB(mysqlConfig.FormatDSN(), databaseConfig)
+}
+
+// NewReplicaDB returns nil when no replica is configured, the replica shares the pool settings of the primary.
+// Times are parsed like on the primary, and its timeouts apply unless the DSN sets its own.
+func NewReplicaDB(databaseConfig config.DatabaseConfig) (*sql.DB, error) {
+	if databaseConfig.ReplicaDSN == "" {
+		return nil, nil
+	}
+
+	mysqlConfig, err := mysql.ParseDSN(databaseConfig.ReplicaDSN)
+	if err != nil {
+		return nil, err
+	}
+	if mysqlConfig.Timeout == 0 {
+		mysqlConfig.Timeout = databaseConfig.DialTimeout
+	}
+	if mysqlConfig.ReadTimeout == 0 {
+		mysqlConfig.ReadTimeout = databaseConfig.ReadTimeout
+	}
+	if mysqlConfig.WriteTimeout == 0 {
+		mysqlConfig.WriteTimeout = databaseConfig.WriteTimeout
+	}
+	mysqlConfig.ParseTime = true
+
+	return openDB(mysqlConfig.FormatDSN(), databaseConfig)
+}
+
+func openDB(dsn string, databaseConfig config.DatabaseConfig) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
 
 	// set the idle conns and idle durations
-	db.SetMaxIdleConns(5)
-	db.SetMaxOpenConns(20)
-	db.SetConnMaxIdleTime(10 * time.Minute)
-	db.SetConnMaxLifetime(1 * time.Hour)
+	db.SetMaxIdleConns(databaseConfig.MaxIdleConns)
+	db.SetMaxOpenConns(databaseConfig.MaxOpenConns)
+	db.SetConnMaxIdleTime(databaseConfig.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(databaseConfig.ConnMaxLifetime)
 	return db, nil
 }
+
+// NewTransactionManager runs reads on the replica when one is given, writes always go to db
+func NewTransactionManager(db *sql.DB, replica *database.Replica, databaseConfig config.DatabaseConfig) *database.TransactionManager {
+	transactionManager := database.NewTransactionManager(db, replica)
+	transactionManager.MaxRetries = databaseConfig.MaxRetries
+	transactionManager.RetryBackoff = databaseConfig.RetryBackoff
+
+	switch databaseConfig.IsolationLevel {
+	case "read_uncommitted":
+		transactionManager.Isolation = sql.LevelReadUncommitted
+	case "read_committed":
+		transactionManager.Isolation = sql.LevelReadCommitted
+	case "repeatable_read":
+		transactionManager.Isolation = sql.LevelRepeatableRead
+	case "serializable":
+		transactionManager.Isolation = sql.LevelSerializable
+	}
+	return transactionManager
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"crypto/tls"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/proto/categorypb"
+	"github.com/rozanlaudzai/go-mysql-restful-api/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// NewGRPCServer serves the category service behind the auth check, rate limit and deadlines
+// of the REST API, tlsConfig is nil when TLS is disabled
+func NewGRPCServer(categoryServer *rpc.CategoryServer, auth *middleware.AuthMiddleware, rateLimit *middleware.RateLimitMiddleware, timeout middleware.TimeoutConfig, tlsConfig *tls.Config) *grpc.Server {
+	authInterceptor := rpc.NewAuthInterceptor(auth)
+	rateLimitInterceptor := rpc.NewRateLimitInterceptor(rateLimit)
+	timeoutInterceptor := rpc.NewTimeoutInterceptor(timeout)
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(rpc.RecoverUnary, authInterceptor.Unary, rateLimitInterceptor.Unary, timeoutInterceptor.Unary),
+		grpc.ChainStreamInterceptor(rpc.RecoverStream, authInterceptor.Stream, rateLimitInterceptor.Stream),
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(options...)
+	categorypb.RegisterCategoryServiceServer(server, categoryServer)
+	return server
+}
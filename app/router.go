@@ -1,20 +1,415 @@
 package app
 
 import (
+	"net/http"
+	"slices"
+	"strings"
+
 	"github.com/julienschmidt/httprouter"
 	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
 	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/openapi"
 )
 
-func NewRouter(categoryController controller.CategoryController) *httprouter.Router {
+// Route is an endpoint together with its OpenAPI description, the
+// document served at /openapi.json is generated from the registered routes
+type Route struct {
+	openapi.Operation
+	Handle httprouter.Handle
+}
+
+// errors every endpoint may return, the middlewares and the panic handler produce them,
+// a missing or malformed tenant is a 400
+var commonErrors = []int{
+	http.StatusBadRequest,
+	http.StatusUnauthorized,
+	http.StatusForbidden,
+	http.StatusNotAcceptable,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// errors of endpoints reading a request body
+var bodyErrors = []int{
+	http.StatusBadRequest,
+	http.StatusRequestEntityTooLarge,
+	http.StatusUnsupportedMediaType,
+}
+
+// every operation works on the categories of one tenant
+var tenantParameter = openapi.Parameter{
+	Name:        "X-Tenant-ID",
+	In:          "header",
+	Description: "Tenant of the categories, only needed when the credentials are not tied to one and there is no default tenant. Credentials tied to a tenant may only name their own.",
+	Schema:      &openapi.Schema{Type: "string", Pattern: "^[a-z0-9][a-z0-9_-]{0,63}$"},
+}
+
+var categoryIdParameter = openapi.Parameter{
+	Name:        "categoryId",
+	In:          "path",
+	Description: "Unique identifier of the category",
+	Schema:      &openapi.Schema{Type: "integer", Minimum: openapi.Float(1), Example: 1},
+}
+
+var slugParameter = openapi.Parameter{
+	Name:        "slug",
+	In:          "path",
+	Description: "Slug of the category, derived from its name unless set",
+	Schema:      &openapi.Schema{Type: "string", MaxLength: openapi.Int(200), Example: "electronics"},
+}
+
+// filters of the category list, see web.CategoryListRequest
+var categoryListParameters = []openapi.Parameter{
+	{
+		Name:        "type",
+		In:          "query",
+		Description: "Only categories of this type, empty for the categories without one",
+		Schema:      &openapi.Schema{Type: "string", MaxLength: openapi.Int(64), Example: "product"},
+	},
+	{
+		Name:        "is_active",
+		In:          "query",
+		Description: "Only active or only inactive categories",
+		Schema:      &openapi.Schema{Type: "boolean"},
+	},
+	{
+		Name:        "created_after",
+		In:          "query",
+		Description: "Only categories created at or after this time",
+		Schema:      &openapi.Schema{Type: "string", Format: "date-time", Example: "2026-01-02T15:04:05Z"},
+	},
+	{
+		Name:        "created_before",
+		In:          "query",
+		Description: "Only categories created before this time",
+		Schema:      &openapi.Schema{Type: "string", Format: "date-time", Example: "2026-01-02T15:04:05Z"},
+	},
+	{
+		Name:        "updated_after",
+		In:          "query",
+		Description: "Only categories last updated at or after this time",
+		Schema:      &openapi.Schema{Type: "string", Format: "date-time", Example: "2026-01-02T15:04:05Z"},
+	},
+	{
+		Name:        "updated_before",
+		In:          "query",
+		Description: "Only categories last updated before this time",
+		Schema:      &openapi.Schema{Type: "string", Format: "date-time", Example: "2026-01-02T15:04:05Z"},
+	},
+	{
+		Name:        "attr.color",
+		In:          "query",
+		Description: "Only categories whose attribute has one of the values, compared as text. Any attribute path works the same way, such as attr.size.width, repeat the parameter to allow several values. Up to 10 attribute filters, keys are letters, digits, _ and -.",
+		Schema:      &openapi.Schema{Type: "string", Example: "black"},
+	},
+}
+
+var categoryTypeParameter = openapi.Parameter{
+	Name:        "type",
+	In:          "path",
+	Description: "Category type the schema applies to",
+	Schema:      &openapi.Schema{Type: "string", MaxLength: openapi.Int(64), Example: "product"},
+}
+
+// reads translate the category names, see CategoryServiceLocalized
+var acceptLanguageParameter = openapi.Parameter{
+	Name:        "Accept-Language",
+	In:          "header",
+	Description: "Preferred locales, names are translated into the first one having a translation and the names as written are used otherwise",
+	Schema:      &openapi.Schema{Type: "string", Example: "fr-CA, fr;q=0.9, en;q=0.5"},
+}
+
+var localeParameter = openapi.Parameter{
+	Name:        "locale",
+	In:          "path",
+	Description: "BCP 47 language tag of the translation, stored in its canonical form",
+	Schema:      &openapi.Schema{Type: "string", MaxLength: openapi.Int(35), Example: "fr"},
+}
+
+func CategoryRoutes(categoryController controller.CategoryController, categoryTranslationController controller.CategoryTranslationController, categoryAttributeSchemaController controller.CategoryAttributeSchemaController) []Route {
+	tags := []string{"Categories"}
+	routes := []Route{
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodGet,
+				Path:        "/api/categories",
+				ID:          "getAllCategories",
+				Summary:     "Get all categories",
+				Description: "Retrieves the categories ordered by sort_order, then by id, optionally filtered by visibility and timestamps. Returns an empty array if no categories match. Names are translated according to Accept-Language.",
+				Tags:        tags,
+				Parameters:  append(slices.Clone(categoryListParameters), acceptLanguageParameter),
+				Response:    []web.CategoryResponse{},
+				Errors:      append([]int{http.StatusBadRequest}, commonErrors...),
+			},
+			Handle: categoryController.FindAll,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodGet,
+				Path:        "/api/categories/:categoryId",
+				ID:          "getCategoryById",
+				Summary:     "Get category by ID",
+				Description: "Retrieves a specific category by its unique identifier. Returns 404 if the category does not exist.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter, acceptLanguageParameter},
+				Response:    web.CategoryResponse{},
+				Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+			},
+			Handle: categoryController.FindById,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodGet,
+				Path:        "/api/categories/by-slug/:slug",
+				ID:          "getCategoryBySlug",
+				Summary:     "Get category by slug",
+				Description: "Retrieves a category by its slug. A slug the category had before, or another spelling of its slug such as an uppercase one, answers 301 with the current slug in Location. Returns 404 if no category has or had the slug. Names are translated according to Accept-Language.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{slugParameter, acceptLanguageParameter},
+				Response:    web.CategoryResponse{},
+				Redirect:    http.StatusMovedPermanently,
+				Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+			},
+			Handle: categoryController.FindBySlug,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodPost,
+				Path:        "/api/categories",
+				ID:          "createCategory",
+				Summary:     "Create a new category",
+				Description: "Creates a new category with the provided name and details. The category is active unless is_active is false, created_at and updated_at are set by the server. Requests with an Idempotency-Key header can be retried safely, the first response is replayed.",
+				Tags:        tags,
+				Parameters: []openapi.Parameter{{
+					Name:        "Idempotency-Key",
+					In:          "header",
+					Description: "Key making retries of this request return the first response",
+					Schema:      &openapi.Schema{Type: "string", MaxLength: openapi.Int(255)},
+				}},
+				Request:  web.CategoryCreateRequest{},
+				Response: web.CategoryResponse{},
+				Errors:   append(append([]int{http.StatusConflict, http.StatusUnprocessableEntity}, bodyErrors...), commonErrors...),
+			},
+			Handle: categoryController.Create,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodPut,
+				Path:        "/api/categories/:categoryId",
+				ID:          "updateCategory",
+				Summary:     "Update category by ID",
+				Description: "Updates an existing category with the provided name, details left out keep their value. updated_at is set by the server. The category ID is taken from the path. Returns 404 if the category does not exist and 409 if another category of the tenant has the name.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter},
+				Request:     web.CategoryUpdateRequest{},
+				Response:    web.CategoryResponse{},
+				Errors:      append(append([]int{http.StatusNotFound, http.StatusConflict}, bodyErrors...), commonErrors...),
+			},
+			Handle: categoryController.Update,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodPost,
+				Path:        "/api/categories/:categoryId/move",
+				ID:          "moveCategory",
+				Summary:     "Move a category before or after another",
+				Description: "Places the category right before before_id or right after after_id, exactly one of them is required. The category gets a sort_order between its new neighbours, the other categories of the tenant are renumbered when there is no room left. Returns 404 if either category does not exist.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter},
+				Request:     web.CategoryMoveRequest{},
+				Response:    web.CategoryResponse{},
+				Errors:      append(append([]int{http.StatusNotFound}, bodyErrors...), commonErrors...),
+			},
+			Handle: categoryController.Move,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodDelete,
+				Path:        "/api/categories/:categoryId",
+				ID:          "deleteCategory",
+				Summary:     "Delete category by ID",
+				Description: "Deletes a category by its unique identifier. Returns 404 if the category does not exist.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter},
+				Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+			},
+			Handle: categoryController.DeleteById,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodGet,
+				Path:        "/api/categories/:categoryId/translations",
+				ID:          "getCategoryTranslations",
+				Summary:     "Get the translations of a category",
+				Description: "Lists the names of a category in other locales, ordered by locale. Returns 404 if the category does not exist.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter},
+				Response:    []web.CategoryTranslationResponse{},
+				Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+			},
+			Handle: categoryTranslationController.FindAll,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodPut,
+				Path:        "/api/categories/:categoryId/translations/:locale",
+				ID:          "saveCategoryTranslation",
+				Summary:     "Create or replace a translation",
+				Description: "Sets the name of a category in a locale. Returns 404 if the category does not exist.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter, localeParameter},
+				Request:     web.CategoryTranslationSaveRequest{},
+				Response:    web.CategoryTranslationResponse{},
+				Errors:      append(append([]int{http.StatusNotFound}, bodyErrors...), commonErrors...),
+			},
+			Handle: categoryTranslationController.Save,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:      http.MethodDelete,
+				Path:        "/api/categories/:categoryId/translations/:locale",
+				ID:          "deleteCategoryTranslation",
+				Summary:     "Delete a translation",
+				Description: "Deletes the name of a category in a locale. Returns 404 if there is no such translation.",
+				Tags:        tags,
+				Parameters:  []openapi.Parameter{categoryIdParameter, localeParameter},
+				Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+			},
+			Handle: categoryTranslationController.Delete,
+		},
+	}
+
+	// the schema of the tenant and the ones of the types have the same operations
+	for _, schemaRoute := range []struct {
+		path       string
+		id         string
+		owner      string
+		parameters []openapi.Parameter
+	}{
+		{"/api/category-schema", "TenantAttributeSchema", "the tenant", nil},
+		{"/api/category-types/:type/schema", "CategoryTypeAttributeSchema", "a category type", []openapi.Parameter{categoryTypeParameter}},
+	} {
+		routes = append(routes,
+			Route{
+				Operation: openapi.Operation{
+					Method:      http.MethodGet,
+					Path:        schemaRoute.path,
+					ID:          "get" + schemaRoute.id,
+					Summary:     "Get the attribute schema of " + schemaRoute.owner,
+					Description: "Retrieves the JSON Schema of " + schemaRoute.owner + ". The schema of the tenant applies to the categories whose type has none. Returns 404 if there is none.",
+					Tags:        tags,
+					Parameters:  schemaRoute.parameters,
+					Response:    web.CategoryAttributeSchemaResponse{},
+					Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+				},
+				Handle: categoryAttributeSchemaController.FindByType,
+			},
+			Route{
+				Operation: openapi.Operation{
+					Method:      http.MethodPut,
+					Path:        schemaRoute.path,
+					ID:          "save" + schemaRoute.id,
+					Summary:     "Create or replace the attribute schema of " + schemaRoute.owner,
+					Description: "Sets the JSON Schema of " + schemaRoute.owner + ", in the dialect of OpenAPI 3.0 schemas. The schema of the tenant applies to the categories whose type has none. Categories are checked against it when they are created and when their type or attributes change. Returns 400 if the schema is invalid.",
+					Tags:        tags,
+					Parameters:  schemaRoute.parameters,
+					Request:     web.CategoryAttributeSchemaSaveRequest{},
+					Response:    web.CategoryAttributeSchemaResponse{},
+					Errors:      append(slices.Clone(bodyErrors), commonErrors...),
+				},
+				Handle: categoryAttributeSchemaController.Save,
+			},
+			Route{
+				Operation: openapi.Operation{
+					Method:      http.MethodDelete,
+					Path:        schemaRoute.path,
+					ID:          "delete" + schemaRoute.id,
+					Summary:     "Delete the attribute schema of " + schemaRoute.owner,
+					Description: "Deletes the JSON Schema of " + schemaRoute.owner + ". Returns 404 if there is none.",
+					Tags:        tags,
+					Parameters:  schemaRoute.parameters,
+					Errors:      append([]int{http.StatusNotFound}, commonErrors...),
+				},
+				Handle: categoryAttributeSchemaController.Delete,
+			},
+		)
+	}
+	return routes
+}
+
+// NewOpenAPIDocument describes the routes, it is checked in as apispec.json
+func NewOpenAPIDocument(routes []Route) openapi.Document {
+	operations := make([]openapi.Operation, 0, len(routes))
+	for _, route := range routes {
+		operation := route.Operation
+		operation.Parameters = append(slices.Clone(operation.Parameters), tenantParameter)
+		operations = append(operations, operation)
+	}
+
+	return openapi.Generate(openapi.Document{
+		OpenAPI: "3.0.3",
+		Info: openapi.Info{
+			Title:       "Category RESTful API",
+			Description: "A RESTful API for category management built with Go and MySQL. Request and response bodies are JSON by default, XML, MessagePack and CBOR are negotiated through the Content-Type and Accept headers.",
+			Version:     "1.0.0",
+		},
+		Servers:  []openapi.Server{{URL: "http://localhost:3000", Description: "Development server (default port)"}},
+		Tags:     []openapi.Tag{{Name: "Categories", Description: "Operations related to category management"}},
+		Security: []openapi.SecurityRequirement{{"CategoryAuth": {}}, {"CategoryBearer": {}}},
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"CategoryAuth": {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "X-API-Key",
+					Description: "API key configured with the API_KEY setting or issued with the keys command, an issued key may be tied to a tenant. Clients presenting a verified TLS certificate listed in TLS_CLIENT_PRINCIPALS do not need it.",
+				},
+				"CategoryBearer": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "HS256 token signed with the JWT_SECRET setting, the JWT_TENANT_CLAIM claim names the tenant. Accepted when JWT_SECRET is set.",
+				},
+			},
+		},
+	}, operations)
+}
+
+func NewRouter(categoryController controller.CategoryController, categoryTranslationController controller.CategoryTranslationController, categoryAttributeSchemaController controller.CategoryAttributeSchemaController) *httprouter.Router {
 	router := httprouter.New()
 
-	// setup endpoints
-	router.GET("/api/categories", categoryController.FindAll)
-	router.GET("/api/categories/:categoryId", categoryController.FindById)
-	router.POST("/api/categories", categoryController.Create)
-	router.PUT("/api/categories/:categoryId", categoryController.Update)
-	router.DELETE("/api/categories/:categoryId", categoryController.DeleteById)
+	// setup endpoints, httprouter cannot have by-slug next to :categoryId so the lookups by
+	// slug are in a router of their own, tried when nothing else matches
+	slugRouter := httprouter.New()
+	slugRouter.NotFound = http.HandlerFunc(exception.RouteNotFound)
+	slugRouter.MethodNotAllowed = http.HandlerFunc(exception.MethodNotAllowed)
+	slugRouter.PanicHandler = exception.ErrorHandler
+	router.NotFound = slugRouter
+
+	// OPTIONS of the slug routes answer like the others, with whatever GlobalOPTIONS of
+	// router is set to, such as the CORS preflight
+	slugRouter.GlobalOPTIONS = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if router.GlobalOPTIONS != nil {
+			router.GlobalOPTIONS.ServeHTTP(writer, request)
+		}
+	})
+	router.MethodNotAllowed = http.HandlerFunc(exception.MethodNotAllowed)
+	routes := CategoryRoutes(categoryController, categoryTranslationController, categoryAttributeSchemaController)
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, "/api/categories/by-slug/") {
+			slugRouter.Handle(route.Method, route.Path, route.Handle)
+			continue
+		}
+		router.Handle(route.Method, route.Path, route.Handle)
+	}
+
+	// setup api documentation
+	router.Handler(http.MethodGet, "/openapi.json", openapi.NewHandler(NewOpenAPIDocument(routes)))
+	router.Handler(http.MethodGet, "/docs", openapi.NewUIHandler("Category RESTful API", "/openapi.json"))
+	router.Handler(http.MethodGet, openapi.BundlePath, openapi.NewBundleHandler())
 
 	// setup panic handler
 	router.PanicHandler = exception.ErrorHandler
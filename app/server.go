@@ -0,0 +1,26 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+)
+
+func NewServer(serverConfig config.ServerConfig, handler http.Handler) *http.Server {
+
+	// HTTP/2 is only negotiated over TLS
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(serverConfig.HTTP2)
+
+	return &http.Server{
+		Addr:              fmt.Sprintf("%v:%v", serverConfig.Host, serverConfig.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
+		Protocols:         protocols,
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+)
+
+// CertificateReloader serves the current certificate and reloads it when the
+// certificate or key file changes on disk
+type CertificateReloader struct {
+	CertFile string
+	KeyFile  string
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	modTimes    [2]time.Time
+}
+
+func NewCertificateReloader(certFile string, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+	if _, err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (reloader *CertificateReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+	return reloader.certificate, nil
+}
+
+// Reload loads the key pair again if one of the files has been modified
+func (reloader *CertificateReloader) Reload() (bool, error) {
+	modTimes, err := reloader.readModTimes()
+	if err != nil {
+		return false, err
+	}
+
+	reloader.mutex.RLock()
+	unchanged := reloader.certificate != nil && modTimes == reloader.modTimes
+	reloader.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(reloader.CertFile, reloader.KeyFile)
+	if err != nil {
+		return false, err
+	}
+
+	reloader.mutex.Lock()
+	reloader.certificate = &certificate
+	reloader.modTimes = modTimes
+	reloader.mutex.Unlock()
+	return true, nil
+}
+
+// Watch checks the files every interval until the context is done,
+// a failed reload keeps serving the previous certificate
+func (reloader *CertificateReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := reloader.Reload()
+			if err != nil {
+				log.Printf("tls: reloading certificate failed, keeping the previous one: %v", err)
+			} else if reloaded {
+				log.Printf("tls: reloaded certificate from %v", reloader.CertFile)
+			}
+		}
+	}
+}
+
+func (reloader *CertificateReloader) readModTimes() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{reloader.CertFile, reloader.KeyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func NewTLSConfig(tlsConfig config.TLSConfig, reloader *CertificateReloader) (*tls.Config, error) {
+
+	serverTLSConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	// setup client certificate authentication
+	clientAuth := map[string]tls.ClientAuthType{
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify_if_given":    tls.VerifyClientCertIfGiven,
+		"require_and_verify": tls.RequireAndVerifyClientCert,
+	}
+	serverTLSConfig.ClientAuth = clientAuth[tlsConfig.ClientAuth]
+
+	if tlsConfig.ClientCAFile != "" {
+		pem, err := os.ReadFile(tlsConfig.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%v: no certificates found", tlsConfig.ClientCAFile)
+		}
+		serverTLSConfig.ClientCAs = clientCAs
+	}
+
+	return serverTLSConfig, nil
+}
@@ -0,0 +1,16 @@
+package app
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+)
+
+// NewValidator returns the validator of the services, its errors are reported
+// in the language the client prefers
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+	if err := locale.RegisterTranslations(validate); err != nil {
+		panic(err)
+	}
+	return validate
+}
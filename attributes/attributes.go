@@ -0,0 +1,91 @@
+package attributes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+// MaxFilters bounds the attribute filters of a list, each one is a JSON_EXTRACT in the query
+const MaxFilters = 10
+
+// the keys of an attribute path, so they can be quoted in a MySQL JSON path as they are
+var pathKey = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Normalize gives attributes the types they have in JSON, decoders such as MessagePack
+// produce integers the schema and MySQL do not expect
+func Normalize(attributes map[string]any) (map[string]any, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, exception.NewBadRequestError("attributes must be JSON values")
+	}
+	var normalized map[string]any
+	err = json.Unmarshal(encoded, &normalized)
+	return normalized, err
+}
+
+// ParseSchema reads a schema in the dialect of OpenAPI 3.0, which is JSON Schema without
+// references to other documents. $schema, $id and $comment are accepted and ignored.
+func ParseSchema(definition []byte) (*openapi3.Schema, error) {
+	schema := openapi3.NewSchema()
+	err := json.Unmarshal(definition, schema)
+	if err != nil {
+		return nil, exception.NewBadRequestError("schema must be a JSON object")
+	}
+	ctx := openapi3.WithValidationOptions(context.Background(), openapi3.AllowExtraSiblingFields("$schema", "$id", "$comment"))
+	err = schema.Validate(ctx)
+	if err != nil {
+		return nil, exception.NewBadRequestError("invalid schema: " + err.Error())
+	}
+	return schema, nil
+}
+
+// Check reports every attribute not matching the schema, a category without attributes
+// is checked as an empty object so required attributes are enforced
+func Check(schema *openapi3.Schema, attributes map[string]any) error {
+	value := map[string]any{}
+	for key, attribute := range attributes {
+		value[key] = attribute
+	}
+
+	err := schema.VisitJSON(value, openapi3.MultiErrors())
+	if err == nil {
+		return nil
+	}
+	var multiError openapi3.MultiError
+	if !errors.As(err, &multiError) {
+		multiError = openapi3.MultiError{err}
+	}
+	messages := make([]string, 0, len(multiError))
+	for _, err := range multiError {
+		var schemaError *openapi3.SchemaError
+		if !errors.As(err, &schemaError) {
+			messages = append(messages, "attributes: "+err.Error())
+			continue
+		}
+		field := strings.Join(append([]string{"attributes"}, schemaError.JSONPointer()...), ".")
+		messages = append(messages, field+": "+schemaError.Reason)
+	}
+	return exception.NewBadRequestError(strings.Join(messages, "; "))
+}
+
+// Path turns an attribute path such as size.width into the MySQL JSON path $."size"."width"
+func Path(path string) (string, error) {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		if !pathKey.MatchString(key) {
+			return "", exception.NewBadRequestError(fmt.Sprintf("attr.%s is not an attribute path, its keys are letters, digits, _ and - separated by dots", path))
+		}
+		keys[i] = `"` + key + `"`
+	}
+	return "$." + strings.Join(keys, "."), nil
+}
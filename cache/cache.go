@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Add stores the value only when the key is missing, atomically, and reports whether it did
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type Stats struct {
+	Hits   atomic.Int64
+	Misses atomic.Int64
+	Errors atomic.Int64
+}
+
+// Snapshot returns the counters as they are published
+func (stats *Stats) Snapshot() any {
+	return map[string]int64{
+		"hits":   stats.Hits.Load(),
+		"misses": stats.Misses.Load(),
+		"errors": stats.Errors.Load(),
+	}
+}
+
+// NewStatsHandler serves the counters under name, in the format of expvar. It publishes
+// nothing else, expvar.Handler would also show the command line and the secrets in its flags.
+func NewStatsHandler(name string, stats *Stats) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(writer).Encode(map[string]any{name: stats.Snapshot()})
+	})
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRUCache is an in-process cache that evicts the least recently used entry
+// once Size entries are stored
+type LRUCache struct {
+	Size int
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		Size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (cache *LRUCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return nil, false, nil
+	}
+
+	cache.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (cache *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.set(key, value, ttl)
+	return nil
+}
+
+func (cache *LRUCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		if entry.expiresAt.IsZero() || time.Now().Before(entry.expiresAt) {
+			return false, nil
+		}
+	}
+	cache.set(key, value, ttl)
+	return true, nil
+}
+
+// set stores the entry, the caller holds the mutex
+func (cache *LRUCache) set(key string, value []byte, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+
+	// evict the least recently used entries
+	for cache.Size > 0 && cache.order.Len() > cache.Size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (cache *LRUCache) Delete(ctx context.Context, keys ...string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for _, key := range keys {
+		if element, ok := cache.entries[key]; ok {
+			cache.order.Remove(element)
+			delete(cache.entries, key)
+		}
+	}
+	return nil
+}
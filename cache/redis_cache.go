@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisCache talks the Redis protocol (RESP), so any compatible server can back it
+type RedisCache struct {
+	Address     string
+	Password    string
+	DB          int
+	DialTimeout time.Duration
+
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+type RedisError struct {
+	Message string
+}
+
+func (err RedisError) Error() string {
+	return "redis: " + err.Message
+}
+
+func NewRedisCache(address string, password string, db int, poolSize int) *RedisCache {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	return &RedisCache{
+		Address:     address,
+		Password:    password,
+		DB:          db,
+		DialTimeout: 5 * time.Second,
+		pool:        make(chan *redisConn, poolSize),
+	}
+}
+
+func (cache *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := cache.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %T to GET", reply)
+	}
+	return value, true, nil
+}
+
+func (cache *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := cache.do(ctx, args...)
+	return err
+}
+
+// Add is SET with NX, Redis replies with a null bulk string when the key exists
+func (cache *RedisCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []string{"SET", key, string(value), "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	reply, err := cache.do(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (cache *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := cache.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+func (cache *RedisCache) Close() error {
+	for {
+		select {
+		case conn := <-cache.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (cache *RedisCache) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := cache.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.command(ctx, args...)
+
+	// a redis error reply leaves the connection usable, anything else does not
+	var redisError RedisError
+	if err != nil && !errors.As(err, &redisError) {
+		conn.conn.Close()
+		return nil, err
+	}
+	cache.release(conn)
+	return reply, err
+}
+
+func (cache *RedisCache) acquire(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-cache.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: cache.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", cache.Address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if cache.Password != "" {
+		if _, err := conn.command(ctx, "AUTH", cache.Password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if cache.DB != 0 {
+		if _, err := conn.command(ctx, "SELECT", strconv.Itoa(cache.DB)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (cache *RedisCache) release(conn *redisConn) {
+	select {
+	case cache.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (conn *redisConn) command(ctx context.Context, args ...string) (any, error) {
+	// no deadline on the context clears the one from the previous command
+	deadline, _ := ctx.Deadline()
+	if err := conn.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// commands are sent as an array of bulk strings
+	buffer := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buffer = append(buffer, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buffer = append(buffer, arg...)
+		buffer = append(buffer, "\r\n"...)
+	}
+	if _, err := conn.conn.Write(buffer); err != nil {
+		return nil, err
+	}
+
+	return readReply(conn.reader)
+}
+
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError{Message: payload}
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		value := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		return value[:length], nil
+	case '*':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		values := make([]any, 0, length)
+		for i := 0; i < length; i++ {
+			value, err := readReply(reader)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+func (cli *CLI) categories(ctx context.Context, args []string) error {
+	name, args, err := subcommand("categories", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		flagSet := cli.newFlagSet("categories list")
+		tenantFlag := cli.newTenantFlag(flagSet)
+		output := newOutput(flagSet)
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		ctx, err := tenantFlag.context(ctx)
+		if err != nil {
+			return err
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		categories, err := cli.CategoryService.FindAll(ctx, web.CategoryListRequest{})
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(categories, func(a, b web.CategoryResponse) int {
+			return a.Id - b.Id
+		})
+		return cli.writeCategories(output, categories)
+	case "create":
+		flagSet := cli.newFlagSet("categories create")
+		tenantFlag := cli.newTenantFlag(flagSet)
+		output := newOutput(flagSet)
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		ctx, err := tenantFlag.context(ctx)
+		if err != nil {
+			return err
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		if flagSet.NArg() == 0 {
+			return usageError("categories create: missing category name")
+		}
+		categories := []web.CategoryResponse{}
+		for _, categoryName := range flagSet.Args() {
+			category, err := cli.CategoryService.Create(ctx, web.CategoryCreateRequest{Name: categoryName})
+			if err != nil {
+				return fmt.Errorf("category %q: %w", categoryName, err)
+			}
+			categories = append(categories, category)
+		}
+		return cli.writeCategories(output, categories)
+	case "delete":
+		flagSet := cli.newFlagSet("categories delete")
+		tenantFlag := cli.newTenantFlag(flagSet)
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		ctx, err := tenantFlag.context(ctx)
+		if err != nil {
+			return err
+		}
+		if flagSet.NArg() == 0 {
+			return usageError("categories delete: missing category id")
+		}
+		categoryIds := make([]int, 0, flagSet.NArg())
+		for _, arg := range flagSet.Args() {
+			categoryId, err := strconv.Atoi(arg)
+			if err != nil {
+				return usageError("categories delete: invalid category id %q", arg)
+			}
+			categoryIds = append(categoryIds, categoryId)
+		}
+		for _, categoryId := range categoryIds {
+			if err := cli.CategoryService.DeleteById(ctx, categoryId); err != nil {
+				return fmt.Errorf("category %d: %w", categoryId, err)
+			}
+			fmt.Fprintf(cli.Stdout, "deleted category %d\n", categoryId)
+		}
+		return nil
+	default:
+		return usageError("categories: unknown subcommand %q", name)
+	}
+}
+
+func (cli *CLI) writeCategories(output *output, categories []web.CategoryResponse) error {
+	if categories == nil {
+		categories = []web.CategoryResponse{}
+	}
+	rows := make([][]string, 0, len(categories))
+	for _, category := range categories {
+		rows = append(rows, []string{strconv.Itoa(category.Id), category.Name, category.Slug})
+	}
+	return output.write(cli.Stdout, categories, []string{"ID", "NAME", "SLUG"}, rows)
+}
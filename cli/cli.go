@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/fixtures"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+const Usage = `Usage: go-mysql-restful-api [flags] [command]
+
+The flags are the configuration flags, see -h. Commands:
+
+  serve                                   serve the APIs, the default command
+  migrate up                              apply the pending migrations
+  migrate down [-steps n]                 revert the last applied migrations, 1 by default
+  migrate status [-o table|json]          list the migrations and when they were applied
+  categories list [-tenant id] [-o table|json]
+                                          list the categories
+  categories create [-tenant id] [-o table|json] name...
+                                          create categories, validated like the API does
+  categories delete [-tenant id] id...    delete categories
+  keys issue (-tenant id | -all-tenants) [-o table|json] name
+                                          issue an API key, the key is only shown once, a key
+                                          with a tenant only reaches the categories of it and
+                                          one issued with -all-tenants may name any tenant
+  keys revoke name                        revoke the API key with the name
+  keys list [-o table|json]               list the API keys without the keys themselves
+  export [-tenant id] [-file path]        write the categories as JSON, to stdout by default
+  import [-tenant id] [-file path] [-o table|json]
+                                          create the categories of an export, read from stdin
+                                          by default, names that already exist are skipped
+  seed [-tenant id] [-dataset name] [-file path] [-replace] [-o table|json]
+                                          load a named dataset, default by default, or a dataset file,
+                                          names that already exist are skipped and -replace deletes
+                                          the categories the dataset does not name
+
+The categories, export, import and seed commands work on the categories of one tenant,
+-tenant picks it and defaults to auth.default_tenant.
+`
+
+// UsageError is a mistake on the command line, main prints Usage and exits with 2
+type UsageError struct {
+	Message string
+}
+
+func (err UsageError) Error() string {
+	return err.Message
+}
+
+func usageError(format string, args ...any) UsageError {
+	return UsageError{Message: fmt.Sprintf(format, args...)}
+}
+
+// CLI runs the admin commands, they go through the same services as the APIs
+// so nothing bypasses validation
+type CLI struct {
+	Stdin           io.Reader
+	Stdout          io.Writer
+	Stderr          io.Writer
+	CategoryService service.CategoryService
+	APIKeyService   service.APIKeyService
+	Migrator        *database.Migrator
+	Datasets        fs.FS  // the named datasets of seed
+	Tenant          string // tenant of the category commands without -tenant
+}
+
+func NewCLI(categoryService service.CategoryService, apiKeyService service.APIKeyService, migrator *database.Migrator) *CLI {
+	return &CLI{
+		Stdin:           os.Stdin,
+		Stdout:          os.Stdout,
+		Stderr:          os.Stderr,
+		CategoryService: categoryService,
+		APIKeyService:   apiKeyService,
+		Migrator:        migrator,
+		Datasets:        fixtures.FS,
+		Tenant:          "default",
+	}
+}
+
+// Run runs the command named by args[0] with the rest of args, serve is left to main
+func (cli *CLI) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("missing command")
+	}
+
+	var err error
+	switch args[0] {
+	case "migrate":
+		err = cli.migrate(ctx, args[1:])
+	case "categories":
+		err = cli.categories(ctx, args[1:])
+	case "keys":
+		err = cli.keys(ctx, args[1:])
+	case "export":
+		err = cli.exportCategories(ctx, args[1:])
+	case "import":
+		err = cli.importCategories(ctx, args[1:])
+	case "seed":
+		err = cli.seed(ctx, args[1:])
+	default:
+		err = usageError("unknown command %q", args[0])
+	}
+
+	// -h already printed the flags of the command
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+// newFlagSet parses the flags of a command, errors are returned instead of printed
+func (cli *CLI) newFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.SetOutput(cli.Stderr)
+	return flagSet
+}
+
+func parseFlags(flagSet *flag.FlagSet, args []string) error {
+	if err := flagSet.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError("%v: %v", flagSet.Name(), err)
+	}
+	return nil
+}
+
+// tenantFlag adds -tenant to a command working on categories, the categories of each tenant are apart
+type tenantFlag struct {
+	id string
+}
+
+func (cli *CLI) newTenantFlag(flagSet *flag.FlagSet) *tenantFlag {
+	tenantFlag := &tenantFlag{}
+	flagSet.StringVar(&tenantFlag.id, "tenant", cli.Tenant, "tenant of the categories")
+	return tenantFlag
+}
+
+// context returns ctx scoped to the tenant
+func (tenantFlag *tenantFlag) context(ctx context.Context) (context.Context, error) {
+	if !tenant.Valid(tenantFlag.id) {
+		return ctx, usageError("-tenant must be lowercase letters, digits, - and _, got %q", tenantFlag.id)
+	}
+	return tenant.WithID(ctx, tenantFlag.id), nil
+}
+
+// subcommand splits "migrate up ..." style arguments
+func subcommand(command string, args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, usageError("%v: missing subcommand", command)
+	}
+	return args[0], args[1:], nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+func (cli *CLI) keys(ctx context.Context, args []string) error {
+	name, args, err := subcommand("keys", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "issue":
+		flagSet := cli.newFlagSet("keys issue")
+		tenantId := flagSet.String("tenant", "", "tenant the key is tied to")
+		allTenants := flagSet.Bool("all-tenants", false, "issue a key that may name any tenant with X-Tenant-ID")
+		output := newOutput(flagSet)
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		if flagSet.NArg() != 1 {
+			return usageError("keys issue: expected one key name, got %d", flagSet.NArg())
+		}
+		// a key without a tenant reaches every tenant, so that has to be asked for
+		switch {
+		case *tenantId == "" && !*allTenants:
+			return usageError("keys issue: -tenant is required, or -all-tenants for a key that may name any tenant")
+		case *tenantId != "" && *allTenants:
+			return usageError("keys issue: -tenant and -all-tenants are mutually exclusive")
+		}
+		apiKey, err := cli.APIKeyService.Issue(ctx, web.APIKeyIssueRequest{Name: flagSet.Arg(0), TenantId: *tenantId})
+		if err != nil {
+			return err
+		}
+		if err := cli.writeAPIKeys(output, apiKey, []web.APIKeyResponse{apiKey}); err != nil {
+			return err
+		}
+		fmt.Fprintln(cli.Stderr, "the key is not stored, keep it now")
+		return nil
+	case "revoke":
+		flagSet := cli.newFlagSet("keys revoke")
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		if flagSet.NArg() != 1 {
+			return usageError("keys revoke: expected one key name, got %d", flagSet.NArg())
+		}
+		if err := cli.APIKeyService.Revoke(ctx, flagSet.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.Stdout, "revoked key %v\n", flagSet.Arg(0))
+		return nil
+	case "list":
+		flagSet := cli.newFlagSet("keys list")
+		output := newOutput(flagSet)
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		apiKeys, err := cli.APIKeyService.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		return cli.writeAPIKeys(output, apiKeys, apiKeys)
+	default:
+		return usageError("keys: unknown subcommand %q", name)
+	}
+}
+
+// writeAPIKeys writes value as JSON or apiKeys as a table, the key column only shows up when issuing
+func (cli *CLI) writeAPIKeys(output *output, value any, apiKeys []web.APIKeyResponse) error {
+	header := []string{"ID", "NAME", "TENANT", "CREATED AT", "REVOKED AT"}
+	if len(apiKeys) == 1 && apiKeys[0].Key != "" {
+		header = append(header, "KEY")
+	}
+	rows := make([][]string, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		// a key without a tenant may name any
+		tenantId := apiKey.TenantId
+		if tenantId == "" {
+			tenantId = "any"
+		}
+		row := []string{strconv.Itoa(apiKey.Id), apiKey.Name, tenantId, formatTime(&apiKey.CreatedAt), formatTime(apiKey.RevokedAt)}
+		if apiKey.Key != "" {
+			row = append(row, apiKey.Key)
+		}
+		rows = append(rows, row)
+	}
+	return output.write(cli.Stdout, value, header, rows)
+}
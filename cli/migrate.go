@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+)
+
+func (cli *CLI) migrate(ctx context.Context, args []string) error {
+	name, args, err := subcommand("migrate", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "up":
+		flagSet := cli.newFlagSet("migrate up")
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		migrations, err := cli.Migrator.Up(ctx)
+		cli.printMigrations("applied", migrations)
+		return err
+	case "down":
+		flagSet := cli.newFlagSet("migrate down")
+		steps := flagSet.Int("steps", 1, "number of migrations to revert")
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return usageError("migrate down: -steps must be at least 1, got %d", *steps)
+		}
+		migrations, err := cli.Migrator.Down(ctx, *steps)
+		cli.printMigrations("reverted", migrations)
+		return err
+	case "status":
+		flagSet := cli.newFlagSet("migrate status")
+		output := newOutput(flagSet)
+		if err := parseFlags(flagSet, args); err != nil {
+			return err
+		}
+		if err := output.validate(); err != nil {
+			return err
+		}
+		statuses, err := cli.Migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return cli.writeMigrationStatuses(output, statuses)
+	default:
+		return usageError("migrate: unknown subcommand %q", name)
+	}
+}
+
+func (cli *CLI) printMigrations(verb string, migrations []database.Migration) {
+	if len(migrations) == 0 {
+		fmt.Fprintf(cli.Stdout, "no migrations %v\n", verb)
+	}
+	for _, migration := range migrations {
+		fmt.Fprintf(cli.Stdout, "%v %d_%v\n", verb, migration.Version, migration.Name)
+	}
+}
+
+type migrationStatusOutput struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+func (cli *CLI) writeMigrationStatuses(output *output, statuses []database.MigrationStatus) error {
+	values := make([]migrationStatusOutput, 0, len(statuses))
+	rows := make([][]string, 0, len(statuses))
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied"
+		}
+		values = append(values, migrationStatusOutput{Version: status.Version, Name: status.Name, Applied: status.AppliedAt != nil, AppliedAt: status.AppliedAt})
+		rows = append(rows, []string{strconv.Itoa(status.Version), status.Name, state, formatTime(status.AppliedAt)})
+	}
+	return output.write(cli.Stdout, values, []string{"VERSION", "NAME", "STATUS", "APPLIED AT"}, rows)
+}
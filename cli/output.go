@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// output writes the result of a command as an aligned table for people or as JSON for scripts
+type output struct {
+	format string
+}
+
+func newOutput(flagSet *flag.FlagSet) *output {
+	output := &output{}
+	flagSet.StringVar(&output.format, "o", "table", "output format, table or json")
+	return output
+}
+
+func (output *output) validate() error {
+	if output.format != "table" && output.format != "json" {
+		return usageError("-o must be table or json, got %q", output.format)
+	}
+	return nil
+}
+
+// write prints value as indented JSON, or the header and rows as a table
+func (output *output) write(writer io.Writer, value any, header []string, rows [][]string) error {
+	if output.format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	tableWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tableWriter, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tableWriter, strings.Join(row, "\t"))
+	}
+	return tableWriter.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/fixtures"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+// exportCategories writes the categories ordered by id, import reads the same format
+func (cli *CLI) exportCategories(ctx context.Context, args []string) error {
+	flagSet := cli.newFlagSet("export")
+	tenantFlag := cli.newTenantFlag(flagSet)
+	path := flagSet.String("file", "", "file to write, stdout when empty")
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+	ctx, err := tenantFlag.context(ctx)
+	if err != nil {
+		return err
+	}
+
+	categories, err := cli.CategoryService.FindAll(ctx, web.CategoryListRequest{})
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(categories, func(a, b web.CategoryResponse) int {
+		return a.Id - b.Id
+	})
+	if categories == nil {
+		categories = []web.CategoryResponse{}
+	}
+
+	writer := cli.Stdout
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(categories); err != nil {
+		return err
+	}
+	if *path != "" {
+		fmt.Fprintf(cli.Stderr, "exported %d categories to %v\n", len(categories), *path)
+	}
+	return nil
+}
+
+// importCategories creates the categories of an export, ids are assigned anew
+func (cli *CLI) importCategories(ctx context.Context, args []string) error {
+	flagSet := cli.newFlagSet("import")
+	tenantFlag := cli.newTenantFlag(flagSet)
+	path := flagSet.String("file", "", "file to read, stdin when empty")
+	output := newOutput(flagSet)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+	ctx, err := tenantFlag.context(ctx)
+	if err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+
+	reader := cli.Stdin
+	if *path != "" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+	dataset, err := readExport(reader)
+	if err != nil {
+		return err
+	}
+	report, err := fixtures.NewLoader(cli.CategoryService).Load(ctx, dataset)
+	return cli.writeReport(output, report, err)
+}
+
+func (cli *CLI) seed(ctx context.Context, args []string) error {
+	flagSet := cli.newFlagSet("seed")
+	tenantFlag := cli.newTenantFlag(flagSet)
+	name := flagSet.String("dataset", "default", "named dataset to load, one of "+strings.Join(fixtures.Names(cli.Datasets), ", "))
+	path := flagSet.String("file", "", "YAML or JSON dataset file to load instead of a named dataset")
+	replace := flagSet.Bool("replace", false, "delete the categories the dataset does not name")
+	output := newOutput(flagSet)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+	ctx, err := tenantFlag.context(ctx)
+	if err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+
+	var dataset fixtures.Dataset
+	if *path != "" {
+		dataset, err = fixtures.ReadFile(*path)
+	} else {
+		dataset, err = fixtures.Named(cli.Datasets, *name)
+	}
+	if err != nil {
+		return err
+	}
+
+	loader := fixtures.NewLoader(cli.CategoryService)
+	var report fixtures.Report
+	if *replace {
+		report, err = loader.Replace(ctx, dataset)
+	} else {
+		report, err = loader.Load(ctx, dataset)
+	}
+	return cli.writeReport(output, report, err)
+}
+
+// readExport reads the output of export as a dataset, the ids are dropped
+func readExport(reader io.Reader) (fixtures.Dataset, error) {
+	categories := []web.CategoryCreateRequest{}
+	if err := json.NewDecoder(reader).Decode(&categories); err != nil {
+		return fixtures.Dataset{}, fmt.Errorf("import: expected a JSON array of categories: %w", err)
+	}
+	dataset := fixtures.Dataset{Name: "import"}
+	for _, category := range categories {
+		dataset.Categories = append(dataset.Categories, fixtures.Category{
+			Name:        category.Name,
+			Type:        category.Type,
+			Attributes:  category.Attributes,
+			Description: category.Description,
+			ImageURL:    category.ImageURL,
+			SortOrder:   category.SortOrder,
+			IsActive:    category.IsActive,
+		})
+	}
+	return dataset, nil
+}
+
+// writeReport writes the report of a load, including the part done before an error, and returns the error
+func (cli *CLI) writeReport(output *output, report fixtures.Report, err error) error {
+	if err != nil && len(report) == 0 {
+		return err
+	}
+	rows := make([][]string, 0, len(report))
+	for _, result := range report {
+		rows = append(rows, []string{strconv.Itoa(result.Id), result.Name, result.Action})
+	}
+	if writeErr := output.write(cli.Stdout, report, []string{"ID", "NAME", "ACTION"}, rows); err == nil {
+		err = writeErr
+	}
+	return err
+}
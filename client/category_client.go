@@ -0,0 +1,259 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+// CategoryClient calls the category API, it shares the request and response types of model/web
+type CategoryClient struct {
+	BaseURL     string // e.g. http://localhost:3000, without the /api prefix
+	Credentials Credentials
+	HTTPClient  *http.Client
+
+	// Tenant is sent in X-Tenant-ID, empty leaves it to the credentials or the server default
+	Tenant string
+
+	// MaxRetries is how many times a request failing with 429, 5xx or a network error
+	// is sent again, waiting RetryBackoff doubled on every attempt up to MaxRetryBackoff
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	// MaxRetryAfter is the longest Retry-After waited for, a longer one or one ending
+	// after the deadline of the context returns the error response right away
+	MaxRetryAfter time.Duration
+}
+
+func NewCategoryClient(baseURL string, credentials Credentials) *CategoryClient {
+	return &CategoryClient{
+		BaseURL:         strings.TrimSuffix(baseURL, "/"),
+		Credentials:     credentials,
+		HTTPClient:      http.DefaultClient,
+		MaxRetries:      3,
+		RetryBackoff:    100 * time.Millisecond,
+		MaxRetryBackoff: 5 * time.Second,
+		MaxRetryAfter:   time.Minute,
+	}
+}
+
+// Create sends an Idempotency-Key, so retrying it never creates the category twice
+func (client *CategoryClient) Create(ctx context.Context, request web.CategoryCreateRequest) (web.CategoryResponse, error) {
+	category := web.CategoryResponse{}
+	err := client.do(ctx, http.MethodPost, "/api/categories", request, &category)
+	return category, err
+}
+
+// Update changes the category with request.Id
+func (client *CategoryClient) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+	category := web.CategoryResponse{}
+	err := client.do(ctx, http.MethodPut, "/api/categories/"+strconv.Itoa(request.Id), request, &category)
+	return category, err
+}
+
+func (client *CategoryClient) Delete(ctx context.Context, categoryId int) error {
+	return client.do(ctx, http.MethodDelete, "/api/categories/"+strconv.Itoa(categoryId), nil, nil)
+}
+
+func (client *CategoryClient) Get(ctx context.Context, categoryId int) (web.CategoryResponse, error) {
+	category := web.CategoryResponse{}
+	err := client.do(ctx, http.MethodGet, "/api/categories/"+strconv.Itoa(categoryId), nil, &category)
+	return category, err
+}
+
+// GetBySlug follows the redirect of a slug the category had before, the response has its current one
+func (client *CategoryClient) GetBySlug(ctx context.Context, slug string) (web.CategoryResponse, error) {
+	category := web.CategoryResponse{}
+	err := client.do(ctx, http.MethodGet, "/api/categories/by-slug/"+url.PathEscape(slug), nil, &category)
+	return category, err
+}
+
+func (client *CategoryClient) List(ctx context.Context) ([]web.CategoryResponse, error) {
+	var categories []web.CategoryResponse
+	err := client.do(ctx, http.MethodGet, "/api/categories", nil, &categories)
+	return categories, err
+}
+
+// Categories ranges over the categories of one List call, the error of that call is
+// yielded once. The API does not page the list, so all of it is loaded first.
+func (client *CategoryClient) Categories(ctx context.Context) iter.Seq2[web.CategoryResponse, error] {
+	return func(yield func(web.CategoryResponse, error) bool) {
+		categories, err := client.List(ctx)
+		if err != nil {
+			yield(web.CategoryResponse{}, err)
+			return
+		}
+		for _, category := range categories {
+			if !yield(category, nil) {
+				return
+			}
+		}
+	}
+}
+
+// envelope is web.WebResponse with data left undecoded
+type envelope struct {
+	Code   int             `json:"code"`
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// do sends the request, retrying it when allowed, and decodes the data field into result
+func (client *CategoryClient) do(ctx context.Context, method string, path string, body any, result any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	idempotencyKey := ""
+	if method == http.MethodPost {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := client.send(ctx, method, path, payload, idempotencyKey)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= client.MaxRetries {
+				return err
+			}
+			if err := client.wait(ctx, attempt, 0); err != nil {
+				return err
+			}
+			continue
+		}
+
+		retryable := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+		retryAfter := parseRetryAfter(response.Header.Get("Retry-After"))
+		if retryable && attempt < client.MaxRetries && client.canWait(ctx, retryAfter) {
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+			if err := client.wait(ctx, attempt, retryAfter); err != nil {
+				return err
+			}
+			continue
+		}
+		return decodeResponse(response, result)
+	}
+}
+
+func (client *CategoryClient) send(ctx context.Context, method string, path string, payload []byte, idempotencyKey string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, client.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		request.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if client.Tenant != "" {
+		request.Header.Set("X-Tenant-ID", client.Tenant)
+	}
+	if client.Credentials != nil {
+		if err := client.Credentials.Authorize(request); err != nil {
+			return nil, err
+		}
+	}
+	return client.HTTPClient.Do(request)
+}
+
+// canWait tells whether the Retry-After of the server is short enough to wait for
+func (client *CategoryClient) canWait(ctx context.Context, retryAfter time.Duration) bool {
+	if retryAfter > client.MaxRetryAfter {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > retryAfter
+}
+
+// wait sleeps before the next attempt, Retry-After from the server wins over the backoff
+func (client *CategoryClient) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = client.RetryBackoff << attempt
+		if delay > client.MaxRetryBackoff || delay <= 0 {
+			delay = client.MaxRetryBackoff
+		}
+		// jitter keeps clients failing together from retrying together
+		delay = delay/2 + time.Duration(mathrand.Int64N(int64(delay/2)+1))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func decodeResponse(response *http.Response, result any) error {
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	decoded := envelope{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		if response.StatusCode != http.StatusOK {
+			// not an API response, such as an error page of a proxy
+			return &APIError{StatusCode: response.StatusCode, Status: strings.ToUpper(http.StatusText(response.StatusCode))}
+		}
+		return fmt.Errorf("category api: invalid response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var message string
+		json.Unmarshal(decoded.Data, &message)
+		if response.StatusCode == http.StatusNotFound {
+			return NewNotFoundError(message)
+		}
+		return &APIError{StatusCode: response.StatusCode, Status: decoded.Status, Message: message}
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(decoded.Data, result)
+}
+
+// parseRetryAfter reads both forms of Retry-After, seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Credentials authenticate every request the client sends, including retries
+type Credentials interface {
+	Authorize(request *http.Request) error
+}
+
+// APIKey is sent in the X-API-Key header checked by the auth middleware
+type APIKey string
+
+func (key APIKey) Authorize(request *http.Request) error {
+	request.Header.Set("X-API-Key", string(key))
+	return nil
+}
+
+// BearerToken is sent as "Authorization: Bearer", the server accepts HS256 JWTs when auth.jwt_secret is set
+type BearerToken string
+
+func (token BearerToken) Authorize(request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+string(token))
+	return nil
+}
+
+// TokenSource fetches a bearer token for every attempt, so short lived JWTs can be refreshed
+type TokenSource func(ctx context.Context) (string, error)
+
+func (source TokenSource) Authorize(request *http.Request) error {
+	token, err := source(request.Context())
+	if err != nil {
+		return err
+	}
+	return BearerToken(token).Authorize(request)
+}
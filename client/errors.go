@@ -0,0 +1,54 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// NotFoundError mirrors exception.NotFoundError, the message comes from the API
+type NotFoundError struct {
+	Message string
+}
+
+func (err NotFoundError) Error() string {
+	return err.Message
+}
+
+func NewNotFoundError(message string) NotFoundError {
+	return NotFoundError{
+		Message: message,
+	}
+}
+
+// APIError is any other error response, Message is the data field of the response
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (err *APIError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("category api: %d %v", err.StatusCode, err.Status)
+	}
+	return fmt.Sprintf("category api: %d %v: %v", err.StatusCode, err.Status, err.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return errors.As(err, &NotFoundError{})
+}
+
+// StatusCode returns the status code of an error response, 0 for other errors
+func StatusCode(err error) int {
+	var apiError *APIError
+	switch {
+	case errors.As(err, &apiError):
+		return apiError.StatusCode
+	case IsNotFound(err):
+		return http.StatusNotFound
+	default:
+		return 0
+	}
+}
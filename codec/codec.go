@@ -0,0 +1,301 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	ErrNotAcceptable        = errors.New("none of the media types in Accept is supported")
+	ErrUnsupportedMediaType = errors.New("the Content-Type of the request body is not supported")
+)
+
+// DecodeError is a request body or query parameter that cannot be decoded, its message is safe to show to the client
+type DecodeError struct {
+	Message string
+}
+
+func (err DecodeError) Error() string {
+	return err.Message
+}
+
+func NewDecodeError(message string) DecodeError {
+	return DecodeError{
+		Message: message,
+	}
+}
+
+// Codec encodes responses and decodes request bodies of one media type
+type Codec interface {
+	MediaType() string
+	Encode(writer io.Writer, value any) error
+	Decode(reader io.Reader, value any) error
+}
+
+var (
+	JSON        Codec = jsonCodec{}
+	XML         Codec = xmlCodec{}
+	MessagePack Codec = msgpackCodec{}
+	CBOR        Codec = cborCodec{}
+)
+
+// mediaTypes maps every accepted media type to its codec, aliases included
+var mediaTypes = map[string]Codec{
+	"application/json":        JSON,
+	"application/xml":         XML,
+	"text/xml":                XML,
+	"application/msgpack":     MessagePack,
+	"application/x-msgpack":   MessagePack,
+	"application/vnd.msgpack": MessagePack,
+	"application/cbor":        CBOR,
+	"application/*":           JSON,
+	"text/*":                  XML,
+	"*/*":                     JSON,
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// Negotiate picks the codec for the Accept header, JSON when the header is empty
+func Negotiate(accept string) (Codec, error) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, nil
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	// the highest quality wins, ties keep the order of the header
+	slices.SortStableFunc(ranges, func(a, b acceptRange) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		default:
+			return 0
+		}
+	})
+	for _, acceptRange := range ranges {
+		if codec, ok := mediaTypes[acceptRange.mediaType]; ok {
+			return codec, nil
+		}
+	}
+	return nil, ErrNotAcceptable
+}
+
+// ForContentType picks the codec for a request body
+func ForContentType(contentType string) (Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || strings.Contains(mediaType, "*") {
+		return nil, ErrUnsupportedMediaType
+	}
+	codec, ok := mediaTypes[mediaType]
+	if !ok {
+		return nil, ErrUnsupportedMediaType
+	}
+	return codec, nil
+}
+
+// DecodeRequest decodes the request body with the codec matching its Content-Type,
+// every controller goes through it so bodies are decoded the same strict way
+func DecodeRequest(request *http.Request, value any) error {
+	requestCodec, err := ForContentType(request.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	return requestCodec.Decode(request.Body, value)
+}
+
+// Write sends value encoded with codec, responses differ by the Accept header
+func Write(writer http.ResponseWriter, codec Codec, statusCode int, value any) error {
+	writer.Header().Set("Content-Type", codec.MediaType())
+	writer.Header().Add("Vary", "Accept")
+	writer.WriteHeader(statusCode)
+	return codec.Encode(writer, value)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Encode(writer io.Writer, value any) error {
+	return json.NewEncoder(writer).Encode(value)
+}
+
+func (jsonCodec) Decode(reader io.Reader, value any) error {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return jsonDecodeError(err)
+	}
+	return checkTrailingData(io.MultiReader(decoder.Buffered(), reader), true)
+}
+
+// jsonDecodeError turns the errors of encoding/json into messages naming the offending field
+func jsonDecodeError(err error) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return NewDecodeError("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return NewDecodeError("request body is not valid JSON: unexpected end of input")
+	case errors.As(err, &syntaxError):
+		return NewDecodeError(fmt.Sprintf("request body is not valid JSON at offset %d", syntaxError.Offset))
+	case errors.As(err, &typeError):
+		if typeError.Field == "" {
+			return NewDecodeError(fmt.Sprintf("request body must be a JSON object, got %v", typeError.Value))
+		}
+		return NewDecodeError(fmt.Sprintf("field %q must be a %v, got %v", typeError.Field, jsonTypeName(typeError.Type.Kind()), typeError.Value))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return NewDecodeError(strings.TrimPrefix(err.Error(), "json: "))
+	default:
+		// oversized bodies and read errors keep their type
+		return err
+	}
+}
+
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return kind.String()
+	}
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) MediaType() string {
+	return "application/xml"
+}
+
+func (xmlCodec) Encode(writer io.Writer, value any) error {
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(writer).Encode(value)
+}
+
+// encoding/xml has no way to reject unknown elements, they are ignored
+func (xmlCodec) Decode(reader io.Reader, value any) error {
+	bufferedReader := bufio.NewReader(reader)
+	if err := xml.NewDecoder(bufferedReader).Decode(value); err != nil {
+		return binaryDecodeError("XML", err)
+	}
+	return checkTrailingData(bufferedReader, true)
+}
+
+// msgpackCodec and cborCodec use the json struct tags, so field names match the JSON representation
+type msgpackCodec struct{}
+
+func (msgpackCodec) MediaType() string {
+	return "application/msgpack"
+}
+
+func (msgpackCodec) Encode(writer io.Writer, value any) error {
+	encoder := msgpack.NewEncoder(writer)
+	encoder.SetCustomStructTag("json")
+	return encoder.Encode(value)
+}
+
+func (msgpackCodec) Decode(reader io.Reader, value any) error {
+	decoder := msgpack.NewDecoder(reader)
+	decoder.SetCustomStructTag("json")
+	decoder.DisallowUnknownFields(true)
+	if err := decoder.Decode(value); err != nil {
+		return binaryDecodeError("MessagePack", err)
+	}
+	return checkTrailingData(decoder.Buffered(), false)
+}
+
+type cborCodec struct{}
+
+var cborDecMode, _ = cbor.DecOptions{ExtraReturnErrors: cbor.ExtraDecErrorUnknownField}.DecMode()
+
+func (cborCodec) MediaType() string {
+	return "application/cbor"
+}
+
+func (cborCodec) Encode(writer io.Writer, value any) error {
+	return cbor.NewEncoder(writer).Encode(value)
+}
+
+func (cborCodec) Decode(reader io.Reader, value any) error {
+	decoder := cborDecMode.NewDecoder(reader)
+	if err := decoder.Decode(value); err != nil {
+		return binaryDecodeError("CBOR", err)
+	}
+	return checkTrailingData(io.MultiReader(decoder.Buffered(), reader), false)
+}
+
+func binaryDecodeError(format string, err error) error {
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		return err
+	case errors.Is(err, io.EOF):
+		return NewDecodeError("request body is empty")
+	default:
+		return NewDecodeError(fmt.Sprintf("request body is not valid %v: %v", format, err))
+	}
+}
+
+// checkTrailingData rejects anything after the decoded value, text formats may end with whitespace
+func checkTrailingData(rest io.Reader, text bool) error {
+	data, err := io.ReadAll(rest)
+	if err != nil {
+		return err
+	}
+	if text {
+		data = bytes.TrimSpace(data)
+	}
+	if len(data) > 0 {
+		return NewDecodeError("request body must contain a single value, found trailing data")
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"flag"
+	"time"
+)
+
+type Config struct {
+	ConfigFile  string
+	EnvFile     string
+	Args        []string // what follows the flags, the subcommand and its arguments
+	Server      ServerConfig
+	GRPC        GRPCConfig
+	Database    DatabaseConfig
+	Auth        AuthConfig
+	Cache       CacheConfig
+	Timeout     TimeoutConfig
+	Idempotency IdempotencyConfig
+	RateLimit   RateLimitConfig
+	Cors        CorsConfig
+	Compression CompressionConfig
+	OpenAPI     OpenAPIConfig
+	GraphQL     GraphQLConfig
+	Locale      LocaleConfig
+}
+
+type ServerConfig struct {
+	Host              string
+	Port              int
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxBodySize       int64
+	HTTP2             bool
+	TLS               TLSConfig
+}
+
+type TLSConfig struct {
+	CertFile         string
+	KeyFile          string
+	ReloadInterval   time.Duration
+	ClientCAFile     string
+	ClientAuth       string            // none, request, require, verify_if_given or require_and_verify
+	ClientPrincipals map[string]string // client certificate common name to principal name
+}
+
+func (config TLSConfig) Enabled() bool {
+	return config.CertFile != "" || config.KeyFile != ""
+}
+
+// GRPCConfig serves the gRPC API on its own port of server.host, with the TLS settings of the server
+type GRPCConfig struct {
+	Port int // 0 disables the gRPC server
+}
+
+type DatabaseConfig struct {
+	Username        string
+	Password        string
+	Host            string
+	Port            int
+	Name            string
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+	DialTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+
+	ReplicaDSN           string
+	ReplicaCheckInterval time.Duration
+
+	IsolationLevel string // default, read_uncommitted, read_committed, repeatable_read or serializable
+	MaxRetries     int
+	RetryBackoff   time.Duration
+}
+
+type AuthConfig struct {
+	APIKey         string
+	JWTSecret      string // HS256 secret of bearer tokens, they are not accepted when empty
+	JWTTenantClaim string
+	DefaultTenant  string // tenant of requests that do not name one, empty makes X-Tenant-ID required
+}
+
+type LocaleConfig struct {
+	Default string // locale the category names are written in
+}
+
+type CacheConfig struct {
+	Backend          string // none, memory or redis
+	TTL              time.Duration
+	Size             int
+	RedisAddress     string
+	RedisPassword    string
+	RedisDB          int
+	RedisPoolSize    int
+	RedisDialTimeout time.Duration
+}
+
+type TimeoutConfig struct {
+	Default time.Duration
+	Routes  map[string]time.Duration // keyed by "METHOD /path/:param" or a gRPC method
+}
+
+type IdempotencyConfig struct {
+	TTL     time.Duration
+	Size    int           // keys kept in memory when the cache backend is not redis
+	LockTTL time.Duration // how long a request keeps its key claimed if it never finishes
+}
+
+type RateLimit struct {
+	Rate  float64 // tokens refilled per second
+	Burst int
+}
+
+type RateLimitConfig struct {
+	Default     RateLimit
+	Routes      map[string]RateLimit // keyed by "METHOD /path/:param" or a gRPC method
+	Keys        map[string]RateLimit // keyed by API key
+	AuthFailure RateLimit            // failed authentications per client IP
+	IdleTimeout time.Duration
+}
+
+type CorsConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type CompressionConfig struct {
+	Encodings           []string // br, gzip or deflate in order of preference
+	MinSize             int
+	MaxDecompressedSize int64
+}
+
+type OpenAPIConfig struct {
+	ValidateRequests  bool
+	ValidateResponses bool
+}
+
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// setting binds one configuration value to its file key, flag name and environment variable
+type setting struct {
+	key string // file key and flag name
+	env string
+}
+
+// newFlagSet registers every setting with its default value, the flag names
+// double as the keys used in configuration files
+func newFlagSet(config *Config) (*flag.FlagSet, []setting) {
+	flagSet := flag.NewFlagSet("go-mysql-restful-api", flag.ContinueOnError)
+	settings := []setting{}
+
+	stringVar := func(pointer *string, key string, env string, value string, usage string) {
+		flagSet.StringVar(pointer, key, value, usage)
+		settings = append(settings, setting{key: key, env: env})
+	}
+	intVar := func(pointer *int, key string, env string, value int, usage string) {
+		flagSet.IntVar(pointer, key, value, usage)
+		settings = append(settings, setting{key: key, env: env})
+	}
+	int64Var := func(pointer *int64, key string, env string, value int64, usage string) {
+		flagSet.Int64Var(pointer, key, value, usage)
+		settings = append(settings, setting{key: key, env: env})
+	}
+	boolVar := func(pointer *bool, key string, env string, value bool, usage string) {
+		flagSet.BoolVar(pointer, key, value, usage)
+		settings = append(settings, setting{key: key, env: env})
+	}
+	durationVar := func(pointer *time.Duration, key string, env string, value time.Duration, usage string) {
+		flagSet.DurationVar(pointer, key, value, usage)
+		settings = append(settings, setting{key: key, env: env})
+	}
+	valueVar := func(value flag.Value, key string, env string, usage string) {
+		flagSet.Var(value, key, usage)
+		settings = append(settings, setting{key: key, env: env})
+	}
+
+	// sources
+	stringVar(&config.ConfigFile, "config", "CONFIG_FILE", "", "path to a YAML or JSON configuration file")
+	stringVar(&config.EnvFile, "env-file", "ENV_FILE", ".env", "path to a .env file, ignored when missing")
+
+	// server
+	stringVar(&config.Server.Host, "server.host", "SERVER_HOST", "localhost", "host the server listens on")
+	intVar(&config.Server.Port, "server.port", "SERVER_PORT", 3000, "port the server listens on")
+	durationVar(&config.Server.ReadHeaderTimeout, "server.read_header_timeout", "SERVER_READ_HEADER_TIMEOUT", 10*time.Second, "maximum duration for reading request headers")
+	durationVar(&config.Server.ReadTimeout, "server.read_timeout", "SERVER_READ_TIMEOUT", 30*time.Second, "maximum duration for reading the entire request")
+	durationVar(&config.Server.WriteTimeout, "server.write_timeout", "SERVER_WRITE_TIMEOUT", 30*time.Second, "maximum duration before timing out writes of the response")
+	int64Var(&config.Server.MaxBodySize, "server.max_body_size", "SERVER_MAX_BODY_SIZE", 1<<20, "maximum size in bytes of a request body")
+	durationVar(&config.Server.IdleTimeout, "server.idle_timeout", "SERVER_IDLE_TIMEOUT", 2*time.Minute, "maximum duration to wait for the next request on keep-alive connections")
+	boolVar(&config.Server.HTTP2, "server.http2", "SERVER_HTTP2", true, "serve HTTP/2 when TLS is enabled")
+
+	// tls
+	stringVar(&config.Server.TLS.CertFile, "server.tls.cert_file", "TLS_CERT_FILE", "", "PEM certificate file, enables TLS")
+	stringVar(&config.Server.TLS.KeyFile, "server.tls.key_file", "TLS_KEY_FILE", "", "PEM private key file, enables TLS")
+	durationVar(&config.Server.TLS.ReloadInterval, "server.tls.reload_interval", "TLS_RELOAD_INTERVAL", 30*time.Second, "how often certificate files are checked for changes, 0 disables reloading")
+	stringVar(&config.Server.TLS.ClientCAFile, "server.tls.client_ca_file", "TLS_CLIENT_CA_FILE", "", "PEM bundle of CAs trusted for client certificates")
+	stringVar(&config.Server.TLS.ClientAuth, "server.tls.client_auth", "TLS_CLIENT_AUTH", "none", "client certificate policy: none, request, require, verify_if_given or require_and_verify")
+	valueVar(&stringMapValue{&config.Server.TLS.ClientPrincipals}, "server.tls.client_principals", "TLS_CLIENT_PRINCIPALS", "client certificate common names mapped to principals as <common name>=<principal>, comma separated")
+
+	// grpc
+	intVar(&config.GRPC.Port, "grpc.port", "GRPC_PORT", 0, "port the gRPC server listens on, 0 disables it")
+
+	// database
+	stringVar(&config.Database.Username, "database.username", "DB_USERNAME", "", "MySQL username")
+	stringVar(&config.Database.Password, "database.password", "DB_PASSWORD", "", "MySQL password")
+	stringVar(&config.Database.Host, "database.host", "DB_HOST", "localhost", "MySQL host")
+	intVar(&config.Database.Port, "database.port", "DB_PORT", 3306, "MySQL port")
+	stringVar(&config.Database.Name, "database.name", "DB_NAME", "", "MySQL database name")
+	intVar(&config.Database.MaxIdleConns, "database.max_idle_conns", "DB_MAX_IDLE_CONNS", 5, "maximum number of idle connections")
+	intVar(&config.Database.MaxOpenConns, "database.max_open_conns", "DB_MAX_OPEN_CONNS", 20, "maximum number of open connections, 0 means unlimited")
+	durationVar(&config.Database.ConnMaxIdleTime, "database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", 10*time.Minute, "maximum time a connection may be idle")
+	durationVar(&config.Database.ConnMaxLifetime, "database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", time.Hour, "maximum time a connection may be reused")
+	durationVar(&config.Database.DialTimeout, "database.dial_timeout", "DB_DIAL_TIMEOUT", 5*time.Second, "timeout for establishing connections")
+	durationVar(&config.Database.ReadTimeout, "database.read_timeout", "DB_READ_TIMEOUT", 30*time.Second, "I/O read timeout")
+	durationVar(&config.Database.WriteTimeout, "database.write_timeout", "DB_WRITE_TIMEOUT", 30*time.Second, "I/O write timeout")
+	stringVar(&config.Database.ReplicaDSN, "database.replica_dsn", "DB_REPLICA_DSN", "", "DSN of a read replica, reads go to the primary when empty")
+	durationVar(&config.Database.ReplicaCheckInterval, "database.replica_check_interval", "DB_REPLICA_CHECK_INTERVAL", 10*time.Second, "how often the replica health is checked")
+	stringVar(&config.Database.IsolationLevel, "database.isolation_level", "DB_ISOLATION_LEVEL", "default", "transaction isolation level: default, read_uncommitted, read_committed, repeatable_read or serializable")
+	intVar(&config.Database.MaxRetries, "database.max_retries", "DB_MAX_RETRIES", 3, "how many times a transaction is retried after a deadlock or lock wait timeout")
+	durationVar(&config.Database.RetryBackoff, "database.retry_backoff", "DB_RETRY_BACKOFF", 50*time.Millisecond, "initial backoff between transaction retries, doubled on every retry")
+
+	// auth
+	stringVar(&config.Auth.APIKey, "auth.api_key", "API_KEY", "", "API key required in the X-API-Key header")
+	stringVar(&config.Auth.JWTSecret, "auth.jwt_secret", "JWT_SECRET", "", "HS256 secret of the bearer tokens, they are rejected when empty")
+	stringVar(&config.Auth.JWTTenantClaim, "auth.jwt_tenant_claim", "JWT_TENANT_CLAIM", "tenant_id", "claim of the bearer tokens holding the tenant")
+	stringVar(&config.Auth.DefaultTenant, "auth.default_tenant", "DEFAULT_TENANT", "default", "tenant of the requests without X-Tenant-ID, empty makes the header required")
+
+	// cache
+	stringVar(&config.Cache.Backend, "cache.backend", "CACHE_BACKEND", "none", "category cache backend: none, memory or redis")
+	durationVar(&config.Cache.TTL, "cache.ttl", "CACHE_TTL", time.Minute, "how long cached categories are kept")
+	intVar(&config.Cache.Size, "cache.size", "CACHE_SIZE", 10000, "maximum number of entries in the memory cache")
+	stringVar(&config.Cache.RedisAddress, "cache.redis_address", "CACHE_REDIS_ADDRESS", "localhost:6379", "address of the Redis compatible server")
+	stringVar(&config.Cache.RedisPassword, "cache.redis_password", "CACHE_REDIS_PASSWORD", "", "password of the Redis compatible server")
+	intVar(&config.Cache.RedisDB, "cache.redis_db", "CACHE_REDIS_DB", 0, "Redis database number")
+	intVar(&config.Cache.RedisPoolSize, "cache.redis_pool_size", "CACHE_REDIS_POOL_SIZE", 10, "maximum number of idle Redis connections")
+	durationVar(&config.Cache.RedisDialTimeout, "cache.redis_dial_timeout", "CACHE_REDIS_DIAL_TIMEOUT", 5*time.Second, "timeout for connecting to Redis")
+
+	// idempotency keys
+	durationVar(&config.Idempotency.TTL, "idempotency.ttl", "IDEMPOTENCY_TTL", 24*time.Hour, "how long an Idempotency-Key and its response are kept")
+	intVar(&config.Idempotency.Size, "idempotency.size", "IDEMPOTENCY_SIZE", 10000, "maximum number of idempotency keys kept in memory when the cache backend is not redis")
+	durationVar(&config.Idempotency.LockTTL, "idempotency.lock_ttl", "IDEMPOTENCY_LOCK_TTL", time.Minute, "how long an Idempotency-Key stays claimed by a request that never finished, such as one on an instance that stopped")
+
+	// request timeout
+	durationVar(&config.Timeout.Default, "timeout.default", "REQUEST_TIMEOUT", 15*time.Second, "deadline for handling a request, 0 disables it")
+	valueVar(&durationMapValue{&config.Timeout.Routes}, "timeout.routes", "REQUEST_TIMEOUT_ROUTES", "per route deadlines as <METHOD /path>=<duration> or <gRPC method>=<duration>, comma separated")
+
+	// rate limit
+	valueVar(&rateLimitValue{&config.RateLimit.Default}, "rate_limit.default", "RATE_LIMIT", "default limit per client as <rate>:<burst>")
+	valueVar(&rateLimitMapValue{&config.RateLimit.Routes}, "rate_limit.routes", "RATE_LIMIT_ROUTES", "per route limits as <METHOD /path>=<rate>:<burst> or <gRPC method>=<rate>:<burst>, comma separated")
+	valueVar(&rateLimitMapValue{&config.RateLimit.Keys}, "rate_limit.keys", "RATE_LIMIT_KEYS", "per API key limits as <key>=<rate>:<burst>, comma separated")
+	config.RateLimit.AuthFailure = RateLimit{Rate: 0.2, Burst: 20}
+	valueVar(&rateLimitValue{&config.RateLimit.AuthFailure}, "rate_limit.auth_failure", "RATE_LIMIT_AUTH_FAILURE", "failed authentications per client IP as <rate>:<burst>, checked before any API key lookup")
+	durationVar(&config.RateLimit.IdleTimeout, "rate_limit.idle_timeout", "RATE_LIMIT_IDLE_TIMEOUT", 10*time.Minute, "how long unused buckets are kept")
+
+	// cors
+	config.Cors.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE"}
+	config.Cors.AllowedHeaders = []string{"Content-Type", "Content-Encoding", "Authorization", "X-API-Key", "X-Tenant-ID", "Idempotency-Key"}
+	config.Cors.ExposedHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+	valueVar(&stringListValue{&config.Cors.AllowedOrigins}, "cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "origins allowed to call the API, * wildcards supported")
+	valueVar(&stringListValue{&config.Cors.AllowedMethods}, "cors.allowed_methods", "CORS_ALLOWED_METHODS", "methods allowed in preflight requests")
+	valueVar(&stringListValue{&config.Cors.AllowedHeaders}, "cors.allowed_headers", "CORS_ALLOWED_HEADERS", "request headers allowed in preflight requests")
+	valueVar(&stringListValue{&config.Cors.ExposedHeaders}, "cors.exposed_headers", "CORS_EXPOSED_HEADERS", "response headers readable by the browser")
+	boolVar(&config.Cors.AllowCredentials, "cors.allow_credentials", "CORS_ALLOW_CREDENTIALS", false, "whether browsers may send credentials")
+	durationVar(&config.Cors.MaxAge, "cors.max_age", "CORS_MAX_AGE", 0, "how long browsers may cache a preflight")
+
+	// openapi validation
+	boolVar(&config.OpenAPI.ValidateRequests, "openapi.validate_requests", "OPENAPI_VALIDATE_REQUESTS", false, "reject requests not matching apispec.json with 400")
+	boolVar(&config.OpenAPI.ValidateResponses, "openapi.validate_responses", "OPENAPI_VALIDATE_RESPONSES", false, "log responses not matching apispec.json, meant for development")
+
+	// graphql
+	intVar(&config.GraphQL.MaxDepth, "graphql.max_depth", "GRAPHQL_MAX_DEPTH", 10, "deepest nesting of fields a GraphQL query may have")
+	intVar(&config.GraphQL.MaxComplexity, "graphql.max_complexity", "GRAPHQL_MAX_COMPLEXITY", 2500, "fields a GraphQL query may resolve, every item of a page counted")
+
+	// locale
+	stringVar(&config.Locale.Default, "locale.default", "DEFAULT_LOCALE", "en", "locale of the category names, Accept-Language asking for it gets them untranslated")
+
+	// compression
+	config.Compression.Encodings = []string{"br", "gzip", "deflate"}
+	valueVar(&stringListValue{&config.Compression.Encodings}, "compression.encodings", "COMPRESSION_ENCODINGS", "response encodings in order of preference, empty disables response compression")
+	intVar(&config.Compression.MinSize, "compression.min_size", "COMPRESSION_MIN_SIZE", 1024, "responses smaller than this many bytes are sent uncompressed")
+	int64Var(&config.Compression.MaxDecompressedSize, "compression.max_decompressed_size", "COMPRESSION_MAX_DECOMPRESSED_SIZE", 1<<20, "maximum size in bytes of a compressed request body once decompressed, at most server.max_body_size")
+
+	return flagSet, settings
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from, in increasing order of precedence:
+// defaults, the configuration file, the .env file, environment variables
+// and command-line flags
+func Load(args []string) (Config, error) {
+
+	var config Config
+	flagSet, settings := newFlagSet(&config)
+
+	// the first pass only finds out where the configuration and .env files are
+	if err := flagSet.Parse(args); err != nil {
+		return config, err
+	}
+	explicit := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if value, ok := os.LookupEnv("ENV_FILE"); ok && !explicit["env-file"] {
+		config.EnvFile = value
+	}
+	dotenv, err := readEnvFile(config.EnvFile)
+	if err != nil {
+		return config, err
+	}
+
+	if !explicit["config"] {
+		if value, ok := os.LookupEnv("CONFIG_FILE"); ok {
+			config.ConfigFile = value
+		} else if value, ok := dotenv["CONFIG_FILE"]; ok {
+			config.ConfigFile = value
+		}
+	}
+
+	// configuration file
+	if config.ConfigFile != "" {
+		values, err := readConfigFile(config.ConfigFile, flagSet)
+		if err != nil {
+			return config, err
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if flagSet.Lookup(key) == nil {
+				return config, fmt.Errorf("%v: unknown setting %q", config.ConfigFile, key)
+			}
+			if err := flagSet.Set(key, values[key]); err != nil {
+				return config, fmt.Errorf("%v: %v: invalid value %q: %w", config.ConfigFile, key, values[key], err)
+			}
+		}
+	}
+
+	// .env file
+	for _, setting := range settings {
+		if value, ok := dotenv[setting.env]; ok {
+			if err := flagSet.Set(setting.key, value); err != nil {
+				return config, fmt.Errorf("%v: %v: invalid value %q: %w", config.EnvFile, setting.env, value, err)
+			}
+		}
+	}
+
+	// environment variables
+	for _, setting := range settings {
+		if value, ok := os.LookupEnv(setting.env); ok {
+			if err := flagSet.Set(setting.key, value); err != nil {
+				return config, fmt.Errorf("environment variable %v: invalid value %q: %w", setting.env, value, err)
+			}
+		}
+	}
+
+	// the second pass makes command-line flags win over everything else
+	if err := flagSet.Parse(args); err != nil {
+		return config, err
+	}
+	config.Args = flagSet.Args()
+
+	return config, config.Validate()
+}
+
+func readEnvFile(path string) (map[string]string, error) {
+	if path == "" {
+		return map[string]string{}, nil
+	}
+	values, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return values, nil
+}
+
+// readConfigFile flattens a YAML or JSON document into "section.key" settings
+func readConfigFile(path string, flagSet *flag.FlagSet) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	document := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &document)
+	case ".json":
+		err = json.Unmarshal(content, &document)
+	default:
+		return nil, fmt.Errorf("%v: unsupported configuration file format, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", document, flagSet, values)
+	return values, nil
+}
+
+func flatten(prefix string, value any, flagSet *flag.FlagSet, values map[string]string) {
+	switch typed := value.(type) {
+	case map[string]any:
+		// maps such as rate_limit.routes are settings on their own
+		if prefix != "" && flagSet.Lookup(prefix) != nil {
+			entries := []string{}
+			for key, item := range typed {
+				entries = append(entries, fmt.Sprintf("%v=%v", key, item))
+			}
+			sort.Strings(entries)
+			values[prefix] = strings.Join(entries, ",")
+			return
+		}
+		for key, item := range typed {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(key, item, flagSet, values)
+		}
+	case []any:
+		items := make([]string, 0, len(typed))
+		for _, item := range typed {
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(typed)
+	}
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+func (config Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	checkDuration := func(key string, value time.Duration) {
+		check(value >= 0, "%v: must not be negative, got %v", key, value)
+	}
+
+	// server
+	check(config.Server.Port >= 1 && config.Server.Port <= 65535, "server.port (SERVER_PORT): must be between 1 and 65535, got %v", config.Server.Port)
+	checkDuration("server.read_header_timeout (SERVER_READ_HEADER_TIMEOUT)", config.Server.ReadHeaderTimeout)
+	checkDuration("server.read_timeout (SERVER_READ_TIMEOUT)", config.Server.ReadTimeout)
+	checkDuration("server.write_timeout (SERVER_WRITE_TIMEOUT)", config.Server.WriteTimeout)
+	checkDuration("server.idle_timeout (SERVER_IDLE_TIMEOUT)", config.Server.IdleTimeout)
+	check(config.Server.MaxBodySize >= 1, "server.max_body_size (SERVER_MAX_BODY_SIZE): must be at least 1, got %v", config.Server.MaxBodySize)
+
+	// tls
+	tlsConfig := config.Server.TLS
+	check(
+		(tlsConfig.CertFile == "") == (tlsConfig.KeyFile == ""),
+		"server.tls.cert_file (TLS_CERT_FILE) and server.tls.key_file (TLS_KEY_FILE): must be set together",
+	)
+	checkDuration("server.tls.reload_interval (TLS_RELOAD_INTERVAL)", tlsConfig.ReloadInterval)
+	check(
+		slices.Contains([]string{"none", "request", "require", "verify_if_given", "require_and_verify"}, tlsConfig.ClientAuth),
+		"server.tls.client_auth (TLS_CLIENT_AUTH): must be one of none, request, require, verify_if_given or require_and_verify, got %q", tlsConfig.ClientAuth,
+	)
+	check(
+		tlsConfig.Enabled() || (tlsConfig.ClientCAFile == "" && tlsConfig.ClientAuth == "none"),
+		"server.tls.client_ca_file (TLS_CLIENT_CA_FILE) and server.tls.client_auth (TLS_CLIENT_AUTH): require TLS to be enabled",
+	)
+	check(
+		tlsConfig.ClientCAFile != "" || (tlsConfig.ClientAuth != "verify_if_given" && tlsConfig.ClientAuth != "require_and_verify"),
+		"server.tls.client_ca_file (TLS_CLIENT_CA_FILE): is required when server.tls.client_auth is %q", tlsConfig.ClientAuth,
+	)
+
+	// grpc
+	check(config.GRPC.Port >= 0 && config.GRPC.Port <= 65535, "grpc.port (GRPC_PORT): must be between 0 and 65535, got %v", config.GRPC.Port)
+	check(config.GRPC.Port != config.Server.Port, "grpc.port (GRPC_PORT): must differ from server.port, got %v", config.GRPC.Port)
+
+	// database
+	check(config.Database.Username != "", "database.username (DB_USERNAME): is required")
+	check(config.Database.Host != "", "database.host (DB_HOST): is required")
+	check(config.Database.Port >= 1 && config.Database.Port <= 65535, "database.port (DB_PORT): must be between 1 and 65535, got %v", config.Database.Port)
+	check(config.Database.Name != "", "database.name (DB_NAME): is required")
+	check(config.Database.MaxIdleConns >= 0, "database.max_idle_conns (DB_MAX_IDLE_CONNS): must not be negative, got %v", config.Database.MaxIdleConns)
+	check(config.Database.MaxOpenConns >= 0, "database.max_open_conns (DB_MAX_OPEN_CONNS): must not be negative, got %v", config.Database.MaxOpenConns)
+	check(
+		config.Database.MaxOpenConns == 0 || config.Database.MaxIdleConns <= config.Database.MaxOpenConns,
+		"database.max_idle_conns (DB_MAX_IDLE_CONNS): must not exceed database.max_open_conns (%v), got %v",
+		config.Database.MaxOpenConns, config.Database.MaxIdleConns,
+	)
+	checkDuration("database.conn_max_idle_time (DB_CONN_MAX_IDLE_TIME)", config.Database.ConnMaxIdleTime)
+	checkDuration("database.conn_max_lifetime (DB_CONN_MAX_LIFETIME)", config.Database.ConnMaxLifetime)
+	checkDuration("database.dial_timeout (DB_DIAL_TIMEOUT)", config.Database.DialTimeout)
+	checkDuration("database.read_timeout (DB_READ_TIMEOUT)", config.Database.ReadTimeout)
+	checkDuration("database.write_timeout (DB_WRITE_TIMEOUT)", config.Database.WriteTimeout)
+	check(
+		slices.Contains([]string{"default", "read_uncommitted", "read_committed", "repeatable_read", "serializable"}, config.Database.IsolationLevel),
+		"database.isolation_level (DB_ISOLATION_LEVEL): must be one of default, read_uncommitted, read_committed, repeatable_read or serializable, got %q", config.Database.IsolationLevel,
+	)
+	check(config.Database.MaxRetries >= 0, "database.max_retries (DB_MAX_RETRIES): must not be negative, got %v", config.Database.MaxRetries)
+	checkDuration("database.retry_backoff (DB_RETRY_BACKOFF)", config.Database.RetryBackoff)
+	if config.Database.ReplicaDSN != "" {
+		_, err := mysql.ParseDSN(config.Database.ReplicaDSN)
+		check(err == nil, "database.replica_dsn (DB_REPLICA_DSN): %v", err)
+		check(config.Database.ReplicaCheckInterval > 0, "database.replica_check_interval (DB_REPLICA_CHECK_INTERVAL): must be positive, got %v", config.Database.ReplicaCheckInterval)
+	}
+
+	// auth
+	check(config.Auth.APIKey != "", "auth.api_key (API_KEY): is required")
+	check(config.Auth.JWTSecret == "" || len(config.Auth.JWTSecret) >= 32, "auth.jwt_secret (JWT_SECRET): must be at least 32 bytes")
+	check(config.Auth.JWTSecret == "" || config.Auth.JWTTenantClaim != "", "auth.jwt_tenant_claim (JWT_TENANT_CLAIM): is required when auth.jwt_secret is set")
+	check(
+		config.Auth.DefaultTenant == "" || tenant.Valid(config.Auth.DefaultTenant),
+		"auth.default_tenant (DEFAULT_TENANT): must be lowercase letters, digits, - and _, got %q", config.Auth.DefaultTenant,
+	)
+
+	// cache
+	check(
+		slices.Contains([]string{"none", "memory", "redis"}, config.Cache.Backend),
+		"cache.backend (CACHE_BACKEND): must be one of none, memory or redis, got %q", config.Cache.Backend,
+	)
+	checkDuration("cache.ttl (CACHE_TTL)", config.Cache.TTL)
+	check(config.Cache.Size >= 0, "cache.size (CACHE_SIZE): must not be negative, got %v", config.Cache.Size)
+	check(config.Cache.Backend != "redis" || config.Cache.RedisAddress != "", "cache.redis_address (CACHE_REDIS_ADDRESS): is required when cache.backend is redis")
+	check(config.Cache.RedisDB >= 0, "cache.redis_db (CACHE_REDIS_DB): must not be negative, got %v", config.Cache.RedisDB)
+	check(config.Cache.RedisPoolSize >= 1, "cache.redis_pool_size (CACHE_REDIS_POOL_SIZE): must be at least 1, got %v", config.Cache.RedisPoolSize)
+	checkDuration("cache.redis_dial_timeout (CACHE_REDIS_DIAL_TIMEOUT)", config.Cache.RedisDialTimeout)
+
+	// idempotency keys
+	check(config.Idempotency.TTL > 0, "idempotency.ttl (IDEMPOTENCY_TTL): must be positive, got %v", config.Idempotency.TTL)
+	check(config.Idempotency.Size >= 1, "idempotency.size (IDEMPOTENCY_SIZE): must be at least 1, got %v", config.Idempotency.Size)
+	check(config.Idempotency.LockTTL > 0, "idempotency.lock_ttl (IDEMPOTENCY_LOCK_TTL): must be positive, got %v", config.Idempotency.LockTTL)
+	check(config.Idempotency.LockTTL <= config.Idempotency.TTL, "idempotency.lock_ttl (IDEMPOTENCY_LOCK_TTL): must not exceed idempotency.ttl (%v), got %v", config.Idempotency.TTL, config.Idempotency.LockTTL)
+
+	// request timeout, the response must be written before the server drops the connection
+	checkTimeout := func(key string, value time.Duration) {
+		checkDuration(key, value)
+		check(
+			config.Server.WriteTimeout == 0 || value < config.Server.WriteTimeout,
+			"%v: must be shorter than server.write_timeout (%v), got %v", key, config.Server.WriteTimeout, value,
+		)
+		// a request must not outlive the claim on its idempotency key
+		check(
+			value < config.Idempotency.LockTTL,
+			"%v: must be shorter than idempotency.lock_ttl (%v), got %v", key, config.Idempotency.LockTTL, value,
+		)
+	}
+	checkTimeout("timeout.default (REQUEST_TIMEOUT)", config.Timeout.Default)
+	for route, timeout := range config.Timeout.Routes {
+		checkTimeout(fmt.Sprintf("timeout.routes (REQUEST_TIMEOUT_ROUTES) %q", route), timeout)
+	}
+
+	// rate limit
+	checkDuration("rate_limit.idle_timeout (RATE_LIMIT_IDLE_TIMEOUT)", config.RateLimit.IdleTimeout)
+
+	// cors, browsers would send the cookies and credentials of any site's visitors
+	checkDuration("cors.max_age (CORS_MAX_AGE)", config.Cors.MaxAge)
+	check(
+		!config.Cors.AllowCredentials || !slices.Contains(config.Cors.AllowedOrigins, "*"),
+		"cors.allow_credentials (CORS_ALLOW_CREDENTIALS): cannot be true while cors.allowed_origins (CORS_ALLOWED_ORIGINS) has *, list the origins instead",
+	)
+
+	// graphql
+	check(config.GraphQL.MaxDepth >= 1, "graphql.max_depth (GRAPHQL_MAX_DEPTH): must be at least 1, got %v", config.GraphQL.MaxDepth)
+	check(config.GraphQL.MaxComplexity >= 1, "graphql.max_complexity (GRAPHQL_MAX_COMPLEXITY): must be at least 1, got %v", config.GraphQL.MaxComplexity)
+
+	// locale
+	defaultLocale, err := locale.Canonical(config.Locale.Default)
+	check(err == nil && defaultLocale == config.Locale.Default, "locale.default (DEFAULT_LOCALE): must be a canonical BCP 47 language tag such as en or pt-BR, got %q", config.Locale.Default)
+
+	// compression
+	for _, encoding := range config.Compression.Encodings {
+		check(slices.Contains([]string{"br", "gzip", "deflate"}, encoding), "compression.encodings (COMPRESSION_ENCODINGS): must only contain br, gzip or deflate, got %q", encoding)
+	}
+	check(config.Compression.MinSize >= 0, "compression.min_size (COMPRESSION_MIN_SIZE): must not be negative, got %v", config.Compression.MinSize)
+	check(config.Compression.MaxDecompressedSize >= 1, "compression.max_decompressed_size (COMPRESSION_MAX_DECOMPRESSED_SIZE): must be at least 1, got %v", config.Compression.MaxDecompressedSize)
+	// decompressed bodies are read through the body limit as well, a larger cap would never be reached
+	check(config.Compression.MaxDecompressedSize <= config.Server.MaxBodySize, "compression.max_decompressed_size (COMPRESSION_MAX_DECOMPRESSED_SIZE): must not exceed server.max_body_size (%v), got %v", config.Server.MaxBodySize, config.Compression.MaxDecompressedSize)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
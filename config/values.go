@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stringListValue is a comma separated list
+type stringListValue struct {
+	values *[]string
+}
+
+func (value *stringListValue) String() string {
+	if value.values == nil {
+		return ""
+	}
+	return strings.Join(*value.values, ",")
+}
+
+func (value *stringListValue) Set(text string) error {
+	values := []string{}
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*value.values = values
+	return nil
+}
+
+// stringMapValue is a comma separated list of <name>=<value>
+type stringMapValue struct {
+	values *map[string]string
+}
+
+func (value *stringMapValue) String() string {
+	if value.values == nil {
+		return ""
+	}
+	entries := []string{}
+	for name, item := range *value.values {
+		entries = append(entries, name+"="+item)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (value *stringMapValue) Set(text string) error {
+	values := map[string]string{}
+	for _, entry := range strings.Split(text, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, item, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid entry %q, expected <name>=<value>", entry)
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(item)
+	}
+	*value.values = values
+	return nil
+}
+
+// durationMapValue is a comma separated list of <name>=<duration>
+type durationMapValue struct {
+	durations *map[string]time.Duration
+}
+
+func (value *durationMapValue) String() string {
+	if value.durations == nil {
+		return ""
+	}
+	entries := []string{}
+	for name, duration := range *value.durations {
+		entries = append(entries, name+"="+duration.String())
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (value *durationMapValue) Set(text string) error {
+	durations := map[string]time.Duration{}
+	for _, entry := range strings.Split(text, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "=")
+		if index <= 0 {
+			return fmt.Errorf("invalid entry %q, expected <name>=<duration>", entry)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(entry[index+1:]))
+		if err != nil {
+			return err
+		}
+		durations[strings.TrimSpace(entry[:index])] = duration
+	}
+	*value.durations = durations
+	return nil
+}
+
+// rateLimitValue is a limit written as <rate>:<burst>
+type rateLimitValue struct {
+	limit *RateLimit
+}
+
+func (value *rateLimitValue) String() string {
+	if value.limit == nil || value.limit.Rate <= 0 {
+		return ""
+	}
+	return formatRateLimit(*value.limit)
+}
+
+func (value *rateLimitValue) Set(text string) error {
+	if strings.TrimSpace(text) == "" {
+		*value.limit = RateLimit{}
+		return nil
+	}
+	limit, err := parseRateLimit(text)
+	if err != nil {
+		return err
+	}
+	*value.limit = limit
+	return nil
+}
+
+// rateLimitMapValue is a comma separated list of <name>=<rate>:<burst>
+type rateLimitMapValue struct {
+	limits *map[string]RateLimit
+}
+
+func (value *rateLimitMapValue) String() string {
+	if value.limits == nil {
+		return ""
+	}
+	entries := []string{}
+	for name, limit := range *value.limits {
+		entries = append(entries, name+"="+formatRateLimit(limit))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (value *rateLimitMapValue) Set(text string) error {
+	limits := map[string]RateLimit{}
+	for _, entry := range strings.Split(text, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "=")
+		if index <= 0 {
+			return fmt.Errorf("invalid entry %q, expected <name>=<rate>:<burst>", entry)
+		}
+		limit, err := parseRateLimit(entry[index+1:])
+		if err != nil {
+			return err
+		}
+		limits[strings.TrimSpace(entry[:index])] = limit
+	}
+	*value.limits = limits
+	return nil
+}
+
+func parseRateLimit(text string) (RateLimit, error) {
+	var limit RateLimit
+
+	rate, burst, ok := strings.Cut(strings.TrimSpace(text), ":")
+	if !ok {
+		return limit, fmt.Errorf("invalid limit %q, expected <rate>:<burst>", text)
+	}
+
+	parsedRate, err := strconv.ParseFloat(rate, 64)
+	if err != nil || parsedRate <= 0 {
+		return limit, fmt.Errorf("invalid rate %q", rate)
+	}
+	parsedBurst, err := strconv.Atoi(burst)
+	if err != nil || parsedBurst < 1 {
+		return limit, fmt.Errorf("invalid burst %q", burst)
+	}
+
+	limit.Rate = parsedRate
+	limit.Burst = parsedBurst
+	return limit, nil
+}
+
+func formatRateLimit(limit RateLimit) string {
+	return strconv.FormatFloat(limit.Rate, 'f', -1, 64) + ":" + strconv.Itoa(limit.Burst)
+}
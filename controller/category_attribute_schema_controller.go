@@ -0,0 +1,15 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CategoryAttributeSchemaController serves the schema of the tenant and the schemas of category
+// types, the routes of the tenant have no type parameter
+type CategoryAttributeSchemaController interface {
+	Save(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	FindByType(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+type CategoryAttributeSchemaControllerImpl struct {
+	CategoryAttributeSchemaService service.CategoryAttributeSchemaService
+}
+
+func NewCategoryAttributeSchemaController(categoryAttributeSchemaService service.CategoryAttributeSchemaService) CategoryAttributeSchemaController {
+	return &CategoryAttributeSchemaControllerImpl{
+		CategoryAttributeSchemaService: categoryAttributeSchemaService,
+	}
+}
+
+func (controller *CategoryAttributeSchemaControllerImpl) Save(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	// decode the request body to CategoryAttributeSchemaSaveRequest
+	saveRequest := web.CategoryAttributeSchemaSaveRequest{}
+	decodeRequest(request, &saveRequest)
+	saveRequest.CategoryType = params.ByName("type")
+
+	schemaResponse, err := controller.CategoryAttributeSchemaService.Save(request.Context(), saveRequest)
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   schemaResponse,
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+func (controller *CategoryAttributeSchemaControllerImpl) Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	err := controller.CategoryAttributeSchemaService.Delete(request.Context(), params.ByName("type"))
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+func (controller *CategoryAttributeSchemaControllerImpl) FindByType(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	schemaResponse, err := controller.CategoryAttributeSchemaService.FindByType(request.Context(), params.ByName("type"))
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   schemaResponse,
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
@@ -9,7 +9,9 @@ import (
 type CategoryController interface {
 	Create(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 	Update(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	Move(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 	DeleteById(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 	FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 	FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	FindBySlug(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 }
@@ -1,11 +1,14 @@
 package controller
 
 import (
-	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/rozanlaudzai/go-mysql-restful-api/codec"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
 	"github.com/rozanlaudzai/go-mysql-restful-api/service"
 )
@@ -22,13 +25,11 @@ func NewCategoryController(categoryService service.CategoryService) CategoryCont
 
 func (controller *CategoryControllerImpl) Create(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 
-	// decode json to CategoryCreateRequest
-	decoder := json.NewDecoder(request.Body)
+	responseCodec := negotiate(request)
+
+	// decode the request body to CategoryCreateRequest
 	categoryCreateRequest := web.CategoryCreateRequest{}
-	err := decoder.Decode(&categoryCreateRequest)
-	if err != nil {
-		panic(err)
-	}
+	decodeRequest(request, &categoryCreateRequest)
 
 	categoryResponse, err := controller.CategoryService.Create(request.Context(), categoryCreateRequest)
 	if err != nil {
@@ -40,22 +41,16 @@ func (controller *CategoryControllerImpl) Create(writer http.ResponseWriter, req
 		Data:   categoryResponse,
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	// encode webResponse to json
-	if err := json.NewEncoder(writer).Encode(webResponse); err != nil {
-		panic(err)
-	}
+	writeResponse(writer, responseCodec, webResponse)
 }
 
 func (controller *CategoryControllerImpl) Update(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 
-	// decode json to CategoryUpdateRequest
-	decoder := json.NewDecoder(request.Body)
+	responseCodec := negotiate(request)
+
+	// decode the request body to CategoryUpdateRequest
 	categoryUpdateRequest := web.CategoryUpdateRequest{}
-	err := decoder.Decode(&categoryUpdateRequest)
-	if err != nil {
-		panic(err)
-	}
+	decodeRequest(request, &categoryUpdateRequest)
 
 	// get the category id
 	categoryIdString := params.ByName("categoryId")
@@ -76,15 +71,43 @@ func (controller *CategoryControllerImpl) Update(writer http.ResponseWriter, req
 		Data:   categoryResponse,
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	// encode webResponse to json
-	if err := json.NewEncoder(writer).Encode(webResponse); err != nil {
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+func (controller *CategoryControllerImpl) Move(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	// decode the request body to CategoryMoveRequest
+	categoryMoveRequest := web.CategoryMoveRequest{}
+	decodeRequest(request, &categoryMoveRequest)
+
+	// get the category id
+	categoryIdString := params.ByName("categoryId")
+	categoryId, err := strconv.Atoi(categoryIdString)
+	if err != nil {
 		panic(err)
 	}
+
+	categoryMoveRequest.Id = categoryId
+
+	categoryMove, err := controller.CategoryService.Move(request.Context(), categoryMoveRequest)
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   categoryMove.Category,
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
 }
 
 func (controller *CategoryControllerImpl) DeleteById(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 
+	responseCodec := negotiate(request)
+
 	// get the category id
 	categoryIdString := params.ByName("categoryId")
 	categoryId, err := strconv.Atoi(categoryIdString)
@@ -101,15 +124,13 @@ func (controller *CategoryControllerImpl) DeleteById(writer http.ResponseWriter,
 		Status: "OK",
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	// encode webResponse to json
-	if err := json.NewEncoder(writer).Encode(webResponse); err != nil {
-		panic(err)
-	}
+	writeResponse(writer, responseCodec, webResponse)
 }
 
 func (controller *CategoryControllerImpl) FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 
+	responseCodec := negotiate(request)
+
 	// get the category id
 	categoryIdString := params.ByName("categoryId")
 	categoryId, err := strconv.Atoi(categoryIdString)
@@ -127,16 +148,17 @@ func (controller *CategoryControllerImpl) FindById(writer http.ResponseWriter, r
 		Data:   categoryResponse,
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	// encode webResponse to json
-	if err := json.NewEncoder(writer).Encode(webResponse); err != nil {
-		panic(err)
-	}
+	writeResponse(writer, responseCodec, webResponse)
 }
 
 func (controller *CategoryControllerImpl) FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 
-	categoryResponses, err := controller.CategoryService.FindAll(request.Context())
+	responseCodec := negotiate(request)
+
+	// filters come from the query string
+	categoryListRequest := decodeListRequest(request)
+
+	categoryResponses, err := controller.CategoryService.FindAll(request.Context(), categoryListRequest)
 	if err != nil {
 		panic(err)
 	}
@@ -146,9 +168,83 @@ func (controller *CategoryControllerImpl) FindAll(writer http.ResponseWriter, re
 		Data:   categoryResponses,
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	// encode webResponse to json
-	if err := json.NewEncoder(writer).Encode(webResponse); err != nil {
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+func (controller *CategoryControllerImpl) FindBySlug(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	categorySlug := params.ByName("slug")
+	categoryResponse, err := controller.CategoryService.FindBySlug(request.Context(), categorySlug)
+	if err != nil {
 		panic(err)
 	}
+
+	// a slug the category had before moved permanently to its current one
+	if categoryResponse.Slug != categorySlug {
+		writer.Header().Set("Location", "/api/categories/by-slug/"+url.PathEscape(categoryResponse.Slug))
+		webResponse := web.WebResponse{
+			Code:   http.StatusMovedPermanently,
+			Status: "MOVED PERMANENTLY",
+			Data:   categoryResponse,
+		}
+		writeResponse(writer, responseCodec, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   categoryResponse,
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+// decodeListRequest reads the filters of the list, a value that does not parse is a bad request
+func decodeListRequest(request *http.Request) web.CategoryListRequest {
+	query := request.URL.Query()
+	listRequest := web.CategoryListRequest{}
+
+	// an empty type asks for the categories without one
+	if query.Has("type") {
+		categoryType := query.Get("type")
+		listRequest.Type = &categoryType
+	}
+	if value := query.Get("is_active"); value != "" {
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			panic(codec.NewDecodeError("is_active must be true or false"))
+		}
+		listRequest.IsActive = &isActive
+	}
+	for _, parameter := range []struct {
+		name  string
+		field **time.Time
+	}{
+		{"created_after", &listRequest.CreatedAfter},
+		{"created_before", &listRequest.CreatedBefore},
+		{"updated_after", &listRequest.UpdatedAfter},
+		{"updated_before", &listRequest.UpdatedBefore},
+	} {
+		if value := query.Get(parameter.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				panic(codec.NewDecodeError(parameter.name + " must be an RFC 3339 date and time, such as 2026-01-02T15:04:05Z"))
+			}
+			*parameter.field = &parsed
+		}
+	}
+
+	// attr.color=red&attr.color=blue matches either color
+	for name, values := range query {
+		if path, ok := strings.CutPrefix(name, "attr."); ok {
+			if listRequest.Attributes == nil {
+				listRequest.Attributes = map[string][]string{}
+			}
+			listRequest.Attributes[path] = values
+		}
+	}
+	return listRequest
 }
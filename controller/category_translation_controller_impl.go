@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+type CategoryTranslationControllerImpl struct {
+	CategoryTranslationService service.CategoryTranslationService
+}
+
+func NewCategoryTranslationController(categoryTranslationService service.CategoryTranslationService) CategoryTranslationController {
+	return &CategoryTranslationControllerImpl{
+		CategoryTranslationService: categoryTranslationService,
+	}
+}
+
+func (controller *CategoryTranslationControllerImpl) Save(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	// decode the request body to CategoryTranslationSaveRequest
+	saveRequest := web.CategoryTranslationSaveRequest{}
+	decodeRequest(request, &saveRequest)
+
+	// get the category id and the locale
+	categoryId, err := strconv.Atoi(params.ByName("categoryId"))
+	if err != nil {
+		panic(err)
+	}
+	saveRequest.CategoryId = categoryId
+	saveRequest.Locale = params.ByName("locale")
+
+	translationResponse, err := controller.CategoryTranslationService.Save(request.Context(), saveRequest)
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   translationResponse,
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+func (controller *CategoryTranslationControllerImpl) Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	// get the category id
+	categoryId, err := strconv.Atoi(params.ByName("categoryId"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = controller.CategoryTranslationService.Delete(request.Context(), categoryId, params.ByName("locale"))
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
+
+func (controller *CategoryTranslationControllerImpl) FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+
+	responseCodec := negotiate(request)
+
+	// get the category id
+	categoryId, err := strconv.Atoi(params.ByName("categoryId"))
+	if err != nil {
+		panic(err)
+	}
+
+	translationResponses, err := controller.CategoryTranslationService.FindAll(request.Context(), categoryId)
+	if err != nil {
+		panic(err)
+	}
+	webResponse := web.WebResponse{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   translationResponses,
+	}
+
+	writeResponse(writer, responseCodec, webResponse)
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/codec"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+// negotiate runs before the service is called, so an unsupported Accept header changes nothing
+func negotiate(request *http.Request) codec.Codec {
+	responseCodec, err := codec.Negotiate(request.Header.Get("Accept"))
+	if err != nil {
+		panic(err)
+	}
+	return responseCodec
+}
+
+func decodeRequest(request *http.Request, value any) {
+	if err := codec.DecodeRequest(request, value); err != nil {
+		panic(err)
+	}
+}
+
+func writeResponse(writer http.ResponseWriter, responseCodec codec.Codec, webResponse web.WebResponse) {
+	if err := codec.Write(writer, responseCodec, webResponse.Code, webResponse); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one version of the schema, Up applies it and Down reverts it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // nil while pending
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads NNNN_name.up.sql and NNNN_name.down.sql files, ordered by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%v has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies migrations in order and records them in the schema_migration table.
+// MySQL commits DDL implicitly, so a migration that fails halfway is not rolled back
+// and has to be fixed by hand before running again.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		DB:         db,
+		Migrations: migrations,
+	}
+}
+
+// Status lists every migration with the time it was applied
+func (migrator *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrator.Migrations))
+	for _, migration := range migrator.Migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations and returns them
+func (migrator *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range migrator.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := migrator.exec(ctx, migration.Up); err != nil {
+			return done, fmt.Errorf("migration %d_%v: %w", migration.Version, migration.Name, err)
+		}
+		query := "INSERT INTO schema_migration (version, name, applied_at) VALUES (?, ?, ?)"
+		if _, err := migrator.DB.ExecContext(ctx, query, migration.Version, migration.Name, time.Now().UTC()); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them
+func (migrator *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range slices.Backward(migrator.Migrations) {
+		if len(done) >= steps {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %d_%v has no down file", migration.Version, migration.Name)
+		}
+		if err := migrator.exec(ctx, migration.Down); err != nil {
+			return done, fmt.Errorf("migration %d_%v: %w", migration.Version, migration.Name, err)
+		}
+		query := "DELETE FROM schema_migration WHERE version = ?"
+		if _, err := migrator.DB.ExecContext(ctx, query, migration.Version); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func (migrator *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	query := `CREATE TABLE IF NOT EXISTS schema_migration (
+    version INT PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    applied_at DATETIME NOT NULL
+) ENGINE = InnoDB`
+	if _, err := migrator.DB.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	rows, err := migrator.DB.QueryContext(ctx, "SELECT version, applied_at FROM schema_migration")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// exec runs the statements of a migration file one by one, a statement ends with a semicolon at the end of a line
+func (migrator *Migrator) exec(ctx context.Context, script string) error {
+	statement := strings.Builder{}
+	for line := range strings.Lines(script) {
+		statement.WriteString(line)
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasSuffix(trimmed, ";") {
+			continue
+		}
+		query := strings.TrimSuffix(strings.TrimSpace(statement.String()), ";")
+		statement.Reset()
+		if _, err := migrator.DB.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	if query := strings.TrimSpace(statement.String()); query != "" {
+		_, err := migrator.DB.ExecContext(ctx, query)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Replica is a read replica that queries are routed to while it is healthy
+type Replica struct {
+	DB *sql.DB
+
+	healthy atomic.Bool
+}
+
+func NewReplica(db *sql.DB) *Replica {
+	replica := &Replica{DB: db}
+	replica.healthy.Store(true)
+	return replica
+}
+
+func (replica *Replica) Healthy() bool {
+	return replica.healthy.Load()
+}
+
+func (replica *Replica) MarkUnhealthy(err error) {
+	if replica.healthy.Swap(false) {
+		log.Printf("database: replica marked unhealthy, reads go to the primary: %v", err)
+	}
+}
+
+// Check pings the replica and updates its health
+func (replica *Replica) Check(ctx context.Context) bool {
+	if err := replica.DB.PingContext(ctx); err != nil {
+		replica.MarkUnhealthy(err)
+		return false
+	}
+	if !replica.healthy.Swap(true) {
+		log.Printf("database: replica is healthy again")
+	}
+	return true
+}
+
+// Watch checks the replica every interval until the context is done
+func (replica *Replica) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			replica.Check(checkCtx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var ErrReadOnlyTransaction = errors.New("database: cannot write inside a read-only transaction")
+
+// TransactionManager runs callbacks inside a transaction: it commits when the
+// callback succeeds, rolls back on error or panic and retries deadlocks.
+// Calls nested through the callback context use savepoints of the outer transaction.
+type TransactionManager struct {
+	DB           *sql.DB
+	Replica      *Replica // optional, used for read-only transactions while healthy
+	Isolation    sql.IsolationLevel
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+type transactionState struct {
+	tx         *sql.Tx
+	readOnly   bool
+	savepoints int
+}
+
+type transactionContextKey struct{}
+
+func NewTransactionManager(db *sql.DB, replica *Replica) *TransactionManager {
+	return &TransactionManager{
+		DB:           db,
+		Replica:      replica,
+		Isolation:    sql.LevelDefault,
+		MaxRetries:   3,
+		RetryBackoff: 50 * time.Millisecond,
+	}
+}
+
+// Do runs fn in a read-write transaction on the primary
+func (manager *TransactionManager) Do(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return manager.run(ctx, false, fn)
+}
+
+// Read runs fn in a read-only transaction on the replica, or on the primary
+// when there is no healthy replica
+func (manager *TransactionManager) Read(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return manager.run(ctx, true, fn)
+}
+
+func (manager *TransactionManager) run(ctx context.Context, readOnly bool, fn func(ctx context.Context, tx *sql.Tx) error) error {
+
+	// nested call, reuse the outer transaction
+	if state, ok := ctx.Value(transactionContextKey{}).(*transactionState); ok {
+		if state.readOnly && !readOnly {
+			return ErrReadOnlyTransaction
+		}
+		return manager.savepoint(ctx, state, fn)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := manager.attempt(ctx, readOnly, fn)
+		if err == nil || attempt >= manager.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		// exponential backoff with jitter before running the whole callback again
+		backoff := manager.RetryBackoff << attempt
+		if backoff > 0 {
+			backoff = backoff/2 + rand.N(backoff/2+1)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (manager *TransactionManager) attempt(ctx context.Context, readOnly bool, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	tx, err := manager.begin(ctx, readOnly)
+	if err != nil {
+		return err
+	}
+
+	// rollback on panic, the panic keeps going up to the panic handler
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			_ = tx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	state := &transactionState{tx: tx, readOnly: readOnly}
+	if err := fn(context.WithValue(ctx, transactionContextKey{}, state), tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (manager *TransactionManager) begin(ctx context.Context, readOnly bool) (*sql.Tx, error) {
+	options := &sql.TxOptions{Isolation: manager.Isolation, ReadOnly: readOnly}
+
+	if readOnly && manager.Replica != nil && manager.Replica.Healthy() {
+		tx, err := manager.Replica.DB.BeginTx(ctx, options)
+		if err == nil || ctx.Err() != nil {
+			return tx, err
+		}
+		manager.Replica.MarkUnhealthy(err)
+	}
+
+	return manager.DB.BeginTx(ctx, options)
+}
+
+func (manager *TransactionManager) savepoint(ctx context.Context, state *transactionState, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	rollback := func() error {
+		_, err := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			_ = rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err := fn(ctx, state.tx); err != nil {
+		if rollbackErr := rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// IsRetryable reports whether err is a MySQL deadlock (1213) or lock wait timeout (1205)
+func IsRetryable(err error) bool {
+	var mysqlError *mysql.MySQLError
+	if !errors.As(err, &mysqlError) {
+		return false
+	}
+	return mysqlError.Number == 1213 || mysqlError.Number == 1205
+}
@@ -0,0 +1,16 @@
+package exception
+
+// BadRequestError is a request the validator cannot reject on its own, such as attributes not matching their schema
+type BadRequestError struct {
+	Message string
+}
+
+func (err BadRequestError) Error() string {
+	return err.Message
+}
+
+func NewBadRequestError(message string) BadRequestError {
+	return BadRequestError{
+		Message: message,
+	}
+}
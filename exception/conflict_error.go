@@ -0,0 +1,16 @@
+package exception
+
+// ConflictError is a write clashing with existing data, such as a name that is taken
+type ConflictError struct {
+	Message string
+}
+
+func (err ConflictError) Error() string {
+	return err.Message
+}
+
+func NewConflictError(message string) ConflictError {
+	return ConflictError{
+		Message: message,
+	}
+}
@@ -1,20 +1,55 @@
 package exception
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/codec"
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
 )
 
 func ErrorHandler(writer http.ResponseWriter, request *http.Request, err any) {
 
 	switch errAssert := err.(type) {
 	case NotFoundError:
-		WriteErrorResponse(writer, http.StatusNotFound, "NOT FOUND", errAssert.Error()) // data message is always safe because it is my creation
+		WriteErrorResponse(writer, request, http.StatusNotFound, "NOT FOUND", errAssert.Error()) // data message is always safe because it is my creation
+	case ConflictError:
+		WriteErrorResponse(writer, request, http.StatusConflict, "CONFLICT", errAssert.Error())
+	case BadRequestError:
+		WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", errAssert.Error())
 	case validator.ValidationErrors:
-		WriteErrorResponse(writer, http.StatusBadRequest, "BAD REQUEST", "invalid fields")
+		WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", locale.ValidationMessage(request.Context(), errAssert))
+	case codec.DecodeError:
+		WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", errAssert.Error())
+	case error:
+		writeErrorValueResponse(writer, request, errAssert)
 	default:
-		WriteErrorResponse(writer, http.StatusInternalServerError, "INTERNAL SERVER ERROR", "internal server error")
+		WriteErrorResponse(writer, request, http.StatusInternalServerError, "INTERNAL SERVER ERROR", "internal server error")
 	}
 
 }
+
+// writeErrorValueResponse reports unsupported media types, oversized bodies, requests without a tenant and requests that ran out of time or were cancelled
+func writeErrorValueResponse(writer http.ResponseWriter, request *http.Request, err error) {
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		WriteErrorResponse(writer, request, http.StatusRequestEntityTooLarge, "REQUEST ENTITY TOO LARGE", fmt.Sprintf("request body must not be larger than %d bytes", maxBytesError.Limit))
+	case errors.Is(err, codec.ErrNotAcceptable):
+		WriteErrorResponse(writer, request, http.StatusNotAcceptable, "NOT ACCEPTABLE", err.Error())
+	case errors.Is(err, codec.ErrUnsupportedMediaType):
+		WriteErrorResponse(writer, request, http.StatusUnsupportedMediaType, "UNSUPPORTED MEDIA TYPE", err.Error())
+	case errors.Is(err, tenant.ErrMissing):
+		WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", "a tenant is required")
+	case errors.Is(err, context.DeadlineExceeded):
+		WriteErrorResponse(writer, request, http.StatusGatewayTimeout, "GATEWAY TIMEOUT", "request timed out")
+	case errors.Is(err, context.Canceled):
+		WriteErrorResponse(writer, request, http.StatusServiceUnavailable, "SERVICE UNAVAILABLE", "request cancelled")
+	default:
+		WriteErrorResponse(writer, request, http.StatusInternalServerError, "INTERNAL SERVER ERROR", "internal server error")
+	}
+}
@@ -0,0 +1,14 @@
+package exception
+
+import "net/http"
+
+// RouteNotFound answers requests no route matches, in the format of the other errors
+func RouteNotFound(writer http.ResponseWriter, request *http.Request) {
+	WriteErrorResponse(writer, request, http.StatusNotFound, "NOT FOUND", "route not found")
+}
+
+// MethodNotAllowed answers requests for a route without their method, httprouter has already
+// set the Allow header
+func MethodNotAllowed(writer http.ResponseWriter, request *http.Request) {
+	WriteErrorResponse(writer, request, http.StatusMethodNotAllowed, "METHOD NOT ALLOWED", "method not allowed")
+}
@@ -1,15 +1,18 @@
 package exception
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/rozanlaudzai/go-mysql-restful-api/codec"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
 )
 
-func WriteErrorResponse(writer http.ResponseWriter, statusCode int, status string, data string) {
-	writer.Header().Set("Content-Type", "application/json")
-	writer.WriteHeader(statusCode)
+// WriteErrorResponse encodes the error in the media type the client accepts, JSON when none is supported
+func WriteErrorResponse(writer http.ResponseWriter, request *http.Request, statusCode int, status string, data string) {
+	responseCodec, err := codec.Negotiate(request.Header.Get("Accept"))
+	if err != nil {
+		responseCodec = codec.JSON
+	}
 
 	webResponse := web.WebResponse{
 		Code:   statusCode,
@@ -17,8 +20,7 @@ func WriteErrorResponse(writer http.ResponseWriter, statusCode int, status strin
 		Data:   data,
 	}
 
-	// encode webResponse to json
-	if err := json.NewEncoder(writer).Encode(webResponse); err != nil {
+	if err := codec.Write(writer, responseCodec, statusCode, webResponse); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,113 @@
+package fixtures
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FS holds the named datasets shipped with the binary, the name is the file name without its extension
+//
+//go:embed *.yaml
+var FS embed.FS
+
+// Category is identified by its name, the natural key that makes loading a dataset twice a no-op.
+// The other fields only apply when the category is created.
+type Category struct {
+	Name        string         `yaml:"name" json:"name"`
+	Type        string         `yaml:"type,omitempty" json:"type,omitempty"`
+	Attributes  map[string]any `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	Description string         `yaml:"description,omitempty" json:"description,omitempty"`
+	ImageURL    string         `yaml:"image_url,omitempty" json:"image_url,omitempty"`
+	SortOrder   int            `yaml:"sort_order,omitempty" json:"sort_order,omitempty"`
+	IsActive    *bool          `yaml:"is_active,omitempty" json:"is_active,omitempty"`
+}
+
+type Dataset struct {
+	Name       string     `yaml:"-" json:"-"`
+	Categories []Category `yaml:"categories" json:"categories"`
+}
+
+var formats = []string{".yaml", ".yml", ".json"}
+
+// Parse decodes a YAML or JSON dataset, format is the file extension
+func Parse(name string, format string, content []byte) (Dataset, error) {
+	dataset := Dataset{Name: name}
+	var err error
+	switch strings.ToLower(format) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		// an empty file is an empty dataset
+		if err = decoder.Decode(&dataset); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&dataset)
+	default:
+		return dataset, fmt.Errorf("dataset %v: unsupported format %q, use .yaml, .yml or .json", name, format)
+	}
+	if err != nil {
+		return dataset, fmt.Errorf("dataset %v: %w", name, err)
+	}
+
+	seen := map[string]bool{}
+	for _, category := range dataset.Categories {
+		if seen[category.Name] {
+			return dataset, fmt.Errorf("dataset %v: category %q is listed twice", name, category.Name)
+		}
+		seen[category.Name] = true
+	}
+	return dataset, nil
+}
+
+// ReadFile reads a dataset file, it is named after the file
+func ReadFile(filePath string) (Dataset, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return Dataset{}, err
+	}
+	extension := filepath.Ext(filePath)
+	return Parse(strings.TrimSuffix(filepath.Base(filePath), extension), extension, content)
+}
+
+// Named reads the dataset called name from fsys, such as FS or a testdata directory
+func Named(fsys fs.FS, name string) (Dataset, error) {
+	for _, format := range formats {
+		content, err := fs.ReadFile(fsys, name+format)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Dataset{}, err
+		}
+		return Parse(name, format, content)
+	}
+	return Dataset{}, fmt.Errorf("unknown dataset %q, the datasets are %v", name, strings.Join(Names(fsys), ", "))
+}
+
+// Names lists the datasets of fsys
+func Names(fsys fs.FS) []string {
+	entries, _ := fs.ReadDir(fsys, ".")
+	names := []string{}
+	for _, entry := range entries {
+		extension := path.Ext(entry.Name())
+		if !entry.IsDir() && slices.Contains(formats, extension) {
+			names = append(names, strings.TrimSuffix(entry.Name(), extension))
+		}
+	}
+	return names
+}
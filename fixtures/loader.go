@@ -0,0 +1,119 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+type Result struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Action string `json:"action"` // created, skipped or deleted
+}
+
+type Report []Result
+
+// Id returns the id of the category called name, whether it was created or already there
+func (report Report) Id(name string) int {
+	for _, result := range report {
+		if result.Name == name && result.Action != "deleted" {
+			return result.Id
+		}
+	}
+	return 0
+}
+
+// Loader writes datasets through the category service, so they are validated like API
+// requests and go through the decorators of the service, such as the cache
+type Loader struct {
+	CategoryService service.CategoryService
+}
+
+func NewLoader(categoryService service.CategoryService) *Loader {
+	return &Loader{
+		CategoryService: categoryService,
+	}
+}
+
+// Load creates the categories of the dataset whose names do not exist yet.
+// It stops at the first error, the report so far is returned with it.
+func (loader *Loader) Load(ctx context.Context, dataset Dataset) (Report, error) {
+	existing, err := loader.findAll(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	ids := map[string]int{}
+	for _, category := range existing {
+		if _, ok := ids[category.Name]; !ok {
+			ids[category.Name] = category.Id
+		}
+	}
+	return loader.createMissing(ctx, dataset, ids, Report{})
+}
+
+// Replace makes the categories match the dataset: the ones it does not name are
+// deleted, as are duplicates of a name, and the missing ones are created
+func (loader *Loader) Replace(ctx context.Context, dataset Dataset) (Report, error) {
+	existing, err := loader.findAll(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	wanted := map[string]bool{}
+	for _, category := range dataset.Categories {
+		wanted[category.Name] = true
+	}
+
+	report := Report{}
+	ids := map[string]int{}
+	for _, category := range existing {
+		if _, ok := ids[category.Name]; ok || !wanted[category.Name] {
+			if err := loader.CategoryService.DeleteById(ctx, category.Id); err != nil {
+				return report, fmt.Errorf("dataset %v: category %q: %w", dataset.Name, category.Name, err)
+			}
+			report = append(report, Result{Id: category.Id, Name: category.Name, Action: "deleted"})
+			continue
+		}
+		ids[category.Name] = category.Id
+	}
+	return loader.createMissing(ctx, dataset, ids, report)
+}
+
+func (loader *Loader) createMissing(ctx context.Context, dataset Dataset, ids map[string]int, report Report) (Report, error) {
+	for _, category := range dataset.Categories {
+		if id, ok := ids[category.Name]; ok {
+			report = append(report, Result{Id: id, Name: category.Name, Action: "skipped"})
+			continue
+		}
+		created, err := loader.CategoryService.Create(ctx, web.CategoryCreateRequest{
+			Name:        category.Name,
+			Type:        category.Type,
+			Attributes:  category.Attributes,
+			Description: category.Description,
+			ImageURL:    category.ImageURL,
+			SortOrder:   category.SortOrder,
+			IsActive:    category.IsActive,
+		})
+		if err != nil {
+			return report, fmt.Errorf("dataset %v: category %q: %w", dataset.Name, category.Name, err)
+		}
+		ids[category.Name] = created.Id
+		report = append(report, Result{Id: created.Id, Name: category.Name, Action: "created"})
+	}
+	return report, nil
+}
+
+// findAll orders by id, so the oldest category of a name is the one kept
+func (loader *Loader) findAll(ctx context.Context) ([]web.CategoryResponse, error) {
+	categories, err := loader.CategoryService.FindAll(ctx, web.CategoryListRequest{})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(categories, func(a, b web.CategoryResponse) int {
+		return a.Id - b.Id
+	})
+	return categories, nil
+}
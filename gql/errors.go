@@ -0,0 +1,51 @@
+package gql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+// Error is a resolver error with a code in its extensions, clients branch on the code
+type Error struct {
+	Message string
+	Code    string
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func (err *Error) Extensions() map[string]any {
+	return map[string]any{"code": err.Code}
+}
+
+// resolverError maps service errors the way exception.ErrorHandler does, hiding internal ones
+func resolverError(ctx context.Context, err error) error {
+	var notFoundError exception.NotFoundError
+	var conflictError exception.ConflictError
+	var badRequestError exception.BadRequestError
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.As(err, &notFoundError):
+		return &Error{Message: notFoundError.Error(), Code: "NOT_FOUND"}
+	case errors.As(err, &conflictError):
+		return &Error{Message: conflictError.Error(), Code: "CONFLICT"}
+	case errors.As(err, &badRequestError):
+		return &Error{Message: badRequestError.Error(), Code: "BAD_USER_INPUT"}
+	case errors.As(err, &validationErrors):
+		return &Error{Message: locale.ValidationMessage(ctx, validationErrors), Code: "BAD_USER_INPUT"}
+	case errors.Is(err, tenant.ErrMissing):
+		return &Error{Message: "a tenant is required", Code: "BAD_USER_INPUT"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Message: "request timed out", Code: "TIMEOUT"}
+	case errors.Is(err, context.Canceled):
+		return &Error{Message: "request cancelled", Code: "CANCELLED"}
+	default:
+		return &Error{Message: "internal server error", Code: "INTERNAL_SERVER_ERROR"}
+	}
+}
@@ -0,0 +1,163 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+type Config struct {
+	MaxDepth      int // deepest nesting of fields in a query
+	MaxComplexity int // fields a query may resolve, counting every item of a page
+}
+
+// Handler serves GraphQL over HTTP, POST with a JSON body or GET for queries
+type Handler struct {
+	Schema          graphql.Schema
+	CategoryService service.CategoryService
+	Config          Config
+}
+
+func NewHandler(categoryService service.CategoryService, config Config) (*Handler, error) {
+	schema, err := NewSchema(categoryService)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		Schema:          schema,
+		CategoryService: categoryService,
+		Config:          config,
+	}, nil
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func (handler *Handler) ServeHTTP(writer http.ResponseWriter, httpRequest *http.Request) {
+	graphqlRequest, err := decodeRequest(httpRequest)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			exception.ErrorHandler(writer, httpRequest, maxBytesError)
+			return
+		}
+		writeErrors(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(graphqlRequest.Query), Name: "GraphQL request"})})
+	if err != nil {
+		writeErrors(writer, http.StatusBadRequest, err)
+		return
+	}
+	if validation := graphql.ValidateDocument(&handler.Schema, document, nil); !validation.IsValid {
+		writeJSON(writer, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	operation := findOperation(document, graphqlRequest.OperationName)
+	if operation == nil {
+		writeErrors(writer, http.StatusBadRequest, fmt.Errorf("unknown operation %q", graphqlRequest.OperationName))
+		return
+	}
+	if httpRequest.Method == http.MethodGet && operation.Operation != ast.OperationTypeQuery {
+		writer.Header().Set("Allow", http.MethodPost)
+		writeErrors(writer, http.StatusMethodNotAllowed, errors.New("mutations must be sent with POST"))
+		return
+	}
+
+	depth, complexity := newQueryCost(document, operation, graphqlRequest.Variables).measure(operation.SelectionSet, map[string]bool{})
+	if depth > handler.Config.MaxDepth {
+		writeErrors(writer, http.StatusBadRequest, fmt.Errorf("query depth %d exceeds the limit of %d", depth, handler.Config.MaxDepth))
+		return
+	}
+	if complexity > handler.Config.MaxComplexity {
+		writeErrors(writer, http.StatusBadRequest, fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, handler.Config.MaxComplexity))
+		return
+	}
+
+	// every request gets its own loader, so batches never mix callers
+	ctx := withCategoryLoader(httpRequest.Context(), newCategoryLoader(httpRequest.Context(), handler.CategoryService))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        handler.Schema,
+		AST:           document,
+		OperationName: graphqlRequest.OperationName,
+		Args:          graphqlRequest.Variables,
+		Context:       ctx,
+	})
+	writeJSON(writer, http.StatusOK, result)
+}
+
+func decodeRequest(httpRequest *http.Request) (request, error) {
+	graphqlRequest := request{}
+	switch httpRequest.Method {
+	case http.MethodGet:
+		query := httpRequest.URL.Query()
+		graphqlRequest.Query = query.Get("query")
+		graphqlRequest.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &graphqlRequest.Variables); err != nil {
+				return graphqlRequest, errors.New("variables must be a JSON object")
+			}
+		}
+	default:
+		mediaType, _, _ := mime.ParseMediaType(httpRequest.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			return graphqlRequest, errors.New("Content-Type must be application/json")
+		}
+		if err := json.NewDecoder(httpRequest.Body).Decode(&graphqlRequest); err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return graphqlRequest, err
+			}
+			return graphqlRequest, errors.New("request body must be a JSON object with a query")
+		}
+	}
+	if graphqlRequest.Query == "" {
+		return graphqlRequest, errors.New("query is required")
+	}
+	return graphqlRequest, nil
+}
+
+// findOperation picks the named operation, or the only one when no name is given
+func findOperation(document *ast.Document, operationName string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == operationName {
+			return operation
+		}
+	}
+	return found
+}
+
+func writeErrors(writer http.ResponseWriter, statusCode int, err error) {
+	writeJSON(writer, statusCode, &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}})
+}
+
+func writeJSON(writer http.ResponseWriter, statusCode int, result *graphql.Result) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	json.NewEncoder(writer).Encode(result)
+}
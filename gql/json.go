@@ -0,0 +1,51 @@
+package gql
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// jsonScalar carries the attributes of categories, which have no fixed shape
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Any JSON value",
+	Serialize:   func(value any) any { return value },
+	ParseValue:  func(value any) any { return value },
+	ParseLiteral: func(value ast.Value) any {
+		return jsonLiteral(value)
+	},
+})
+
+// jsonLiteral converts a value written in a query, such as {color: "black"}, to its JSON value
+func jsonLiteral(value ast.Value) any {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.IntValue:
+		number, _ := strconv.ParseFloat(value.Value, 64)
+		return number
+	case *ast.FloatValue:
+		number, _ := strconv.ParseFloat(value.Value, 64)
+		return number
+	case *ast.ListValue:
+		list := make([]any, 0, len(value.Values))
+		for _, item := range value.Values {
+			list = append(list, jsonLiteral(item))
+		}
+		return list
+	case *ast.ObjectValue:
+		object := make(map[string]any, len(value.Fields))
+		for _, field := range value.Fields {
+			object[field.Name.Value] = jsonLiteral(field.Value)
+		}
+		return object
+	default:
+		return nil
+	}
+}
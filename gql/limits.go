@@ -0,0 +1,126 @@
+package gql
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listFields are the fields returning a page, their selections are counted once per item
+var listFields = map[string]int{
+	"categories": defaultPageSize,
+}
+
+// queryCost walks an operation with its fragments to measure its depth and complexity.
+// Every field costs 1, the selections of a page cost as much as the page holds, and
+// every id a page is asked for costs 1 on top, as it is loaded whatever the page size.
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// newQueryCost takes the variables of the request, those it leaves out have the default
+// the operation declares for them, which is what the query runs with
+func newQueryCost(document *ast.Document, operation *ast.OperationDefinition, variables map[string]any) *queryCost {
+	cost := &queryCost{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: map[string]any{},
+	}
+	for _, definition := range operation.VariableDefinitions {
+		name := definition.Variable.Name.Value
+		switch value := definition.DefaultValue.(type) {
+		case *ast.IntValue:
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				cost.variables[name] = float64(size)
+			}
+		case *ast.ListValue:
+			cost.variables[name] = make([]any, len(value.Values))
+		}
+	}
+	for name, value := range variables {
+		cost.variables[name] = value
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return cost
+}
+
+// measure returns the depth and complexity of a selection set, fragments are inlined
+func (cost *queryCost) measure(selectionSet *ast.SelectionSet, visited map[string]bool) (depth int, complexity int) {
+	if selectionSet == nil {
+		return 0, 0
+	}
+	for _, selection := range selectionSet.Selections {
+		var selectionDepth, selectionComplexity int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity := cost.measure(selection.SelectionSet, visited)
+			selectionDepth = childDepth + 1
+			pageSize := cost.pageSize(selection)
+			if ids, ok := cost.ids(selection); ok {
+				pageSize = min(pageSize, ids)
+				selectionComplexity += ids
+			}
+			selectionComplexity += 1 + childComplexity*pageSize
+		case *ast.InlineFragment:
+			selectionDepth, selectionComplexity = cost.measure(selection.SelectionSet, visited)
+		case *ast.FragmentSpread:
+			// cycles are rejected by validation, visited only keeps a bad document from looping
+			name := selection.Name.Value
+			if fragment, ok := cost.fragments[name]; ok && !visited[name] {
+				visited[name] = true
+				selectionDepth, selectionComplexity = cost.measure(fragment.SelectionSet, visited)
+				delete(visited, name)
+			}
+		}
+		depth = max(depth, selectionDepth)
+		complexity += selectionComplexity
+	}
+	return depth, complexity
+}
+
+// pageSize is the first argument of a list field, literal or variable, or its default
+func (cost *queryCost) pageSize(field *ast.Field) int {
+	size, ok := listFields[field.Name.Value]
+	if !ok {
+		return 1
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			size, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			if variable, ok := cost.variables[value.Name.Value].(float64); ok {
+				size = int(variable)
+			}
+		}
+	}
+	return min(max(size, 1), maxPageSize)
+}
+
+// ids is the length of the ids argument of a list field, literal or variable
+func (cost *queryCost) ids(field *ast.Field) (int, bool) {
+	if _, ok := listFields[field.Name.Value]; !ok {
+		return 0, false
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "ids" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.ListValue:
+			return len(value.Values), true
+		case *ast.Variable:
+			if variable, ok := cost.variables[value.Name.Value].([]any); ok {
+				return len(variable), true
+			}
+		}
+	}
+	return 0, false
+}
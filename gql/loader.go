@@ -0,0 +1,129 @@
+package gql
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+// categoryLoader batches the category lookups of one request. Resolvers get a thunk
+// from Load, the executor resolves thunks only after every sibling field asked for its
+// category, so the first thunk loads all pending ids with a single FindByIds.
+type categoryLoader struct {
+	ctx             context.Context
+	categoryService service.CategoryService
+
+	mutex   sync.Mutex
+	pending []int
+	loaded  map[int]*web.CategoryResponse // nil when the category does not exist
+	errs    map[int]error
+}
+
+func newCategoryLoader(ctx context.Context, categoryService service.CategoryService) *categoryLoader {
+	return &categoryLoader{
+		ctx:             ctx,
+		categoryService: categoryService,
+		loaded:          map[int]*web.CategoryResponse{},
+		errs:            map[int]error{},
+	}
+}
+
+// Load returns a thunk resolving to the category, or to nil when it does not exist
+func (loader *categoryLoader) Load(categoryId int) func() (any, error) {
+	loader.mutex.Lock()
+	_, loaded := loader.loaded[categoryId]
+	_, failed := loader.errs[categoryId]
+	if !loaded && !failed && !slices.Contains(loader.pending, categoryId) {
+		loader.pending = append(loader.pending, categoryId)
+	}
+	loader.mutex.Unlock()
+
+	return func() (any, error) {
+		category, err := loader.get(categoryId)
+		if err != nil || category == nil {
+			return nil, err
+		}
+		return *category, nil
+	}
+}
+
+// LoadMany resolves the categories in the order of the ids, leaving out missing ones
+func (loader *categoryLoader) LoadMany(categoryIds []int) ([]web.CategoryResponse, error) {
+	thunks := make([]func() (any, error), len(categoryIds))
+	for i, categoryId := range categoryIds {
+		thunks[i] = loader.Load(categoryId)
+	}
+
+	categories := []web.CategoryResponse{}
+	for _, thunk := range thunks {
+		category, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		if category != nil {
+			categories = append(categories, category.(web.CategoryResponse))
+		}
+	}
+	return categories, nil
+}
+
+// Prime stores categories loaded or written elsewhere, so later lookups skip the query
+func (loader *categoryLoader) Prime(categories ...web.CategoryResponse) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	for _, category := range categories {
+		loader.loaded[category.Id] = &category
+		delete(loader.errs, category.Id)
+	}
+}
+
+// Forget drops a deleted category
+func (loader *categoryLoader) Forget(categoryId int) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	loader.loaded[categoryId] = nil
+}
+
+func (loader *categoryLoader) get(categoryId int) (*web.CategoryResponse, error) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	if category, ok := loader.loaded[categoryId]; ok {
+		return category, nil
+	}
+	if err, ok := loader.errs[categoryId]; ok {
+		return nil, err
+	}
+
+	batch := loader.pending
+	loader.pending = nil
+	if !slices.Contains(batch, categoryId) {
+		batch = append(batch, categoryId)
+	}
+
+	categories, err := loader.categoryService.FindByIds(loader.ctx, batch)
+	for _, id := range batch {
+		if err != nil {
+			loader.errs[id] = err
+		} else {
+			loader.loaded[id] = nil
+		}
+	}
+	for _, category := range categories {
+		loader.loaded[category.Id] = &category
+	}
+	return loader.loaded[categoryId], loader.errs[categoryId]
+}
+
+type loaderContextKey struct{}
+
+func withCategoryLoader(ctx context.Context, loader *categoryLoader) context.Context {
+	return context.WithValue(ctx, loaderContextKey{}, loader)
+}
+
+func categoryLoaderFromContext(ctx context.Context) *categoryLoader {
+	return ctx.Value(loaderContextKey{}).(*categoryLoader)
+}
@@ -0,0 +1,398 @@
+package gql
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+	maxIds          = 1000
+)
+
+// NewSchema describes categories, the resolvers go through categoryService
+func NewSchema(categoryService service.CategoryService) (graphql.Schema, error) {
+	categoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Category",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"slug":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"type":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"attributes":  &graphql.Field{Type: graphql.NewNonNull(jsonScalar)},
+			"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"imageUrl":    categoryField(graphql.String, func(category web.CategoryResponse) any { return category.ImageURL }),
+			"sortOrder":   categoryField(graphql.Int, func(category web.CategoryResponse) any { return category.SortOrder }),
+			"isActive":    categoryField(graphql.Boolean, func(category web.CategoryResponse) any { return category.IsActive }),
+			"createdAt":   categoryField(graphql.DateTime, func(category web.CategoryResponse) any { return category.CreatedAt }),
+			"updatedAt":   categoryField(graphql.DateTime, func(category web.CategoryResponse) any { return category.UpdatedAt }),
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String, Description: "Pass as after to get the next page"},
+		},
+	})
+
+	categoryConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategoryConnection",
+		Fields: graphql.Fields{
+			"nodes": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType)))},
+			"totalCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Categories matching the filter across all pages, counted by another query",
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					count, err := params.Source.(map[string]any)["totalCount"].(func() (int, error))()
+					if err != nil {
+						return nil, resolverError(params.Context, err)
+					}
+					return count, nil
+				},
+			},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+
+	categoryInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CategoryInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"slug":        &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Derived from the name when left out"},
+			"type":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"attributes":  &graphql.InputObjectFieldConfig{Type: jsonScalar, Description: "An object checked against the attribute schema of the type, replaces the attributes on update"},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"imageUrl":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"sortOrder":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"isActive":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "True when a category is created without it"},
+		},
+		Description: "Fields left out of an update keep their value",
+	})
+
+	resolvers := &resolvers{categoryService: categoryService}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"category": &graphql.Field{
+				Type:        categoryType,
+				Description: "The category with the id, null when it does not exist",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: resolvers.category,
+			},
+			"categories": &graphql.Field{
+				Type:        graphql.NewNonNull(categoryConnectionType),
+				Description: "Categories ordered by id",
+				Args: graphql.FieldConfigArgument{
+					"ids":          &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int)), Description: "Only these categories, at most 1000"},
+					"nameContains": &graphql.ArgumentConfig{Type: graphql.String, Description: "Case insensitive substring of the name"},
+					"isActive":     &graphql.ArgumentConfig{Type: graphql.Boolean, Description: "Only active or only inactive categories"},
+					"type":         &graphql.ArgumentConfig{Type: graphql.String, Description: "Only categories of this type"},
+					"first":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize, Description: "Page size, at most 1000"},
+					"after":        &graphql.ArgumentConfig{Type: graphql.String, Description: "endCursor of the previous page"},
+				},
+				Resolve: resolvers.categories,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createCategory": &graphql.Field{
+				Type: graphql.NewNonNull(categoryType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(categoryInputType)},
+				},
+				Resolve: resolvers.createCategory,
+			},
+			"updateCategory": &graphql.Field{
+				Type: graphql.NewNonNull(categoryType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(categoryInputType)},
+				},
+				Resolve: resolvers.updateCategory,
+			},
+			"deleteCategory": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: resolvers.deleteCategory,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+type resolvers struct {
+	categoryService service.CategoryService
+}
+
+func (resolvers *resolvers) category(params graphql.ResolveParams) (any, error) {
+	thunk := categoryLoaderFromContext(params.Context).Load(params.Args["id"].(int))
+	return func() (any, error) {
+		category, err := thunk()
+		if err != nil {
+			return nil, resolverError(params.Context, err)
+		}
+		return category, nil
+	}, nil
+}
+
+func (resolvers *resolvers) categories(params graphql.ResolveParams) (any, error) {
+	loader := categoryLoaderFromContext(params.Context)
+
+	first := params.Args["first"].(int)
+	if first < 0 {
+		return nil, &Error{Message: "first must not be negative", Code: "BAD_USER_INPUT"}
+	}
+	first = min(first, maxPageSize)
+	after, err := decodeCursor(params.Args["after"])
+	if err != nil {
+		return nil, &Error{Message: "invalid after cursor", Code: "BAD_USER_INPUT"}
+	}
+
+	request := web.CategoryListRequest{}
+	if nameContains, ok := params.Args["nameContains"].(string); ok {
+		request.NameContains = &nameContains
+	}
+	if isActive, ok := params.Args["isActive"].(bool); ok {
+		request.IsActive = &isActive
+	}
+	if categoryType, ok := params.Args["type"].(string); ok {
+		request.Type = &categoryType
+	}
+
+	if ids, ok := params.Args["ids"].([]any); ok {
+		return resolvers.categoriesByIds(params, loader, ids, request, first, after)
+	}
+
+	// the database returns the page, one more tells whether there is a next one
+	request.AfterSortOrder, request.AfterId, request.Limit = after.SortOrder, after.Id, first+1
+	categories, err := resolvers.categoryService.FindAll(params.Context, request)
+	if err != nil {
+		return nil, resolverError(params.Context, err)
+	}
+	loader.Prime(categories...)
+
+	request.AfterSortOrder, request.AfterId, request.Limit = 0, 0, 0
+	totalCount := func() (int, error) {
+		return resolvers.categoryService.Count(params.Context, request)
+	}
+	return categoryConnection(categories[:min(first, len(categories))], len(categories) > first, totalCount), nil
+}
+
+// categoriesByIds filters and pages the categories of at most maxIds ids in memory
+func (resolvers *resolvers) categoriesByIds(params graphql.ResolveParams, loader *categoryLoader, ids []any, request web.CategoryListRequest, first int, after cursor) (any, error) {
+	if len(ids) > maxIds {
+		return nil, &Error{Message: fmt.Sprintf("at most %d ids are allowed", maxIds), Code: "BAD_USER_INPUT"}
+	}
+	categoryIds := make([]int, len(ids))
+	for i, id := range ids {
+		categoryIds[i] = id.(int)
+	}
+	categories, err := loader.LoadMany(categoryIds)
+	if err != nil {
+		return nil, resolverError(params.Context, err)
+	}
+
+	categories = slices.DeleteFunc(slices.Clone(categories), func(category web.CategoryResponse) bool {
+		return !matches(category, request)
+	})
+	slices.SortFunc(categories, func(a, b web.CategoryResponse) int {
+		return cursorOf(a).compare(cursorOf(b))
+	})
+	categories = slices.CompactFunc(categories, func(a, b web.CategoryResponse) bool {
+		return a.Id == b.Id
+	})
+
+	// the page starts after the cursor, in the order the database pages in
+	start := 0
+	if after.Id > 0 {
+		var found bool
+		start, found = slices.BinarySearchFunc(categories, after, func(category web.CategoryResponse, after cursor) int {
+			return cursorOf(category).compare(after)
+		})
+		if found {
+			start++
+		}
+	}
+	end := min(start+first, len(categories))
+	totalCount := func() (int, error) {
+		return len(categories), nil
+	}
+	return categoryConnection(categories[start:end], end < len(categories), totalCount), nil
+}
+
+// matches applies the filters of request the way the repository does
+func matches(category web.CategoryResponse, request web.CategoryListRequest) bool {
+	switch {
+	case request.NameContains != nil && !strings.Contains(strings.ToLower(category.Name), strings.ToLower(*request.NameContains)):
+		return false
+	case request.IsActive != nil && category.IsActive != *request.IsActive:
+		return false
+	case request.Type != nil && category.Type != *request.Type:
+		return false
+	}
+	return true
+}
+
+// categoryConnection is the value of a CategoryConnection, totalCount only runs when it is selected
+func categoryConnection(nodes []web.CategoryResponse, hasNextPage bool, totalCount func() (int, error)) map[string]any {
+	pageInfo := map[string]any{"hasNextPage": hasNextPage, "endCursor": nil}
+	if len(nodes) > 0 {
+		pageInfo["endCursor"] = encodeCursor(cursorOf(nodes[len(nodes)-1]))
+	}
+	return map[string]any{
+		"nodes":      nodes,
+		"totalCount": totalCount,
+		"pageInfo":   pageInfo,
+	}
+}
+
+func (resolvers *resolvers) createCategory(params graphql.ResolveParams) (any, error) {
+	input := params.Args["input"].(map[string]any)
+	attributes, err := attributesInput(input)
+	if err != nil {
+		return nil, err
+	}
+	request := web.CategoryCreateRequest{Name: input["name"].(string), Attributes: attributes}
+	request.Slug, _ = input["slug"].(string)
+	request.Type, _ = input["type"].(string)
+	request.Description, _ = input["description"].(string)
+	request.ImageURL, _ = input["imageUrl"].(string)
+	request.SortOrder, _ = input["sortOrder"].(int)
+	if isActive, ok := input["isActive"].(bool); ok {
+		request.IsActive = &isActive
+	}
+	category, err := resolvers.categoryService.Create(params.Context, request)
+	if err != nil {
+		return nil, resolverError(params.Context, err)
+	}
+	categoryLoaderFromContext(params.Context).Prime(category)
+	return category, nil
+}
+
+func (resolvers *resolvers) updateCategory(params graphql.ResolveParams) (any, error) {
+	input := params.Args["input"].(map[string]any)
+	attributes, err := attributesInput(input)
+	if err != nil {
+		return nil, err
+	}
+	request := web.CategoryUpdateRequest{Id: params.Args["id"].(int), Name: input["name"].(string), Attributes: attributes}
+	request.Slug, _ = input["slug"].(string)
+	if categoryType, ok := input["type"].(string); ok {
+		request.Type = &categoryType
+	}
+	if description, ok := input["description"].(string); ok {
+		request.Description = &description
+	}
+	if imageURL, ok := input["imageUrl"].(string); ok {
+		request.ImageURL = &imageURL
+	}
+	if sortOrder, ok := input["sortOrder"].(int); ok {
+		request.SortOrder = &sortOrder
+	}
+	if isActive, ok := input["isActive"].(bool); ok {
+		request.IsActive = &isActive
+	}
+	category, err := resolvers.categoryService.Update(params.Context, request)
+	if err != nil {
+		return nil, resolverError(params.Context, err)
+	}
+	categoryLoaderFromContext(params.Context).Prime(category)
+	return category, nil
+}
+
+func (resolvers *resolvers) deleteCategory(params graphql.ResolveParams) (any, error) {
+	categoryId := params.Args["id"].(int)
+	if err := resolvers.categoryService.DeleteById(params.Context, categoryId); err != nil {
+		return nil, resolverError(params.Context, err)
+	}
+	categoryLoaderFromContext(params.Context).Forget(categoryId)
+	return true, nil
+}
+
+// attributesInput is nil when the input has no attributes, which must otherwise be an object
+func attributesInput(input map[string]any) (map[string]any, error) {
+	value, ok := input["attributes"]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	attributes, ok := value.(map[string]any)
+	if !ok {
+		return nil, &Error{Message: "attributes must be an object", Code: "BAD_USER_INPUT"}
+	}
+	return attributes, nil
+}
+
+// categoryField is a non-null field whose name is not the json name of the response field
+func categoryField(fieldType graphql.Output, value func(category web.CategoryResponse) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(fieldType),
+		Resolve: func(params graphql.ResolveParams) (any, error) {
+			category, _ := params.Source.(web.CategoryResponse)
+			return value(category), nil
+		},
+	}
+}
+
+// cursors are opaque to clients, they hold the id of the last category of the page
+// cursor is the position of a category in the list, which is ordered by sort_order and id
+type cursor struct {
+	SortOrder int
+	Id        int
+}
+
+func cursorOf(category web.CategoryResponse) cursor {
+	return cursor{SortOrder: category.SortOrder, Id: category.Id}
+}
+
+func (position cursor) compare(other cursor) int {
+	return cmp.Or(cmp.Compare(position.SortOrder, other.SortOrder), cmp.Compare(position.Id, other.Id))
+}
+
+func encodeCursor(position cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(position.SortOrder) + ":" + strconv.Itoa(position.Id)))
+}
+
+// decodeCursor returns the zero cursor, the start of the list, for a missing one
+func decodeCursor(value any) (cursor, error) {
+	encoded, ok := value.(string)
+	if !ok || encoded == "" {
+		return cursor{}, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor{}, err
+	}
+	sortOrder, id, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return cursor{}, errors.New("invalid cursor")
+	}
+	var position cursor
+	if position.SortOrder, err = strconv.Atoi(sortOrder); err != nil {
+		return cursor{}, err
+	}
+	if position.Id, err = strconv.Atoi(id); err != nil || position.Id < 1 {
+		return cursor{}, errors.New("invalid cursor")
+	}
+	return position, nil
+}
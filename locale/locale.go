@@ -0,0 +1,67 @@
+package locale
+
+import (
+	"context"
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+// maxPreferences bounds the locales taken from one Accept-Language header
+const maxPreferences = 10
+
+// wildcard is what * parses to
+var wildcard = language.Make("mul")
+
+// Parse reads an Accept-Language header into locales in order of preference, each followed
+// by its more general ones, so "fr-CA, en;q=0.5" is fr-CA, fr, en. A malformed header is ignored.
+func Parse(acceptLanguage string) []string {
+	tags, qualities, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return nil
+	}
+
+	locales := []string{}
+	for i, tag := range tags {
+		// q=0 means not acceptable and * leaves the choice to the server
+		if qualities[i] <= 0 || tag == language.Und || tag == wildcard {
+			continue
+		}
+		for ; tag != language.Und && len(locales) < maxPreferences; tag = tag.Parent() {
+			if !slices.Contains(locales, tag.String()) {
+				locales = append(locales, tag.String())
+			}
+		}
+	}
+	return locales
+}
+
+// Canonical returns the canonical form of a BCP 47 tag, so en-us and en-US are stored alike
+func Canonical(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", err
+	}
+	return tag.String(), nil
+}
+
+// Fallbacks returns the preferred locales to look translations up in. They end at the default
+// locale, a category without a translation in it keeps its name, which is written in it.
+func Fallbacks(preferences []string, defaultLocale string) []string {
+	if i := slices.Index(preferences, defaultLocale); i >= 0 {
+		return preferences[:i+1]
+	}
+	return preferences
+}
+
+type contextKey struct{}
+
+// WithPreferences returns ctx carrying the locales the client prefers, see Parse
+func WithPreferences(ctx context.Context, locales []string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locales)
+}
+
+func Preferences(ctx context.Context) []string {
+	locales, _ := ctx.Value(contextKey{}).([]string)
+	return locales
+}
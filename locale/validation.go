@@ -0,0 +1,128 @@
+package locale
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	de_translations "github.com/go-playground/validator/v10/translations/de"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// the languages validation messages are written in
+var translator = ut.New(en.New(), en.New(), de.New(), es.New(), fr.New(), id.New())
+
+// sharedTranslator lets every validator register the same messages, the texts are kept by
+// translator and the first registration already added them
+type sharedTranslator struct {
+	ut.Translator
+}
+
+func (trans sharedTranslator) Add(key any, text string, override bool) error {
+	return ignoreConflict(trans.Translator.Add(key, text, override))
+}
+
+func (trans sharedTranslator) AddCardinal(key any, text string, rule locales.PluralRule, override bool) error {
+	return ignoreConflict(trans.Translator.AddCardinal(key, text, rule, override))
+}
+
+func (trans sharedTranslator) AddOrdinal(key any, text string, rule locales.PluralRule, override bool) error {
+	return ignoreConflict(trans.Translator.AddOrdinal(key, text, rule, override))
+}
+
+func (trans sharedTranslator) AddRange(key any, text string, rule locales.PluralRule, override bool) error {
+	return ignoreConflict(trans.Translator.AddRange(key, text, rule, override))
+}
+
+func ignoreConflict(err error) error {
+	var conflict *ut.ErrConflictingTranslation
+	if errors.As(err, &conflict) {
+		return nil
+	}
+	return err
+}
+
+// getTranslator returns the translator of a locale such as pt_BR, validators look their
+// messages up by it
+func getTranslator(name string) (ut.Translator, bool) {
+	trans, ok := translator.GetTranslator(name)
+	return sharedTranslator{trans}, ok
+}
+
+var registrations = map[string]func(*validator.Validate, ut.Translator) error{
+	"en": en_translations.RegisterDefaultTranslations,
+	"de": de_translations.RegisterDefaultTranslations,
+	"es": es_translations.RegisterDefaultTranslations,
+	"fr": fr_translations.RegisterDefaultTranslations,
+	"id": id_translations.RegisterDefaultTranslations,
+}
+
+// messages of the tags the validator has none for
+var extraMessages = map[string]map[string]string{
+	"bcp47_language_tag": {
+		"en": "{0} must be a BCP 47 language tag such as fr or pt-BR",
+		"de": "{0} muss ein BCP-47-Sprachtag wie fr oder pt-BR sein",
+		"es": "{0} debe ser una etiqueta de idioma BCP 47 como fr o pt-BR",
+		"fr": "{0} doit être une balise de langue BCP 47 comme fr ou pt-BR",
+		"id": "{0} harus berupa tag bahasa BCP 47 seperti fr atau pt-BR",
+	},
+}
+
+// RegisterTranslations makes the validation errors of validate translatable by ValidationMessage,
+// fields are named after their JSON names as clients know them
+func RegisterTranslations(validate *validator.Validate) error {
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	for name, register := range registrations {
+		trans, _ := getTranslator(name)
+		if err := register(validate, trans); err != nil {
+			return err
+		}
+		for tag, messages := range extraMessages {
+			err := validate.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+				return trans.Add(tag, messages[name], false)
+			}, func(trans ut.Translator, fieldError validator.FieldError) string {
+				message, _ := trans.T(tag, fieldError.Field())
+				return message
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidationMessage describes errs in the first preferred language there are messages in,
+// "invalid fields" when the client prefers none
+func ValidationMessage(ctx context.Context, errs validator.ValidationErrors) string {
+	for _, preference := range Preferences(ctx) {
+		trans, ok := getTranslator(strings.ReplaceAll(preference, "-", "_"))
+		if !ok {
+			continue
+		}
+		messages := make([]string, 0, len(errs))
+		for _, fieldError := range errs {
+			messages = append(messages, fieldError.Translate(trans))
+		}
+		return strings.Join(messages, "; ")
+	}
+	return "invalid fields"
+}
@@ -1,61 +1,87 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
 	"os"
+	"os/signal"
 
-	"github.com/go-playground/validator/v10"
-	"github.com/joho/godotenv"
-	"github.com/julienschmidt/httprouter"
 	"github.com/rozanlaudzai/go-mysql-restful-api/app"
-	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
-	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
-	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cli"
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/migrations"
 	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
 	"github.com/rozanlaudzai/go-mysql-restful-api/service"
 )
 
 func main() {
 
-	if err := godotenv.Load(); err != nil {
-		panic(err)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(os.Stderr, "\n", cli.Usage)
+		return
 	}
-
-	db, err := app.NewDB()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	validate := validator.New()
 
-	categoryRepository := repository.NewCategoryRepository()
-	categoryService := service.NewCategoryService(categoryRepository, db, validate)
-	categoryController := controller.NewCategoryController(categoryService)
-
-	// setup endpoints
-	router := httprouter.New()
-	router.GET("/api/categories", categoryController.FindAll)
-	router.GET("/api/categories/:categoryId", categoryController.FindById)
-	router.POST("/api/categories", categoryController.Create)
-	router.PUT("/api/categories/:categoryId", categoryController.Update)
-	router.DELETE("/api/categories/:categoryId", categoryController.DeleteById)
-	router.PanicHandler = exception.ErrorHandler
-
-	// setup address
-	serverPort := os.Getenv("SERVER_PORT")
-	address := fmt.Sprintf("localhost:%v", serverPort)
-
-	// setup auth middleware
-	apiKey := os.Getenv("API_KEY")
-	authMiddleware := middleware.NewAuthMiddleware(router, apiKey)
-
-	server := http.Server{
-		Addr:    address,
-		Handler: authMiddleware,
+	// the flags are followed by the command, serving is the default
+	if len(cfg.Args) == 0 || cfg.Args[0] == "serve" {
+		serve(cfg)
+		return
+	}
+
+	if err := runCommand(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		var usageError cli.UsageError
+		if errors.As(err, &usageError) {
+			fmt.Fprint(os.Stderr, "\n", cli.Usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+
+}
+
+// runCommand runs an admin command with the services the server uses, the replica is left out
+// so a command reads what it wrote
+func runCommand(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	db, err := app.NewDB(cfg.Database)
+	if err != nil {
+		return err
 	}
-	fmt.Printf("Listening to http://%v\n", address)
-	if err = server.ListenAndServe(); err != nil {
-		panic(err)
+	defer db.Close()
+
+	loadedMigrations, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		return err
 	}
 
+	transactionManager := app.NewTransactionManager(db, nil, cfg.Database)
+	commands := cli.NewCLI(
+		newCategoryService(cfg, transactionManager, &cache.Stats{}),
+		service.NewAPIKeyService(repository.NewAPIKeyRepository(), transactionManager, app.NewValidator()),
+		database.NewMigrator(db, loadedMigrations),
+	)
+	commands.Tenant = cfg.Auth.DefaultTenant
+	return commands.Run(ctx, cfg.Args)
+}
+
+// newCategoryService is shared by serve and the commands, so a shared cache such as
+// redis is invalidated whichever of them changes a category
+func newCategoryService(cfg config.Config, transactionManager *database.TransactionManager, cacheStats *cache.Stats) service.CategoryService {
+	categoryRepository := repository.NewCategoryRepository()
+	categoryService := service.NewCategoryService(categoryRepository, repository.NewCategoryAttributeSchemaRepository(), transactionManager, app.NewValidator())
+	if categoryCache := app.NewCache(cfg.Cache); categoryCache != nil {
+		categoryService = service.NewCategoryServiceCache(categoryService, categoryCache, cfg.Cache.TTL, cacheStats)
+	}
+	return categoryService
 }
@@ -1,27 +1,194 @@
 package middleware
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrTenantForbidden = errors.New("the tenant is not allowed for these credentials")
+	ErrTenantInvalid   = errors.New("X-Tenant-ID must be lowercase letters, digits, - and _, at most 64 characters")
+	ErrTenantRequired  = errors.New("a tenant is required, send X-Tenant-ID")
+	ErrTooManyFailures = errors.New("too many failed authentications, try again later")
 )
 
 type AuthMiddleware struct {
 	Handler       http.Handler
 	CorrectAPIKey string
+
+	// ClientPrincipals maps the common name of a verified client certificate
+	// to a principal, such clients do not need an API key
+	ClientPrincipals map[string]string
+
+	// PublicPaths are served without authentication, such as the API documentation
+	PublicPaths []string
+
+	// Keys looks up the keys issued with the keys command, nil when only CorrectAPIKey is accepted
+	Keys APIKeyLookup
+
+	// JWT verifies bearer tokens, nil when they are not accepted
+	JWT *JWTVerifier
+
+	// DefaultTenant is used when the credentials do not fix a tenant and none is asked for,
+	// empty makes X-Tenant-ID required
+	DefaultTenant string
+
+	// FailureLimit bounds the failed authentications per client IP, so unknown API keys
+	// cannot make an api_key lookup for every request. A zero Rate disables it.
+	FailureLimit RateLimit
+
+	failures *limiter
+}
+
+type APIKeyLookup interface {
+	Lookup(ctx context.Context, apiKey string) (web.APIKeyResponse, bool, error)
+}
+
+// Credentials are what a request presents, the gRPC server reads them from metadata
+type Credentials struct {
+	APIKey          string
+	BearerToken     string
+	TenantId        string // X-Tenant-ID
+	ConnectionState *tls.ConnectionState
+	RemoteAddr      string // host:port of the client, failures are counted per host
 }
 
 func NewAuthMiddleware(handler http.Handler, correctAPIkey string) *AuthMiddleware {
 	return &AuthMiddleware{
 		Handler:       handler,
 		CorrectAPIKey: correctAPIkey,
+		DefaultTenant: "default",
+		failures:      newLimiter(10 * time.Minute),
 	}
 }
 
 func (middleware *AuthMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	if request.Header.Get("X-API-Key") == middleware.CorrectAPIKey {
+	if slices.Contains(middleware.PublicPaths, request.URL.Path) {
 		middleware.Handler.ServeHTTP(writer, request)
-	} else {
-		exception.WriteErrorResponse(writer, http.StatusUnauthorized, "UNAUTHORIZED", "")
+		return
+	}
+
+	credentials := Credentials{
+		APIKey:          request.Header.Get("X-API-Key"),
+		BearerToken:     bearerToken(request.Header.Get("Authorization")),
+		TenantId:        request.Header.Get("X-Tenant-ID"),
+		ConnectionState: request.TLS,
+		RemoteAddr:      request.RemoteAddr,
+	}
+	principal, err := middleware.Authenticate(request.Context(), credentials)
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		exception.WriteErrorResponse(writer, request, http.StatusUnauthorized, "UNAUTHORIZED", "")
+	case errors.Is(err, ErrTooManyFailures):
+		writer.Header().Set("Retry-After", strconv.Itoa(secondsUntil(middleware.FailureLimit, 1)))
+		exception.WriteErrorResponse(writer, request, http.StatusTooManyRequests, "TOO MANY REQUESTS", err.Error())
+	case errors.Is(err, ErrTenantForbidden):
+		exception.WriteErrorResponse(writer, request, http.StatusForbidden, "FORBIDDEN", err.Error())
+	case errors.Is(err, ErrTenantInvalid), errors.Is(err, ErrTenantRequired):
+		exception.WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", err.Error())
+	case err != nil:
+		exception.ErrorHandler(writer, request, err)
+	default:
+		ctx := tenant.WithID(WithPrincipal(request.Context(), principal), principal.TenantId)
+		middleware.Handler.ServeHTTP(writer, request.WithContext(ctx))
+	}
+}
+
+// Authenticate checks a client certificate first, then the configured API key, the issued
+// ones and a bearer token, and resolves the tenant of the principal. The gRPC server shares it.
+// Clients past FailureLimit are turned away before any of it.
+func (middleware *AuthMiddleware) Authenticate(ctx context.Context, credentials Credentials) (Principal, error) {
+	client := "ip:" + remoteHost(credentials.RemoteAddr)
+	limitFailures := middleware.FailureLimit.Rate > 0 && credentials.RemoteAddr != "" && middleware.failures != nil
+	if limitFailures && !middleware.failures.available(client, middleware.FailureLimit, time.Now()) {
+		return Principal{}, ErrTooManyFailures
+	}
+
+	principal, err := middleware.authenticate(ctx, credentials)
+	if errors.Is(err, ErrUnauthenticated) && limitFailures {
+		middleware.failures.take(map[string]RateLimit{client: middleware.FailureLimit}, time.Now())
+	}
+	if err != nil {
+		return principal, err
+	}
+
+	// credentials tied to a tenant may only name their own
+	if principal.TenantId != "" {
+		if credentials.TenantId != "" && credentials.TenantId != principal.TenantId {
+			return Principal{}, ErrTenantForbidden
+		}
+		return principal, nil
+	}
+
+	switch {
+	case credentials.TenantId != "":
+		if !tenant.Valid(credentials.TenantId) {
+			return Principal{}, ErrTenantInvalid
+		}
+		principal.TenantId = credentials.TenantId
+	case middleware.DefaultTenant != "":
+		principal.TenantId = middleware.DefaultTenant
+	default:
+		return Principal{}, ErrTenantRequired
+	}
+	return principal, nil
+}
+
+func (middleware *AuthMiddleware) authenticate(ctx context.Context, credentials Credentials) (Principal, error) {
+	if principal, ok := middleware.clientCertificatePrincipal(credentials.ConnectionState); ok {
+		return principal, nil
+	}
+	if credentials.APIKey != "" && credentials.APIKey == middleware.CorrectAPIKey {
+		return Principal{Name: "api-key", Method: "api_key"}, nil
+	}
+	if credentials.APIKey != "" && middleware.Keys != nil {
+		apiKey, ok, err := middleware.Keys.Lookup(ctx, credentials.APIKey)
+		if err != nil {
+			return Principal{}, err
+		}
+		if ok {
+			return Principal{Name: apiKey.Name, Method: "api_key", TenantId: apiKey.TenantId}, nil
+		}
+	}
+	if credentials.BearerToken != "" && middleware.JWT != nil {
+		principal, err := middleware.JWT.Verify(credentials.BearerToken)
+		if err != nil {
+			return Principal{}, ErrUnauthenticated
+		}
+		return principal, nil
+	}
+	return Principal{}, ErrUnauthenticated
+}
+
+func (middleware *AuthMiddleware) clientCertificatePrincipal(connectionState *tls.ConnectionState) (Principal, bool) {
+	// only certificates verified against the client CAs are trusted
+	if connectionState == nil || len(connectionState.VerifiedChains) == 0 || len(middleware.ClientPrincipals) == 0 {
+		return Principal{}, false
+	}
+
+	commonName := connectionState.VerifiedChains[0][0].Subject.CommonName
+	name, ok := middleware.ClientPrincipals[commonName]
+	if !ok {
+		return Principal{}, false
+	}
+	return Principal{Name: name, Method: "client_certificate"}, true
+}
+
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
 	}
+	return strings.TrimSpace(token)
 }
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+// BodyLimitMiddleware caps the size of request bodies, reading past the limit
+// fails with *http.MaxBytesError which is reported as 413
+type BodyLimitMiddleware struct {
+	Handler  http.Handler
+	MaxBytes int64
+}
+
+func NewBodyLimitMiddleware(handler http.Handler, maxBytes int64) *BodyLimitMiddleware {
+	return &BodyLimitMiddleware{
+		Handler:  handler,
+		MaxBytes: maxBytes,
+	}
+}
+
+func (middleware *BodyLimitMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	// a declared length over the limit is rejected before reading anything
+	if request.ContentLength > middleware.MaxBytes {
+		exception.WriteErrorResponse(writer, request, http.StatusRequestEntityTooLarge, "REQUEST ENTITY TOO LARGE", fmt.Sprintf("request body must not be larger than %d bytes", middleware.MaxBytes))
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, middleware.MaxBytes)
+	middleware.Handler.ServeHTTP(writer, request)
+}
@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rozanlaudzai/go-mysql-restful-api/codec"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+type CompressionConfig struct {
+	Encodings           []string // response encodings in order of preference: br, gzip and deflate
+	MinSize             int      // smaller responses are sent uncompressed
+	MaxDecompressedSize int64    // cap on compressed request bodies once decompressed, at most the body limit
+}
+
+// CompressionMiddleware compresses responses for clients sending Accept-Encoding
+// and decompresses request bodies sent with Content-Encoding
+type CompressionMiddleware struct {
+	Handler http.Handler
+	Config  CompressionConfig
+}
+
+func NewCompressionMiddleware(handler http.Handler, config CompressionConfig) *CompressionMiddleware {
+	return &CompressionMiddleware{
+		Handler: handler,
+		Config:  config,
+	}
+}
+
+func (middleware *CompressionMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	request, ok := middleware.decompressRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	writer.Header().Add("Vary", "Accept-Encoding")
+	encoding := negotiateEncoding(request.Header.Get("Accept-Encoding"), middleware.Config.Encodings)
+	if encoding == "" || request.Method == http.MethodHead {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+
+	compressWriter := &compressWriter{ResponseWriter: writer, encoding: encoding, minSize: middleware.Config.MinSize}
+	defer compressWriter.Close()
+	middleware.Handler.ServeHTTP(compressWriter, request)
+}
+
+// decompressRequest replaces a compressed body with a decompressing reader, the
+// decompressed size is capped so a small zip bomb cannot exhaust memory. The
+// Content-Encoding header is dropped and kept in the context instead.
+func (middleware *CompressionMiddleware) decompressRequest(writer http.ResponseWriter, request *http.Request) (*http.Request, bool) {
+	contentEncoding := strings.ToLower(strings.TrimSpace(request.Header.Get("Content-Encoding")))
+	if contentEncoding == "" || contentEncoding == "identity" {
+		return request, true
+	}
+
+	wire := &wireBody{ReadCloser: request.Body}
+	var body io.ReadCloser
+	switch contentEncoding {
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(wire)
+		if err != nil {
+			exception.WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", "invalid gzip request body")
+			return request, false
+		}
+		body = reader
+	case "deflate":
+		body = flate.NewReader(wire)
+	case "br":
+		body = io.NopCloser(brotli.NewReader(wire))
+	default:
+		exception.WriteErrorResponse(writer, request, http.StatusUnsupportedMediaType, "UNSUPPORTED MEDIA TYPE", "unsupported Content-Encoding "+strconv.Quote(contentEncoding))
+		return request, false
+	}
+
+	body = &decompressedBody{ReadCloser: body, wire: wire, encoding: contentEncoding}
+	request.Body = http.MaxBytesReader(writer, body, middleware.Config.MaxDecompressedSize)
+	request.Header.Del("Content-Encoding")
+	request.Header.Del("Content-Length")
+	request.ContentLength = -1
+	return request.WithContext(context.WithValue(request.Context(), contentEncodingContextKey{}, contentEncoding)), true
+}
+
+type contentEncodingContextKey struct{}
+
+// contentEncoding is the encoding the body was sent with, also once it was decompressed
+func contentEncoding(request *http.Request) string {
+	if encoding, ok := request.Context().Value(contentEncodingContextKey{}).(string); ok {
+		return encoding
+	}
+	return request.Header.Get("Content-Encoding")
+}
+
+// wireBody remembers why reading the compressed body failed
+type wireBody struct {
+	io.ReadCloser
+	err error
+}
+
+func (body *wireBody) Read(data []byte) (int, error) {
+	n, err := body.ReadCloser.Read(data)
+	if err != nil && err != io.EOF {
+		body.err = err
+	}
+	return n, err
+}
+
+// decompressedBody reports a stream that cannot be decompressed, such as a bad checksum
+// or a truncated stream, as a decode error so it is answered with 400 instead of 500
+type decompressedBody struct {
+	io.ReadCloser
+	wire     *wireBody
+	encoding string
+}
+
+func (body *decompressedBody) Read(data []byte) (int, error) {
+	n, err := body.ReadCloser.Read(data)
+	if err == nil || err == io.EOF || (body.wire.err != nil && errors.Is(err, body.wire.err)) {
+		return n, err
+	}
+	return n, codec.NewDecodeError(fmt.Sprintf("request body is not valid %v: %v", body.encoding, err))
+}
+
+// negotiateEncoding picks the preferred encoding with the highest quality in Accept-Encoding
+func negotiateEncoding(acceptEncoding string, encodings []string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range encodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the response and only compresses it once
+// it grows past minSize, so small responses keep their Content-Length
+type compressWriter struct {
+	http.ResponseWriter
+	encoding   string
+	minSize    int
+	statusCode int
+	buffer     bytes.Buffer
+	encoder    io.WriteCloser
+	done       bool // headers were sent, either compressed or not
+}
+
+func (writer *compressWriter) WriteHeader(statusCode int) {
+	if writer.statusCode == 0 {
+		writer.statusCode = statusCode
+	}
+}
+
+func (writer *compressWriter) Write(data []byte) (int, error) {
+	if writer.statusCode == 0 {
+		writer.statusCode = http.StatusOK
+	}
+	if writer.encoder != nil {
+		return writer.encoder.Write(data)
+	}
+	if writer.done {
+		return writer.ResponseWriter.Write(data)
+	}
+
+	writer.buffer.Write(data)
+	if writer.buffer.Len() >= writer.minSize {
+		if err := writer.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// start sends the headers and the buffered data, compressed when the response allows it
+func (writer *compressWriter) start() error {
+	writer.done = true
+	if writer.statusCode == 0 {
+		writer.statusCode = http.StatusOK
+	}
+	header := writer.Header()
+	compressible := header.Get("Content-Encoding") == "" &&
+		writer.statusCode != http.StatusNoContent && writer.statusCode != http.StatusNotModified &&
+		writer.buffer.Len() >= writer.minSize
+
+	if compressible {
+		header.Set("Content-Encoding", writer.encoding)
+		header.Del("Content-Length")
+		switch writer.encoding {
+		case "br":
+			writer.encoder = brotli.NewWriter(writer.ResponseWriter)
+		case "gzip":
+			writer.encoder = gzip.NewWriter(writer.ResponseWriter)
+		case "deflate":
+			writer.encoder, _ = flate.NewWriter(writer.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+
+	writer.ResponseWriter.WriteHeader(writer.statusCode)
+	if writer.encoder != nil {
+		_, err := writer.encoder.Write(writer.buffer.Bytes())
+		return err
+	}
+	_, err := writer.ResponseWriter.Write(writer.buffer.Bytes())
+	return err
+}
+
+func (writer *compressWriter) Flush() {
+	if !writer.done {
+		writer.start()
+	}
+	if flusher, ok := writer.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+// Close sends a response that never reached minSize and finishes the compressed stream
+func (writer *compressWriter) Close() error {
+	if writer.statusCode == 0 {
+		return nil
+	}
+	if !writer.done {
+		if err := writer.start(); err != nil {
+			return err
+		}
+	}
+	if writer.encoder != nil {
+		return writer.encoder.Close()
+	}
+	return nil
+}
+
+func (writer *compressWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+type CorsConfig struct {
+	AllowedOrigins   []string // exact origins or patterns such as https://*.example.com
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CorsMiddleware sends preflight requests straight to the Router so they skip
+// authentication, every other request goes through Handler.
+// Router.GlobalOPTIONS should be set to the Preflight method.
+type CorsMiddleware struct {
+	Handler http.Handler
+	Router  http.Handler
+	Config  CorsConfig
+}
+
+func NewCorsMiddleware(handler http.Handler, router http.Handler, config CorsConfig) *CorsMiddleware {
+	return &CorsMiddleware{
+		Handler: handler,
+		Router:  router,
+		Config:  config,
+	}
+}
+
+func (middleware *CorsMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if isPreflight(request) {
+		// httprouter answers OPTIONS with the Allow header and then calls GlobalOPTIONS
+		middleware.Router.ServeHTTP(writer, request)
+		return
+	}
+
+	origin := request.Header.Get("Origin")
+	if origin != "" {
+		writer.Header().Add("Vary", "Origin")
+		if allowed, named := middleware.originAllowed(origin); allowed {
+			middleware.writeOriginHeaders(writer, origin, named)
+			if len(middleware.Config.ExposedHeaders) > 0 {
+				writer.Header().Set("Access-Control-Expose-Headers", strings.Join(middleware.Config.ExposedHeaders, ", "))
+			}
+		}
+	}
+
+	middleware.Handler.ServeHTTP(writer, request)
+}
+
+func (middleware *CorsMiddleware) Preflight(writer http.ResponseWriter, request *http.Request) {
+	header := writer.Header()
+
+	// a plain OPTIONS request only needs the Allow header set by httprouter
+	if !isPreflight(request) {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := request.Header.Get("Origin")
+	allowed, named := middleware.originAllowed(origin)
+	if !allowed {
+		exception.WriteErrorResponse(writer, request, http.StatusForbidden, "FORBIDDEN", "origin not allowed")
+		return
+	}
+
+	// the method must exist on the route and be allowed by the config
+	method := strings.ToUpper(request.Header.Get("Access-Control-Request-Method"))
+	routeMethods := strings.Split(header.Get("Allow"), ", ")
+	if !slices.Contains(routeMethods, method) || !containsFold(middleware.Config.AllowedMethods, method) {
+		exception.WriteErrorResponse(writer, request, http.StatusForbidden, "FORBIDDEN", "method not allowed")
+		return
+	}
+
+	requestHeaders := request.Header.Get("Access-Control-Request-Headers")
+	for _, requestHeader := range strings.Split(requestHeaders, ",") {
+		requestHeader = strings.TrimSpace(requestHeader)
+		if requestHeader == "" || containsFold(middleware.Config.AllowedHeaders, "*") {
+			continue
+		}
+		if !containsFold(middleware.Config.AllowedHeaders, requestHeader) {
+			exception.WriteErrorResponse(writer, request, http.StatusForbidden, "FORBIDDEN", "header not allowed")
+			return
+		}
+	}
+
+	middleware.writeOriginHeaders(writer, origin, named)
+	header.Set("Access-Control-Allow-Methods", strings.Join(middleware.Config.AllowedMethods, ", "))
+	if requestHeaders != "" {
+		header.Set("Access-Control-Allow-Headers", requestHeaders)
+	}
+	if middleware.Config.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(middleware.Config.MaxAge.Seconds())))
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// writeOriginHeaders echoes the origin instead of "*" so credentials keep working, they are
+// only allowed for origins named by a pattern, never for any origin through a bare "*"
+func (middleware *CorsMiddleware) writeOriginHeaders(writer http.ResponseWriter, origin string, named bool) {
+	writer.Header().Set("Access-Control-Allow-Origin", origin)
+	if middleware.Config.AllowCredentials && named {
+		writer.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// originAllowed also reports whether a pattern other than a bare "*" matched the origin
+func (middleware *CorsMiddleware) originAllowed(origin string) (allowed bool, named bool) {
+	if origin == "" {
+		return false, false
+	}
+	for _, pattern := range middleware.Config.AllowedOrigins {
+		if matchOrigin(strings.ToLower(pattern), strings.ToLower(origin)) {
+			allowed = true
+			named = named || strings.TrimSpace(pattern) != "*"
+		}
+	}
+	return allowed, named
+}
+
+func isPreflight(request *http.Request) bool {
+	return request.Method == http.MethodOptions &&
+		request.Header.Get("Origin") != "" &&
+		request.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// matchOrigin supports "*" wildcards anywhere in the pattern
+func matchOrigin(pattern string, origin string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == origin
+	}
+
+	if !strings.HasPrefix(origin, parts[0]) {
+		return false
+	}
+	origin = origin[len(parts[0]):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(origin, part)
+		if index < 0 {
+			return false
+		}
+		origin = origin[index+len(part):]
+	}
+	return strings.HasSuffix(origin, parts[len(parts)-1])
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
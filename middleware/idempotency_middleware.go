@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored with a key, headers set by
+// outer middlewares such as the rate limit counters are left out
+var replayedHeaders = []string{"Content-Type", "Content-Language", "Location", "Vary"}
+
+type IdempotencyConfig struct {
+	TTL     time.Duration // how long a key is remembered after its first response
+	Size    int           // maximum number of keys kept in memory
+	LockTTL time.Duration // how long a key stays claimed by a request that never finished, TTL when zero
+}
+
+// idempotentResponse is what gets stored for a key, a zero StatusCode marks
+// the claim of a request that is still running
+type idempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  int         `json:"status_code"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// IdempotencyMiddleware makes POST requests with an Idempotency-Key header safe to retry:
+// the first response is stored and replayed for repeated requests with the same key
+type IdempotencyMiddleware struct {
+	Handler http.Handler
+	Store   cache.Cache
+	Config  IdempotencyConfig
+}
+
+func NewIdempotencyMiddleware(handler http.Handler, store cache.Cache, config IdempotencyConfig) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		Handler: handler,
+		Store:   store,
+		Config:  config,
+	}
+}
+
+func (middleware *IdempotencyMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	idempotencyKey := request.Header.Get("Idempotency-Key")
+	if request.Method != http.MethodPost || idempotencyKey == "" {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		exception.WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", "Idempotency-Key must not be longer than 255 characters")
+		return
+	}
+
+	// an oversized body is reported like the controllers report it
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+
+	// keys are scoped to the caller, its tenant and the endpoint, the fingerprint covers the
+	// body and the headers telling how it is read, a body sent compressed counts as another request
+	principal, _ := PrincipalFromContext(request.Context())
+	key := "idempotency:" + principal.TenantId + ":" + principal.Method + ":" + principal.Name + ":" + request.URL.Path + ":" + idempotencyKey
+	hash := sha256.New()
+	hash.Write([]byte(request.Header.Get("Content-Type") + "\n" + contentEncoding(request) + "\n"))
+	hash.Write(body)
+	requestFingerprint := hex.EncodeToString(hash.Sum(nil))
+
+	// the key is claimed in the store before the handler runs, so only one request runs
+	// for a key whichever instance it reaches, the others find its claim or its response
+	if !middleware.claim(request, key, requestFingerprint) {
+		stored, ok := middleware.load(request, key)
+		switch {
+		case ok && stored.Fingerprint != requestFingerprint:
+			exception.WriteErrorResponse(writer, request, http.StatusUnprocessableEntity, "UNPROCESSABLE ENTITY", "Idempotency-Key was already used with a different request body, Content-Type or Content-Encoding")
+		case !ok || stored.StatusCode == 0:
+			exception.WriteErrorResponse(writer, request, http.StatusConflict, "CONFLICT", "a request with this Idempotency-Key is still in progress")
+		default:
+			for name, values := range stored.Header {
+				writer.Header()[name] = values
+			}
+			writer.Header().Set("Idempotent-Replayed", "true")
+			writer.WriteHeader(stored.StatusCode)
+			writer.Write(stored.Body)
+		}
+		return
+	}
+
+	// the claim is dropped unless a response replaces it, also when the handler panics
+	saved := false
+	defer func() {
+		if !saved {
+			middleware.release(request, key)
+		}
+	}()
+
+	recorder := &responseRecorder{ResponseWriter: writer, statusCode: http.StatusOK}
+	middleware.Handler.ServeHTTP(recorder, request)
+
+	// server errors are not stored, so the client can retry them
+	if recorder.statusCode >= 500 {
+		return
+	}
+	saved = middleware.save(request, key, idempotentResponse{
+		Fingerprint: requestFingerprint,
+		StatusCode:  recorder.statusCode,
+		Header:      storedHeader(writer.Header()),
+		Body:        recorder.body.Bytes(),
+	})
+}
+
+func storedHeader(header http.Header) http.Header {
+	stored := http.Header{}
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			stored[name] = values
+		}
+	}
+	return stored
+}
+
+// claim adds the key unless it is stored already, with the fingerprint and no response yet
+func (middleware *IdempotencyMiddleware) claim(request *http.Request, key string, fingerprint string) bool {
+	value, err := json.Marshal(idempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		log.Printf("idempotency: encode %v: %v", key, err)
+		return true
+	}
+	added, err := middleware.Store.Add(request.Context(), key, value, cmp.Or(middleware.Config.LockTTL, middleware.Config.TTL))
+	if err != nil {
+		log.Printf("idempotency: add %v: %v", key, err)
+		return true
+	}
+	return added
+}
+
+// release drops the claim of a request whose response is not stored
+func (middleware *IdempotencyMiddleware) release(request *http.Request, key string) {
+	if err := middleware.Store.Delete(context.WithoutCancel(request.Context()), key); err != nil {
+		log.Printf("idempotency: delete %v: %v", key, err)
+	}
+}
+
+// claim, load and save only log store failures, the request is then handled as if it had no key
+func (middleware *IdempotencyMiddleware) load(request *http.Request, key string) (idempotentResponse, bool) {
+	var stored idempotentResponse
+	value, ok, err := middleware.Store.Get(request.Context(), key)
+	if err != nil {
+		log.Printf("idempotency: get %v: %v", key, err)
+		return stored, false
+	}
+	if !ok {
+		return stored, false
+	}
+	if err := json.Unmarshal(value, &stored); err != nil {
+		log.Printf("idempotency: decode %v: %v", key, err)
+		return stored, false
+	}
+	return stored, true
+}
+
+func (middleware *IdempotencyMiddleware) save(request *http.Request, key string, response idempotentResponse) bool {
+	value, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("idempotency: encode %v: %v", key, err)
+		return false
+	}
+
+	// the request context may already be cancelled, the response was sent anyway
+	if err := middleware.Store.Set(context.WithoutCancel(request.Context()), key, value, middleware.Config.TTL); err != nil {
+		log.Printf("idempotency: set %v: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// responseRecorder writes through to the client and keeps a copy of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	if !recorder.wroteHeader {
+		recorder.statusCode = statusCode
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	recorder.wroteHeader = true
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+// JWTVerifier accepts HS256 tokens signed with Secret, such as the ones a gateway in front
+// of the API issues. The subject names the principal and TenantClaim holds its tenant.
+type JWTVerifier struct {
+	Secret      []byte
+	TenantClaim string
+	Leeway      time.Duration // allowed clock skew for exp and nbf
+	Now         func() time.Time
+}
+
+func NewJWTVerifier(secret string, tenantClaim string) *JWTVerifier {
+	return &JWTVerifier{
+		Secret:      []byte(secret),
+		TenantClaim: tenantClaim,
+		Leeway:      30 * time.Second,
+		Now:         time.Now,
+	}
+}
+
+// Verify checks the signature and the exp and nbf claims, a token without a valid tenant is rejected
+func (verifier *JWTVerifier) Verify(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("jwt: malformed token")
+	}
+
+	header := struct {
+		Algorithm string `json:"alg"`
+	}{}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return Principal{}, err
+	}
+	// the algorithm is fixed, a token cannot choose a weaker one such as none
+	if header.Algorithm != "HS256" {
+		return Principal{}, fmt.Errorf("jwt: unsupported algorithm %q", header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, errors.New("jwt: malformed signature")
+	}
+	mac := hmac.New(sha256.New, verifier.Secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Principal{}, errors.New("jwt: invalid signature")
+	}
+
+	claims := map[string]any{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return Principal{}, err
+	}
+	now := verifier.Now()
+	if expiresAt, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(expiresAt), 0).Add(verifier.Leeway)) {
+		return Principal{}, errors.New("jwt: token expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(verifier.Leeway).Before(time.Unix(int64(notBefore), 0)) {
+		return Principal{}, errors.New("jwt: token not valid yet")
+	}
+
+	tenantId, _ := claims[verifier.TenantClaim].(string)
+	if !tenant.Valid(tenantId) {
+		return Principal{}, fmt.Errorf("jwt: claim %v must hold a tenant id", verifier.TenantClaim)
+	}
+	subject, _ := claims["sub"].(string)
+	return Principal{Name: subject, Method: "jwt", TenantId: tenantId}, nil
+}
+
+func decodeJWTPart(part string, target any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("jwt: malformed token")
+	}
+	if err := json.Unmarshal(decoded, target); err != nil {
+		return errors.New("jwt: malformed token")
+	}
+	return nil
+}
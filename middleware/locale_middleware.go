@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+)
+
+// LocaleMiddleware puts the locales of Accept-Language in the request context, category names
+// and validation messages are translated into them
+type LocaleMiddleware struct {
+	Handler http.Handler
+}
+
+func NewLocaleMiddleware(handler http.Handler) *LocaleMiddleware {
+	return &LocaleMiddleware{
+		Handler: handler,
+	}
+}
+
+func (middleware *LocaleMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	// responses depend on the header, shared caches have to keep them apart
+	writer.Header().Add("Vary", "Accept-Language")
+
+	acceptLanguage := request.Header.Get("Accept-Language")
+	if acceptLanguage == "" {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+	ctx := locale.WithPreferences(request.Context(), locale.Parse(acceptLanguage))
+	middleware.Handler.ServeHTTP(writer, request.WithContext(ctx))
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+type OpenAPIValidationConfig struct {
+	ValidateRequests  bool // reject requests not matching the document with 400
+	ValidateResponses bool // report responses not matching the document, meant for development and tests
+}
+
+// OpenAPIValidationMiddleware checks requests and responses of the documented routes
+// against the OpenAPI document, routes missing from the document are not checked
+type OpenAPIValidationMiddleware struct {
+	Handler http.Handler
+	Config  OpenAPIValidationConfig
+	Router  routers.Router
+
+	// OnResponseError is called for every invalid response, the response was already sent.
+	// It logs by default, tests set it to fail.
+	OnResponseError func(request *http.Request, err error)
+}
+
+func NewOpenAPIValidationMiddleware(handler http.Handler, spec []byte, config OpenAPIValidationConfig) (*OpenAPIValidationMiddleware, error) {
+	document, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := document.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	// the server URLs are examples, routes are matched on the path alone
+	document.Servers = nil
+	router, err := legacy.NewRouter(document)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAPIValidationMiddleware{
+		Handler: handler,
+		Config:  config,
+		Router:  router,
+		OnResponseError: func(request *http.Request, err error) {
+			log.Printf("openapi: invalid response to %v %v: %v", request.Method, request.URL.Path, err)
+		},
+	}, nil
+}
+
+func (middleware *OpenAPIValidationMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	route, pathParams, err := middleware.Router.FindRoute(request)
+	if err != nil || (!middleware.Config.ValidateRequests && !middleware.Config.ValidateResponses) {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+
+	// only JSON bodies are described, the other media types are checked by the decoders
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    request,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			ExcludeRequestBody: !isJSON(request.Header.Get("Content-Type")),
+		},
+	}
+
+	if middleware.Config.ValidateRequests {
+		if err := openapi3filter.ValidateRequest(request.Context(), requestInput); err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				exception.ErrorHandler(writer, request, maxBytesError)
+				return
+			}
+			exception.WriteErrorResponse(writer, request, http.StatusBadRequest, "BAD REQUEST", requestErrorMessage(err))
+			return
+		}
+	}
+
+	if !middleware.Config.ValidateResponses {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: writer, statusCode: http.StatusOK}
+	middleware.Handler.ServeHTTP(recorder, request)
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 recorder.statusCode,
+		Header:                 writer.Header(),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			ExcludeResponseBody:   !isJSON(writer.Header().Get("Content-Type")),
+		},
+	}
+	responseInput.SetBodyBytes(recorder.body.Bytes())
+	if err := openapi3filter.ValidateResponse(request.Context(), responseInput); err != nil {
+		middleware.OnResponseError(request, err)
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// requestErrorMessage leaves out the schema dump kin-openapi appends to its errors
+func requestErrorMessage(err error) string {
+	var requestError *openapi3filter.RequestError
+	if !errors.As(err, &requestError) {
+		return err.Error()
+	}
+
+	var schemaError *openapi3.SchemaError
+	if errors.As(requestError.Err, &schemaError) {
+		field := schemaError.JSONPointer()
+		prefix := "request body"
+		if requestError.Parameter != nil {
+			prefix = requestError.Parameter.In + " parameter " + requestError.Parameter.Name
+		}
+		if len(field) > 0 {
+			return prefix + " field \"" + strings.Join(field, ".") + "\": " + schemaError.Reason
+		}
+		return prefix + ": " + schemaError.Reason
+	}
+	return requestError.Error()
+}
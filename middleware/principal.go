@@ -0,0 +1,20 @@
+package middleware
+
+import "context"
+
+type Principal struct {
+	Name     string
+	Method   string // api_key, client_certificate or jwt
+	TenantId string
+}
+
+type principalContextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+)
+
+type RateLimit struct {
+	Rate  float64 // tokens refilled per second
+	Burst int     // bucket capacity
+}
+
+type RateLimitConfig struct {
+	Default     RateLimit
+	Routes      map[string]RateLimit // keyed by "METHOD /path/:param", or a gRPC method such as "/category.v1.CategoryService/List"
+	Keys        map[string]RateLimit // keyed by API key
+	AuthFailure RateLimit            // failed authentications per client IP
+	IdleTimeout time.Duration
+}
+
+type tokenBucket struct {
+	limit    RateLimit
+	tokens   float64
+	lastSeen time.Time
+}
+
+// limiter keeps the token buckets of the clients, buckets unused for idleTimeout are dropped
+type limiter struct {
+	idleTimeout time.Duration
+
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newLimiter(idleTimeout time.Duration) *limiter {
+	return &limiter{
+		idleTimeout: idleTimeout,
+		buckets:     map[string]*tokenBucket{},
+		lastSweep:   time.Now(),
+	}
+}
+
+// RateLimitMiddleware runs behind the auth middleware, so buckets belong to credentials it
+// accepted. Requests without a principal, such as the public paths, are limited per client IP.
+type RateLimitMiddleware struct {
+	Handler http.Handler
+	Config  RateLimitConfig
+
+	limiter *limiter
+}
+
+func NewRateLimitMiddleware(handler http.Handler, config RateLimitConfig) *RateLimitMiddleware {
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 10 * time.Minute
+	}
+	return &RateLimitMiddleware{
+		Handler: handler,
+		Config:  config,
+		limiter: newLimiter(config.IdleTimeout),
+	}
+}
+
+func (middleware *RateLimitMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	route, _, _ := lookupRoute(middleware.Config.Routes, request)
+	limits := middleware.limits(request.Context(), route, request.Header.Get("X-API-Key"), request.RemoteAddr)
+	if len(limits) == 0 {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+
+	allowed, state := middleware.limiter.take(limits, time.Now())
+
+	header := writer.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(state.limit.Burst))
+	header.Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(state.tokens))))
+	header.Set("RateLimit-Reset", strconv.Itoa(secondsUntil(state.limit, float64(state.limit.Burst)-state.tokens)))
+
+	if !allowed {
+		header.Set("Retry-After", strconv.Itoa(secondsUntil(state.limit, 1-state.tokens)))
+		exception.WriteErrorResponse(writer, request, http.StatusTooManyRequests, "TOO MANY REQUESTS", "rate limit exceeded")
+		return
+	}
+
+	middleware.Handler.ServeHTTP(writer, request)
+}
+
+// Allow takes a token for a call that is not an HTTP request, the gRPC server passes its
+// method as route. It shares the buckets of ServeHTTP, retryAfter is in seconds.
+func (middleware *RateLimitMiddleware) Allow(ctx context.Context, route string, apiKey string, remoteAddr string) (allowed bool, retryAfter int) {
+	limits := middleware.limits(ctx, route, apiKey, remoteAddr)
+	if len(limits) == 0 {
+		return true, 0
+	}
+	allowed, state := middleware.limiter.take(limits, time.Now())
+	if allowed {
+		return true, 0
+	}
+	return false, secondsUntil(state.limit, 1-state.tokens)
+}
+
+// limits returns the buckets of a call, every client has a global bucket, and another one for
+// routes with their own limit. The limit of an API key only applies once the key was accepted.
+func (middleware *RateLimitMiddleware) limits(ctx context.Context, route string, apiKey string, remoteAddr string) map[string]RateLimit {
+	principal, authenticated := PrincipalFromContext(ctx)
+	client := clientIdentity(principal, authenticated, remoteAddr)
+
+	limits := map[string]RateLimit{}
+	if limit, ok := middleware.Config.Keys[apiKey]; ok && authenticated && principal.Method == "api_key" {
+		limits[client] = limit
+	} else if middleware.Config.Default.Rate > 0 {
+		limits[client] = middleware.Config.Default
+	}
+	if limit, ok := middleware.Config.Routes[route]; ok && route != "" {
+		limits[client+"|"+route] = limit
+	}
+	return limits
+}
+
+// take consumes one token from every bucket only when all of them have one,
+// and returns the most restrictive bucket for the response headers
+func (limiter *limiter) take(limits map[string]RateLimit, now time.Time) (bool, tokenBucket) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.sweep(now)
+
+	allowed := true
+	var tightest *tokenBucket
+	buckets := make([]*tokenBucket, 0, len(limits))
+	for key, limit := range limits {
+		bucket := limiter.refill(key, limit, now)
+
+		if bucket.tokens < 1 {
+			allowed = false
+		}
+		if tightest == nil || bucket.tokens < tightest.tokens {
+			tightest = bucket
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if allowed {
+		for _, bucket := range buckets {
+			bucket.tokens--
+		}
+	}
+	return allowed, *tightest
+}
+
+// available reports whether the bucket of key has a token left, without taking it
+func (limiter *limiter) available(key string, limit RateLimit, now time.Time) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.sweep(now)
+	return limiter.refill(key, limit, now).tokens >= 1
+}
+
+// refill returns the bucket of key with the tokens earned since it was last seen
+func (limiter *limiter) refill(key string, limit RateLimit, now time.Time) *tokenBucket {
+	bucket, ok := limiter.buckets[key]
+	if !ok || bucket.limit != limit {
+		bucket = &tokenBucket{limit: limit, tokens: float64(limit.Burst), lastSeen: now}
+		limiter.buckets[key] = bucket
+	}
+
+	// refill the bucket based on the elapsed time
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.Rate)
+	bucket.lastSeen = now
+	return bucket
+}
+
+// sweep removes buckets that have not been used within the idle timeout
+func (limiter *limiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < limiter.idleTimeout/2 {
+		return
+	}
+	for key, bucket := range limiter.buckets {
+		if now.Sub(bucket.lastSeen) > limiter.idleTimeout {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.lastSweep = now
+}
+
+// clientIdentity uses the tenant and principal the auth middleware accepted, otherwise the
+// client IP. Unverified credentials are never part of it, new ones would get new buckets.
+func clientIdentity(principal Principal, authenticated bool, remoteAddr string) string {
+	if authenticated {
+		return "tenant:" + principal.TenantId + "|" + principal.Method + ":" + principal.Name
+	}
+	return "ip:" + remoteHost(remoteAddr)
+}
+
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func secondsUntil(limit RateLimit, tokens float64) int {
+	if tokens <= 0 || limit.Rate <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / limit.Rate))
+}
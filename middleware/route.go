@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// lookupRoute finds the value configured for the request, routes are keyed by
+// "METHOD /path/:param" using httprouter style patterns
+func lookupRoute[V any](routes map[string]V, request *http.Request) (string, V, bool) {
+	match := ""
+	for route := range routes {
+		method, pattern, ok := strings.Cut(route, " ")
+		if !ok || method != request.Method || !matchPath(pattern, request.URL.Path) {
+			continue
+		}
+		if match == "" || moreSpecific(route, match) {
+			match = route
+		}
+	}
+	if match == "" {
+		var zero V
+		return "", zero, false
+	}
+	return match, routes[match], true
+}
+
+// moreSpecific orders the routes matching a request the way httprouter picks between them,
+// segment by segment a static segment beats a parameter and a parameter beats a catch-all.
+// Routes as specific as each other are compared as text, so the same route is picked whatever the map order
+func moreSpecific(route string, other string) bool {
+	_, pattern, _ := strings.Cut(route, " ")
+	_, otherPattern, _ := strings.Cut(other, " ")
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	otherSegments := strings.Split(strings.Trim(otherPattern, "/"), "/")
+
+	for i := 0; i < len(segments) && i < len(otherSegments); i++ {
+		if kind, otherKind := segmentKind(segments[i]), segmentKind(otherSegments[i]); kind != otherKind {
+			return kind < otherKind
+		}
+	}
+	if len(segments) != len(otherSegments) {
+		return len(segments) > len(otherSegments)
+	}
+	return route < other
+}
+
+// segmentKind ranks a pattern segment, static segments first, then parameters, then catch-alls
+func segmentKind(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, "*"):
+		return 2
+	case strings.HasPrefix(segment, ":"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// matchPath compares a path against an httprouter style pattern
+func matchPath(pattern string, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if !strings.HasPrefix(segment, ":") && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(pathSegments)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type TimeoutConfig struct {
+	Default time.Duration
+	Routes  map[string]time.Duration // keyed by "METHOD /path/:param", or a gRPC method such as "/category.v1.CategoryService/List"
+}
+
+// TimeoutMiddleware puts a deadline on the request context, so database calls
+// made with that context are cancelled once the deadline is hit
+type TimeoutMiddleware struct {
+	Handler http.Handler
+	Config  TimeoutConfig
+}
+
+func NewTimeoutMiddleware(handler http.Handler, config TimeoutConfig) *TimeoutMiddleware {
+	return &TimeoutMiddleware{
+		Handler: handler,
+		Config:  config,
+	}
+}
+
+func (middleware *TimeoutMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	timeout := middleware.Config.Default
+	if _, routeTimeout, ok := lookupRoute(middleware.Config.Routes, request); ok {
+		timeout = routeTimeout
+	}
+
+	if timeout <= 0 {
+		middleware.Handler.ServeHTTP(writer, request)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), timeout)
+	defer cancel()
+	middleware.Handler.ServeHTTP(writer, request.WithContext(ctx))
+}
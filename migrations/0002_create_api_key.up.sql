@@ -0,0 +1,9 @@
+CREATE TABLE api_key (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL,
+    UNIQUE KEY api_key_name (name),
+    UNIQUE KEY api_key_key_hash (key_hash)
+) ENGINE = InnoDB;
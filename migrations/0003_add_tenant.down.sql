@@ -0,0 +1,3 @@
+ALTER TABLE api_key DROP COLUMN tenant_id;
+ALTER TABLE category DROP INDEX category_tenant_name;
+ALTER TABLE category DROP COLUMN tenant_id;
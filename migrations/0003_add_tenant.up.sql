@@ -0,0 +1,12 @@
+ALTER TABLE category ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' AFTER id;
+
+-- names become unique per tenant, older duplicates keep their name and newer ones get their id appended
+UPDATE category duplicate
+JOIN (
+    SELECT tenant_id, name, MIN(id) AS id FROM category GROUP BY tenant_id, name HAVING COUNT(*) > 1
+) kept ON duplicate.tenant_id = kept.tenant_id AND duplicate.name = kept.name AND duplicate.id <> kept.id
+SET duplicate.name = CONCAT(LEFT(duplicate.name, 185), ' (', duplicate.id, ')');
+
+ALTER TABLE category ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE category ADD UNIQUE KEY category_tenant_name (tenant_id, name);
+ALTER TABLE api_key ADD COLUMN tenant_id VARCHAR(64) NULL AFTER name;
@@ -0,0 +1,8 @@
+CREATE TABLE category_translation (
+    category_id INT NOT NULL,
+    locale VARCHAR(35) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    PRIMARY KEY (category_id, locale),
+    KEY category_translation_locale (locale),
+    CONSTRAINT category_translation_category FOREIGN KEY (category_id) REFERENCES category (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
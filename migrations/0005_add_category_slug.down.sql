@@ -0,0 +1,3 @@
+DROP TABLE category_slug_redirect;
+ALTER TABLE category DROP INDEX category_tenant_slug;
+ALTER TABLE category DROP COLUMN slug;
@@ -0,0 +1,24 @@
+-- slugs are compared byte for byte, the default collation would find cafe when asked for café
+ALTER TABLE category ADD COLUMN slug VARCHAR(200) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NULL AFTER name;
+
+-- existing categories get the letters and digits of their name, the service also transliterates
+-- the ones it creates; duplicates get their id appended
+UPDATE category SET slug = COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(LOWER(REGEXP_REPLACE(name, '[^[:alnum:]]+', '-')), 190)), ''), 'category');
+UPDATE category duplicate
+JOIN (
+    SELECT tenant_id, slug, MIN(id) AS id FROM category GROUP BY tenant_id, slug HAVING COUNT(*) > 1
+) kept ON duplicate.tenant_id = kept.tenant_id AND duplicate.slug = kept.slug AND duplicate.id <> kept.id
+SET duplicate.slug = CONCAT(duplicate.slug, '-', duplicate.id);
+
+ALTER TABLE category MODIFY COLUMN slug VARCHAR(200) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL;
+ALTER TABLE category ADD UNIQUE KEY category_tenant_slug (tenant_id, slug);
+
+-- the slugs a category had before, lookups by them are redirected to its current slug
+CREATE TABLE category_slug_redirect (
+    tenant_id VARCHAR(64) NOT NULL,
+    slug VARCHAR(200) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    category_id INT NOT NULL,
+    PRIMARY KEY (tenant_id, slug),
+    KEY category_slug_redirect_category (category_id),
+    CONSTRAINT category_slug_redirect_category FOREIGN KEY (category_id) REFERENCES category (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,8 @@
+ALTER TABLE category DROP INDEX category_tenant_sort_order;
+ALTER TABLE category
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at,
+    DROP COLUMN is_active,
+    DROP COLUMN sort_order,
+    DROP COLUMN image_url,
+    DROP COLUMN description;
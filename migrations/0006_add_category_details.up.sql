@@ -0,0 +1,15 @@
+ALTER TABLE category
+    ADD COLUMN description VARCHAR(2000) NOT NULL DEFAULT '' AFTER slug,
+    ADD COLUMN image_url VARCHAR(2048) NOT NULL DEFAULT '' AFTER description,
+    ADD COLUMN sort_order INT NOT NULL DEFAULT 0 AFTER image_url,
+    ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE AFTER sort_order,
+    ADD COLUMN created_at DATETIME NULL AFTER is_active,
+    ADD COLUMN updated_at DATETIME NULL AFTER created_at;
+
+-- the service sets the timestamps in UTC, existing categories get the time of the migration
+UPDATE category SET created_at = UTC_TIMESTAMP(), updated_at = UTC_TIMESTAMP();
+
+ALTER TABLE category
+    MODIFY COLUMN created_at DATETIME NOT NULL,
+    MODIFY COLUMN updated_at DATETIME NOT NULL;
+ALTER TABLE category ADD KEY category_tenant_sort_order (tenant_id, sort_order, id);
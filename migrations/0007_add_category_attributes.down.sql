@@ -0,0 +1,5 @@
+DROP TABLE category_attribute_schema;
+ALTER TABLE category DROP INDEX category_tenant_type;
+ALTER TABLE category
+    DROP COLUMN attributes,
+    DROP COLUMN type;
@@ -0,0 +1,14 @@
+ALTER TABLE category
+    ADD COLUMN type VARCHAR(64) NOT NULL DEFAULT '' AFTER slug,
+    ADD COLUMN attributes JSON NULL AFTER type;
+
+ALTER TABLE category ADD KEY category_tenant_type (tenant_id, type);
+
+-- the schema with an empty category_type applies to the categories of the tenant without a schema of their own
+CREATE TABLE category_attribute_schema (
+    tenant_id VARCHAR(64) NOT NULL,
+    category_type VARCHAR(64) NOT NULL,
+    definition JSON NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (tenant_id, category_type)
+) ENGINE = InnoDB;
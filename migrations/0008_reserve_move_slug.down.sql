@@ -0,0 +1,2 @@
+-- the renamed slugs are kept, they still lead to their categories
+DO 0;
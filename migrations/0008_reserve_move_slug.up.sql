@@ -0,0 +1,4 @@
+-- move is reserved, POST /api/categories/{id}/move makes GET /api/categories/by-slug/move unreachable,
+-- categories that have it get their id appended like duplicates did
+UPDATE category SET slug = CONCAT(slug, '-', id) WHERE slug = 'move';
+DELETE FROM category_slug_redirect WHERE slug = 'move';
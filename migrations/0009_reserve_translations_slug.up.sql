@@ -0,0 +1,4 @@
+-- translations is reserved as well, GET /api/categories/by-slug/translations is routed to the translations
+-- of a category, categories whose name gave them that slug in 0005 get their id appended like move
+UPDATE category SET slug = CONCAT(slug, '-', id) WHERE slug = 'translations';
+DELETE FROM category_slug_redirect WHERE slug = 'translations';
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds the schema migrations, NNNN_name.up.sql applies a version and NNNN_name.down.sql reverts it
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// APIKey only keeps the SHA-256 hash of the key, the key itself is shown once when issued
+type APIKey struct {
+	Id        int
+	Name      string
+	TenantId  string // empty when the key may act for any tenant
+	KeyHash   string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
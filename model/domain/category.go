@@ -1,6 +1,17 @@
 package domain
 
+import "time"
+
 type Category struct {
-	Id   int
-	Name string
+	Id          int
+	Name        string
+	Slug        string
+	Type        string
+	Attributes  map[string]any
+	Description string
+	ImageURL    string
+	SortOrder   int
+	IsActive    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// CategoryAttributeSchema is the JSON Schema the attributes of the categories of a type must match,
+// the one with an empty CategoryType applies to the categories of types without one
+type CategoryAttributeSchema struct {
+	CategoryType string
+	Definition   []byte
+	UpdatedAt    time.Time
+}
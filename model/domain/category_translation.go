@@ -0,0 +1,8 @@
+package domain
+
+// CategoryTranslation is the name of a category in a locale, a canonical BCP 47 tag
+type CategoryTranslation struct {
+	CategoryId int
+	Locale     string
+	Name       string
+}
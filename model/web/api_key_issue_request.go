@@ -0,0 +1,6 @@
+package web
+
+type APIKeyIssueRequest struct {
+	Name     string `json:"name" validate:"required,min=1,max=100"`
+	TenantId string `json:"tenant_id"` // empty for a key that picks the tenant with X-Tenant-ID
+}
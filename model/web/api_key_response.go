@@ -0,0 +1,13 @@
+package web
+
+import "time"
+
+// Key is only set in the response to issuing the key
+type APIKeyResponse struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	TenantId  string     `json:"tenant_id,omitempty"`
+	Key       string     `json:"key,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,10 @@
+package web
+
+import "time"
+
+// CategoryType is empty for the schema of the tenant, Schema is left out of XML like category attributes
+type CategoryAttributeSchemaResponse struct {
+	CategoryType string         `json:"category_type" xml:"category_type" example:"product"`
+	Schema       map[string]any `json:"schema" xml:"-" example:"{\"type\":\"object\",\"required\":[\"color\"],\"properties\":{\"color\":{\"type\":\"string\",\"enum\":[\"black\",\"white\"]}}}"`
+	UpdatedAt    time.Time      `json:"updated_at" xml:"updated_at" example:"2026-01-02T03:04:05Z"`
+}
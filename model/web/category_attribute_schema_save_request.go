@@ -0,0 +1,7 @@
+package web
+
+// CategoryType comes from the path, it is empty for the schema of the tenant
+type CategoryAttributeSchemaSaveRequest struct {
+	CategoryType string         `json:"category_type" xml:"category_type" validate:"max=64" openapi:"-"`
+	Schema       map[string]any `json:"schema" xml:"-" validate:"required" example:"{\"type\":\"object\",\"required\":[\"color\"],\"properties\":{\"color\":{\"type\":\"string\",\"enum\":[\"black\",\"white\"]}}}"`
+}
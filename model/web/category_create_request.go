@@ -1,5 +1,14 @@
 package web
 
+// Slug is derived from Name when empty, IsActive is true when left out. Attributes are checked
+// against the attribute schema of Type, or of the tenant when Type has none.
 type CategoryCreateRequest struct {
-	Name string `json:"name" validate:"required,min=1,max=200"`
+	Name        string         `json:"name" xml:"name" validate:"required,min=1,max=200" example:"Electronics"`
+	Slug        string         `json:"slug,omitempty" xml:"slug,omitempty" validate:"max=200" example:"electronics"`
+	Type        string         `json:"type,omitempty" xml:"type,omitempty" validate:"max=64" example:"product"`
+	Attributes  map[string]any `json:"attributes,omitempty" xml:"-" validate:"max=100" example:"{\"color\":\"black\",\"warranty_years\":2}"`
+	Description string         `json:"description,omitempty" xml:"description,omitempty" validate:"max=2000" example:"Phones, laptops and accessories"`
+	ImageURL    string         `json:"image_url,omitempty" xml:"image_url,omitempty" validate:"omitempty,max=2048,http_url" example:"https://cdn.example.com/electronics.png"`
+	SortOrder   int            `json:"sort_order,omitempty" xml:"sort_order,omitempty" validate:"min=-1000000,max=1000000" example:"10"`
+	IsActive    *bool          `json:"is_active,omitempty" xml:"is_active,omitempty" example:"true"`
 }
@@ -0,0 +1,23 @@
+package web
+
+import "time"
+
+// CategoryListRequest filters the list of categories, it is read from the query string.
+// Fields left nil match every category, the ranges include After and exclude Before.
+// Attributes maps attribute paths such as color or size.width to the values they may have,
+// the values are compared as text. A Limit above zero returns a page in the order of the list,
+// by sort_order and id: at most Limit categories following the one with AfterSortOrder and
+// AfterId, or the first ones when AfterId is 0. The gRPC and GraphQL servers page this way.
+type CategoryListRequest struct {
+	NameContains   *string // case insensitive part of the name, GraphQL only
+	Type           *string
+	IsActive       *bool
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	UpdatedAfter   *time.Time
+	UpdatedBefore  *time.Time
+	Attributes     map[string][]string
+	AfterSortOrder int
+	AfterId        int
+	Limit          int
+}
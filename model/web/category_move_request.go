@@ -0,0 +1,9 @@
+package web
+
+// Id comes from the path, so it is not part of the documented body. Exactly one of
+// BeforeId and AfterId names the category the moved one is placed next to.
+type CategoryMoveRequest struct {
+	Id       int  `json:"id" xml:"id" validate:"required" openapi:"-"`
+	BeforeId *int `json:"before_id,omitempty" xml:"before_id,omitempty" validate:"omitempty,min=1" example:"3"`
+	AfterId  *int `json:"after_id,omitempty" xml:"after_id,omitempty" validate:"omitempty,min=1" example:"2"`
+}
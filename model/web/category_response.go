@@ -1,6 +1,20 @@
 package web
 
+import "time"
+
+// Locale is set when Name is a translation picked through Accept-Language. Attributes are
+// left out of XML, which has no way to write arbitrary JSON.
 type CategoryResponse struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
+	Id          int            `json:"id" xml:"id" example:"1"`
+	Name        string         `json:"name" xml:"name" example:"Electronics"`
+	Slug        string         `json:"slug" xml:"slug" example:"electronics"`
+	Type        string         `json:"type" xml:"type" example:"product"`
+	Attributes  map[string]any `json:"attributes" xml:"-" example:"{\"color\":\"black\",\"warranty_years\":2}"`
+	Description string         `json:"description" xml:"description" example:"Phones, laptops and accessories"`
+	ImageURL    string         `json:"image_url" xml:"image_url" example:"https://cdn.example.com/electronics.png"`
+	SortOrder   int            `json:"sort_order" xml:"sort_order" example:"10"`
+	IsActive    bool           `json:"is_active" xml:"is_active" example:"true"`
+	CreatedAt   time.Time      `json:"created_at" xml:"created_at" example:"2026-01-02T03:04:05Z"`
+	UpdatedAt   time.Time      `json:"updated_at" xml:"updated_at" example:"2026-01-02T03:04:05Z"`
+	Locale      string         `json:"locale,omitempty" xml:"locale,omitempty" example:"fr"`
 }
@@ -0,0 +1,7 @@
+package web
+
+type CategoryTranslationResponse struct {
+	CategoryId int    `json:"category_id" xml:"category_id" example:"1"`
+	Locale     string `json:"locale" xml:"locale" example:"fr"`
+	Name       string `json:"name" xml:"name" example:"Électronique"`
+}
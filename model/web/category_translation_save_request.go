@@ -0,0 +1,8 @@
+package web
+
+// CategoryId and Locale come from the path, so they are not part of the documented body
+type CategoryTranslationSaveRequest struct {
+	CategoryId int    `json:"category_id" xml:"category_id" validate:"required" openapi:"-"`
+	Locale     string `json:"locale" xml:"locale" validate:"required,max=35,bcp47_language_tag" openapi:"-"`
+	Name       string `json:"name" xml:"name" validate:"required,min=1,max=200" example:"Électronique"`
+}
@@ -1,6 +1,17 @@
 package web
 
+// Id comes from the path, so it is not part of the documented body. When Slug is empty
+// it is derived from Name again if Name changes. The other fields keep their value when
+// left out, an empty Description or ImageURL clears it. Attributes replace the ones the
+// category has, an empty object clears them.
 type CategoryUpdateRequest struct {
-	Id   int    `json:"id" validate:"required"`
-	Name string `json:"name" validate:"required,min=1,max=200"`
+	Id          int            `json:"id" xml:"id" validate:"required" openapi:"-"`
+	Name        string         `json:"name" xml:"name" validate:"required,min=1,max=200" example:"Electronics"`
+	Slug        string         `json:"slug,omitempty" xml:"slug,omitempty" validate:"max=200" example:"electronics"`
+	Type        *string        `json:"type,omitempty" xml:"type,omitempty" validate:"omitempty,max=64" example:"product"`
+	Attributes  map[string]any `json:"attributes,omitempty" xml:"-" validate:"omitempty,max=100" example:"{\"color\":\"black\",\"warranty_years\":2}"`
+	Description *string        `json:"description,omitempty" xml:"description,omitempty" validate:"omitempty,max=2000" example:"Phones, laptops and accessories"`
+	ImageURL    *string        `json:"image_url,omitempty" xml:"image_url,omitempty" validate:"omitzero,max=2048,http_url" example:"https://cdn.example.com/electronics.png"`
+	SortOrder   *int           `json:"sort_order,omitempty" xml:"sort_order,omitempty" validate:"omitempty,min=-1000000,max=1000000" example:"10"`
+	IsActive    *bool          `json:"is_active,omitempty" xml:"is_active,omitempty" example:"true"`
 }
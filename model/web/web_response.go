@@ -1,7 +1,10 @@
 package web
 
+import "encoding/xml"
+
 type WebResponse struct {
-	Code   int    `json:"code"`
-	Status string `json:"status"`
-	Data   any    `json:"data"`
+	XMLName xml.Name `json:"-" xml:"response"`
+	Code    int      `json:"code" xml:"code"`
+	Status  string   `json:"status" xml:"status"`
+	Data    any      `json:"data" xml:"data"`
 }
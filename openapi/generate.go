@@ -0,0 +1,367 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const mediaTypeJSON = "application/json"
+
+// Operation describes a route for the generator, the path uses the httprouter syntax
+type Operation struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Parameters overrides the generated path parameters by name and adds query and header parameters,
+	// path parameters without an entry are documented as strings
+	Parameters []Parameter
+
+	Request  any // zero value of the request body, nil when there is none
+	Response any // zero value of the data field of the response, nil when data is null
+	Redirect int // status code of responses with the same data and a Location header, 0 when there are none
+	Errors   []int
+}
+
+type generator struct {
+	schemas   map[string]*Schema
+	responses map[string]*Response
+}
+
+// Generate fills the paths and components of document from the operations,
+// the request and response schemas come from the struct types and their validate tags
+func Generate(document Document, operations []Operation) Document {
+	generator := &generator{
+		schemas:   map[string]*Schema{},
+		responses: map[string]*Response{},
+	}
+
+	document.Paths = map[string]PathItem{}
+	for _, operation := range operations {
+		path := openAPIPath(operation.Path)
+		if document.Paths[path] == nil {
+			document.Paths[path] = PathItem{}
+		}
+		document.Paths[path][strings.ToLower(operation.Method)] = generator.operation(operation)
+	}
+
+	document.Components.Schemas = generator.schemas
+	document.Components.Responses = generator.responses
+	return document
+}
+
+func (generator *generator) operation(operation Operation) *OperationObject {
+	object := &OperationObject{
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		OperationID: operation.ID,
+		Tags:        operation.Tags,
+		Parameters:  parameters(operation),
+		Responses:   map[string]*Response{},
+	}
+
+	if operation.Request != nil {
+		object.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				mediaTypeJSON: {Schema: generator.schema(reflect.TypeOf(operation.Request))},
+			},
+		}
+	}
+
+	data := &Schema{Type: "object", Nullable: true}
+	if operation.Response != nil {
+		data = generator.schema(reflect.TypeOf(operation.Response))
+	}
+	object.Responses[strconv.Itoa(http.StatusOK)] = &Response{
+		Description: http.StatusText(http.StatusOK),
+		Content: map[string]MediaType{
+			mediaTypeJSON: {Schema: envelope(data)},
+		},
+	}
+
+	if operation.Redirect != 0 {
+		object.Responses[strconv.Itoa(operation.Redirect)] = &Response{
+			Description: http.StatusText(operation.Redirect),
+			Headers: map[string]*Header{
+				"Location": {Description: "Where the resource is now", Schema: &Schema{Type: "string"}},
+			},
+			Content: object.Responses[strconv.Itoa(http.StatusOK)].Content,
+		}
+	}
+
+	for _, statusCode := range operation.Errors {
+		object.Responses[strconv.Itoa(statusCode)] = &Response{Ref: "#/components/responses/" + generator.errorResponse(statusCode)}
+	}
+	return object
+}
+
+// errorResponse registers the shared response of a status code, errors carry a message in data
+func (generator *generator) errorResponse(statusCode int) string {
+	name := strings.ReplaceAll(http.StatusText(statusCode), " ", "")
+	generator.responses[name] = &Response{
+		Description: http.StatusText(statusCode),
+		Content: map[string]MediaType{
+			mediaTypeJSON: {
+				Schema: envelope(&Schema{Type: "string"}),
+				Example: map[string]any{
+					"code":   statusCode,
+					"status": strings.ToUpper(http.StatusText(statusCode)),
+					"data":   strings.ToLower(http.StatusText(statusCode)),
+				},
+			},
+		},
+	}
+	return name
+}
+
+// envelope wraps data in the web.WebResponse fields
+func envelope(data *Schema) *Schema {
+	return &Schema{
+		Type:     "object",
+		Required: []string{"code", "status", "data"},
+		Properties: map[string]*Schema{
+			"code":   {Type: "integer"},
+			"status": {Type: "string"},
+			"data":   data,
+		},
+	}
+}
+
+// parameters turns ":name" and "*name" path segments into path parameters
+func parameters(operation Operation) []Parameter {
+	var result []Parameter
+	overrides := map[string]bool{}
+	for _, segment := range strings.Split(operation.Path, "/") {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		parameter := Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		for _, override := range operation.Parameters {
+			if override.In == "path" && override.Name == name {
+				parameter = override
+				parameter.Required = true
+				overrides[name] = true
+			}
+		}
+		result = append(result, parameter)
+	}
+
+	for _, parameter := range operation.Parameters {
+		if parameter.In != "path" || !overrides[parameter.Name] {
+			result = append(result, parameter)
+		}
+	}
+	return result
+}
+
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema describes a Go type, named structs become components referenced by $ref
+func (generator *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := generator.schemas[t.Name()]; !ok {
+			generator.schemas[t.Name()] = &Schema{} // placeholder for recursive types
+			generator.schemas[t.Name()] = generator.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		return generator.structSchema(t)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: generator.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object"}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema uses the json names of the fields, fields tagged openapi:"-" are left out
+func (generator *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("openapi") == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := generator.schema(field.Type)
+		if constrain(fieldSchema, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		if example, ok := field.Tag.Lookup("example"); ok {
+			fieldSchema.Example = exampleValue(fieldSchema.Type, example)
+		}
+		schema.Properties[name] = fieldSchema
+	}
+	return schema
+}
+
+// constrain maps validator tags to schema constraints and reports whether the field is required,
+// constraints cannot sit next to a $ref so referenced structs are left alone
+func constrain(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			break // the following rules apply to the elements
+		}
+		if name == "required" {
+			required = true
+			continue
+		}
+		if schema.Ref != "" {
+			continue
+		}
+
+		switch name {
+		case "min", "gte":
+			setLowerBound(schema, param, false)
+		case "max", "lte":
+			setUpperBound(schema, param, false)
+		case "gt":
+			setLowerBound(schema, param, true)
+		case "lt":
+			setUpperBound(schema, param, true)
+		case "len":
+			setLowerBound(schema, param, false)
+			setUpperBound(schema, param, false)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, exampleValue(schema.Type, value))
+			}
+		case "email":
+			schema.Format = "email"
+		case "url", "uri", "http_url":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "datetime":
+			schema.Format = "date-time"
+		case "alpha":
+			schema.Pattern = "^[a-zA-Z]+$"
+		case "alphanum":
+			schema.Pattern = "^[a-zA-Z0-9]+$"
+		case "numeric":
+			schema.Pattern = "^[-+]?[0-9]+(?:\\.[0-9]+)?$"
+		case "lowercase":
+			schema.Pattern = "^[^A-Z]*$"
+		}
+	}
+	return required
+}
+
+// setLowerBound applies to the length of strings, the size of arrays or the value of numbers
+func setLowerBound(schema *Schema, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		length := int(value)
+		if exclusive {
+			length++
+		}
+		schema.MinLength = &length
+	case "array":
+		items := int(value)
+		if exclusive {
+			items++
+		}
+		schema.MinItems = &items
+	case "integer", "number":
+		schema.Minimum = &value
+		schema.ExclusiveMinimum = exclusive
+	}
+}
+
+func setUpperBound(schema *Schema, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		length := int(value)
+		if exclusive {
+			length--
+		}
+		schema.MaxLength = &length
+	case "array":
+		items := int(value)
+		if exclusive {
+			items--
+		}
+		schema.MaxItems = &items
+	case "integer", "number":
+		schema.Maximum = &value
+		schema.ExclusiveMaximum = exclusive
+	}
+}
+
+// exampleValue converts a tag value to the JSON type of the schema
+func exampleValue(schemaType string, value string) any {
+	switch schemaType {
+	case "integer":
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	case "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			return boolean
+		}
+	case "object":
+		var object map[string]any
+		if err := json.Unmarshal([]byte(value), &object); err == nil {
+			return object
+		}
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+// the Redoc bundle is vendored into ui/ and embedded, so the page loads no third-party script,
+// a new Redoc release is taken by changing the version below and regenerating
+//go:generate curl -fsSL -o ui/redoc.standalone.js https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js
+
+//go:embed ui
+var ui embed.FS
+
+// BundlePath is where NewBundleHandler is expected to be mounted
+const BundlePath = "/docs/redoc.standalone.js"
+
+// Marshal encodes the document the way it is checked in as apispec.json
+func Marshal(document Document) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// NewHandler serves the document as JSON, it is encoded once
+func NewHandler(document Document) http.Handler {
+	body, err := Marshal(document)
+	if err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+	})
+}
+
+// NewUIHandler serves a Redoc page rendering the document found at specURL,
+// without a vendored bundle the page only points at the document
+func NewUIHandler(title string, specURL string) http.Handler {
+	bundleURL := ""
+	if _, err := fs.Stat(ui, "ui/redoc.standalone.js"); err == nil {
+		bundleURL = BundlePath
+	}
+
+	var body bytes.Buffer
+	page := template.Must(template.ParseFS(ui, "ui/index.html"))
+	if err := page.Execute(&body, map[string]string{"Title": title, "SpecURL": specURL, "BundleURL": bundleURL}); err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.Write(body.Bytes())
+	})
+}
+
+// NewBundleHandler serves the embedded Redoc bundle, it is not found until the bundle is vendored
+func NewBundleHandler() http.Handler {
+	bundle, err := ui.ReadFile("ui/redoc.standalone.js")
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if err != nil {
+			http.NotFound(writer, request)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		writer.Header().Set("Cache-Control", "public, max-age=86400")
+		writer.Write(bundle)
+	})
+}
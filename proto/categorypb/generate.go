@@ -0,0 +1,4 @@
+// Package categorypb holds the code generated from proto/category.proto
+package categorypb
+
+//go:generate protoc -I .. --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative category.proto
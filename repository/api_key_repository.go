@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, tx *sql.Tx, apiKey domain.APIKey) (domain.APIKey, error)
+	Revoke(ctx context.Context, tx *sql.Tx, name string) error
+	FindAll(ctx context.Context, tx *sql.Tx) ([]domain.APIKey, error)
+	FindActiveByHash(ctx context.Context, tx *sql.Tx, keyHash string) (domain.APIKey, error)
+}
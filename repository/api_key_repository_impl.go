@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+)
+
+type APIKeyRepositoryImpl struct {
+}
+
+func NewAPIKeyRepository() APIKeyRepository {
+	return &APIKeyRepositoryImpl{}
+}
+
+func (repository *APIKeyRepositoryImpl) Create(ctx context.Context, tx *sql.Tx, apiKey domain.APIKey) (domain.APIKey, error) {
+	query := "INSERT INTO api_key (name, tenant_id, key_hash, created_at) VALUES (?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, apiKey.Name, sql.NullString{String: apiKey.TenantId, Valid: apiKey.TenantId != ""}, apiKey.KeyHash, apiKey.CreatedAt)
+	if err != nil {
+		return apiKey, err
+	}
+
+	lastId, err := result.LastInsertId()
+	if err != nil {
+		return apiKey, err
+	}
+	apiKey.Id = int(lastId)
+
+	return apiKey, nil
+}
+
+func (repository *APIKeyRepositoryImpl) Revoke(ctx context.Context, tx *sql.Tx, name string) error {
+	query := "UPDATE api_key SET revoked_at = ? WHERE name = ? AND revoked_at IS NULL"
+	result, err := tx.ExecContext(ctx, query, time.Now().UTC(), name)
+	if err != nil {
+		return err
+	}
+
+	// check rows affected, if it's 0 then there is no active key with the name
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return exception.NewNotFoundError("api key not found")
+	}
+
+	return nil
+}
+
+func (repository *APIKeyRepositoryImpl) FindAll(ctx context.Context, tx *sql.Tx) ([]domain.APIKey, error) {
+
+	apiKeys := []domain.APIKey{}
+
+	query := "SELECT id, name, tenant_id, created_at, revoked_at FROM api_key ORDER BY id"
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return apiKeys, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		apiKey := domain.APIKey{}
+		var tenantId sql.NullString
+		err = rows.Scan(&apiKey.Id, &apiKey.Name, &tenantId, &apiKey.CreatedAt, &apiKey.RevokedAt)
+		if err != nil {
+			return apiKeys, err
+		}
+		apiKey.TenantId = tenantId.String
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, rows.Err()
+}
+
+func (repository *APIKeyRepositoryImpl) FindActiveByHash(ctx context.Context, tx *sql.Tx, keyHash string) (domain.APIKey, error) {
+
+	apiKey := domain.APIKey{}
+
+	query := "SELECT id, name, tenant_id, created_at FROM api_key WHERE key_hash = ? AND revoked_at IS NULL"
+	rows, err := tx.QueryContext(ctx, query, keyHash)
+	if err != nil {
+		return apiKey, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var tenantId sql.NullString
+		err = rows.Scan(&apiKey.Id, &apiKey.Name, &tenantId, &apiKey.CreatedAt)
+		apiKey.TenantId = tenantId.String
+		apiKey.KeyHash = keyHash
+		return apiKey, err
+	}
+
+	return apiKey, exception.NewNotFoundError("api key not found")
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+)
+
+type CategoryAttributeSchemaRepository interface {
+	Save(ctx context.Context, tx *sql.Tx, schema domain.CategoryAttributeSchema) (domain.CategoryAttributeSchema, error)
+	Delete(ctx context.Context, tx *sql.Tx, categoryType string) error
+	FindByType(ctx context.Context, tx *sql.Tx, categoryType string) (domain.CategoryAttributeSchema, error)
+	FindApplicable(ctx context.Context, tx *sql.Tx, categoryType string) (domain.CategoryAttributeSchema, error) // the schema of the type, else the one of the tenant
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+// CategoryAttributeSchemaRepositoryImpl keeps a schema per tenant and category type,
+// the empty type is the schema of the tenant
+type CategoryAttributeSchemaRepositoryImpl struct {
+}
+
+func NewCategoryAttributeSchemaRepository() CategoryAttributeSchemaRepository {
+	return &CategoryAttributeSchemaRepositoryImpl{}
+}
+
+func (repository *CategoryAttributeSchemaRepositoryImpl) Save(ctx context.Context, tx *sql.Tx, schema domain.CategoryAttributeSchema) (domain.CategoryAttributeSchema, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return schema, err
+	}
+
+	query := "INSERT INTO category_attribute_schema (tenant_id, category_type, definition, updated_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE definition = ?, updated_at = ?"
+	_, err = tx.ExecContext(ctx, query, tenantId, schema.CategoryType, string(schema.Definition), schema.UpdatedAt, string(schema.Definition), schema.UpdatedAt)
+	return schema, err
+}
+
+func (repository *CategoryAttributeSchemaRepositoryImpl) Delete(ctx context.Context, tx *sql.Tx, categoryType string) error {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM category_attribute_schema WHERE tenant_id = ? AND category_type = ?"
+	result, err := tx.ExecContext(ctx, query, tenantId, categoryType)
+	if err != nil {
+		return err
+	}
+
+	// check rows affected, if it's 0 then schema not found
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return exception.NewNotFoundError("schema not found")
+	}
+
+	return nil
+}
+
+func (repository *CategoryAttributeSchemaRepositoryImpl) FindByType(ctx context.Context, tx *sql.Tx, categoryType string) (domain.CategoryAttributeSchema, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return domain.CategoryAttributeSchema{}, err
+	}
+
+	query := "SELECT category_type, definition, updated_at FROM category_attribute_schema WHERE tenant_id = ? AND category_type = ?"
+	return repository.queryOne(ctx, tx, query, tenantId, categoryType)
+}
+
+// FindApplicable sorts the schema of the type before the one of the tenant, the empty type
+func (repository *CategoryAttributeSchemaRepositoryImpl) FindApplicable(ctx context.Context, tx *sql.Tx, categoryType string) (domain.CategoryAttributeSchema, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return domain.CategoryAttributeSchema{}, err
+	}
+
+	query := "SELECT category_type, definition, updated_at FROM category_attribute_schema WHERE tenant_id = ? AND category_type IN (?, '') ORDER BY category_type DESC LIMIT 1"
+	return repository.queryOne(ctx, tx, query, tenantId, categoryType)
+}
+
+func (repository *CategoryAttributeSchemaRepositoryImpl) queryOne(ctx context.Context, tx *sql.Tx, query string, args ...any) (domain.CategoryAttributeSchema, error) {
+
+	schema := domain.CategoryAttributeSchema{}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return schema, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err = rows.Scan(&schema.CategoryType, &schema.Definition, &schema.UpdatedAt)
+		return schema, err
+	}
+
+	return schema, exception.NewNotFoundError("schema not found")
+}
@@ -3,14 +3,51 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
 )
 
+// CategoryFilter narrows FindAll down, nil fields match every category. Attributes maps
+// attribute paths such as size.width to the values, compared as text, they may have.
+// A Limit above zero returns a page ordered by sort_order and id, the categories following
+// the one with AfterSortOrder and AfterId, from the first one when AfterId is 0.
+type CategoryFilter struct {
+	NameContains   *string // case insensitive
+	Type           *string
+	IsActive       *bool
+	CreatedAfter   *time.Time // inclusive
+	CreatedBefore  *time.Time // exclusive
+	UpdatedAfter   *time.Time
+	UpdatedBefore  *time.Time
+	Attributes     map[string][]string
+	AfterSortOrder int
+	AfterId        int
+	Limit          int
+}
+
+// ErrSlugTaken is the duplicate key of (tenant_id, slug), Create and Update return it as it is
+var ErrSlugTaken = exception.NewConflictError("category slug already exists")
+
+// CategoryPosition is where a category is in the order of its tenant
+type CategoryPosition struct {
+	Id        int
+	SortOrder int
+}
+
 type CategoryRepository interface {
 	Create(ctx context.Context, tx *sql.Tx, category domain.Category) (domain.Category, error)
 	Update(ctx context.Context, tx *sql.Tx, category domain.Category) (domain.Category, error)
 	DeleteById(ctx context.Context, tx *sql.Tx, categoryId int) error
 	FindById(ctx context.Context, tx *sql.Tx, categoryId int) (domain.Category, error)
-	FindAll(ctx context.Context, tx *sql.Tx) ([]domain.Category, error)
+	FindAll(ctx context.Context, tx *sql.Tx, filter CategoryFilter) ([]domain.Category, error) // ordered by sort_order, then id, pages by id
+	Count(ctx context.Context, tx *sql.Tx, filter CategoryFilter) (int, error)                 // the page fields are ignored
+	FindByIds(ctx context.Context, tx *sql.Tx, categoryIds []int) ([]domain.Category, error)
+	FindBySlug(ctx context.Context, tx *sql.Tx, slug string) (domain.Category, error)
+	FindByRedirect(ctx context.Context, tx *sql.Tx, slug string) (domain.Category, error) // a slug the category had before
+	FindSlugs(ctx context.Context, tx *sql.Tx, base string) ([]string, error)             // base and base-n slugs in use
+	SaveRedirect(ctx context.Context, tx *sql.Tx, slug string, categoryId int) error
+	LockPositions(ctx context.Context, tx *sql.Tx) ([]CategoryPosition, error) // in order, locked until the transaction ends
+	UpdatePositions(ctx context.Context, tx *sql.Tx, positions []CategoryPosition) error
 }
@@ -3,11 +3,23 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/attributes"
 	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
 )
 
+// CategoryRepositoryImpl scopes every query to the tenant of the context,
+// so one tenant can neither read nor change the categories of another
 type CategoryRepositoryImpl struct {
 }
 
@@ -15,36 +27,170 @@ func NewCategoryRepository() CategoryRepository {
 	return &CategoryRepositoryImpl{}
 }
 
-func (repository *CategoryRepositoryImpl) FindAll(ctx context.Context, tx *sql.Tx) ([]domain.Category, error) {
+func (repository *CategoryRepositoryImpl) FindAll(ctx context.Context, tx *sql.Tx, filter CategoryFilter) ([]domain.Category, error) {
 
 	categories := []domain.Category{}
 
-	query := "SELECT id, name FROM category"
-	rows, err := tx.QueryContext(ctx, query)
+	where, args, err := categoryWhere(ctx, filter)
+	if err != nil {
+		return categories, err
+	}
+
+	// pages follow the order of the list, the keyset is (sort_order, id)
+	query := "SELECT " + categoryColumns + " FROM category WHERE " + where
+	if filter.Limit > 0 && filter.AfterId > 0 {
+		query += " AND (sort_order > ? OR (sort_order = ? AND id > ?))"
+		args = append(args, filter.AfterSortOrder, filter.AfterSortOrder, filter.AfterId)
+	}
+	query += " ORDER BY sort_order, id"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return categories, err
 	}
 	defer rows.Close()
 
-	var category domain.Category
 	for rows.Next() {
-		err = rows.Scan(&category.Id, &category.Name)
+		category, err := scanCategory(rows)
 		if err != nil {
 			return categories, err
 		}
 		categories = append(categories, category)
 	}
 
-	return categories, nil
+	return categories, rows.Err()
+}
+
+// Count counts the categories FindAll returns without paging
+func (repository *CategoryRepositoryImpl) Count(ctx context.Context, tx *sql.Tx, filter CategoryFilter) (int, error) {
+	where, args, err := categoryWhere(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM category WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+// categoryWhere is the condition of FindAll and Count, the tenant of the context and the filter
+func categoryWhere(ctx context.Context, filter CategoryFilter) (string, []any, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := "tenant_id = ?"
+	args := []any{tenantId}
+	if filter.NameContains != nil {
+		query += " AND name LIKE ?"
+		args = append(args, "%"+likeEscaper.Replace(*filter.NameContains)+"%")
+	}
+	if filter.Type != nil {
+		query += " AND type = ?"
+		args = append(args, *filter.Type)
+	}
+	if filter.IsActive != nil {
+		query += " AND is_active = ?"
+		args = append(args, *filter.IsActive)
+	}
+	for _, condition := range []struct {
+		predicate string
+		value     *time.Time
+	}{
+		{" AND created_at >= ?", filter.CreatedAfter},
+		{" AND created_at < ?", filter.CreatedBefore},
+		{" AND updated_at >= ?", filter.UpdatedAfter},
+		{" AND updated_at < ?", filter.UpdatedBefore},
+	} {
+		if condition.value != nil {
+			query += condition.predicate
+			args = append(args, condition.value.UTC())
+		}
+	}
+
+	// the paths are bound like the values, Path only lets through keys that need no escaping
+	if len(filter.Attributes) > attributes.MaxFilters {
+		return "", nil, exception.NewBadRequestError(fmt.Sprintf("at most %d attribute filters are allowed", attributes.MaxFilters))
+	}
+	for _, path := range slices.Sorted(maps.Keys(filter.Attributes)) {
+		jsonPath, err := attributes.Path(path)
+		if err != nil {
+			return "", nil, err
+		}
+		values := filter.Attributes[path]
+		if len(values) == 0 {
+			continue
+		}
+		query += " AND JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) IN (?" + strings.Repeat(", ?", len(values)-1) + ")"
+		args = append(args, jsonPath)
+		for _, value := range values {
+			args = append(args, value)
+		}
+	}
+	return query, args, nil
+}
+
+// likeEscaper makes a text match itself in a LIKE pattern, \ is the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// FindByIds loads several categories in one query, ids that do not exist are left out
+func (repository *CategoryRepositoryImpl) FindByIds(ctx context.Context, tx *sql.Tx, categoryIds []int) ([]domain.Category, error) {
+
+	categories := []domain.Category{}
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return categories, err
+	}
+	if len(categoryIds) == 0 {
+		return categories, nil
+	}
+
+	args := make([]any, 0, len(categoryIds)+1)
+	args = append(args, tenantId)
+	for _, categoryId := range categoryIds {
+		args = append(args, categoryId)
+	}
+	query := "SELECT " + categoryColumns + " FROM category WHERE tenant_id = ? AND id IN (?" + strings.Repeat(", ?", len(categoryIds)-1) + ")"
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return categories, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return categories, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
 }
 
 func (repository *CategoryRepositoryImpl) Create(ctx context.Context, tx *sql.Tx, category domain.Category) (domain.Category, error) {
-	query := "INSERT INTO category (name) VALUES (?)"
-	result, err := tx.ExecContext(ctx, query, category.Name)
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return category, err
+	}
+
+	encodedAttributes, err := encodeAttributes(category.Attributes)
 	if err != nil {
 		return category, err
 	}
 
+	query := "INSERT INTO category (tenant_id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, tenantId, category.Name, category.Slug, category.Type, encodedAttributes, category.Description, category.ImageURL, category.SortOrder, category.IsActive, category.CreatedAt, category.UpdatedAt)
+	if err != nil {
+		return category, duplicateError(err)
+	}
+
 	lastId, err := result.LastInsertId()
 	if err != nil {
 		return category, err
@@ -58,28 +204,42 @@ func (repository *CategoryRepositoryImpl) FindById(ctx context.Context, tx *sql.
 
 	category := domain.Category{}
 
-	query := "SELECT id, name FROM category WHERE id = ?"
-	rows, err := tx.QueryContext(ctx, query, categoryId)
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return category, err
+	}
+
+	query := "SELECT " + categoryColumns + " FROM category WHERE tenant_id = ? AND id = ?"
+	rows, err := tx.QueryContext(ctx, query, tenantId, categoryId)
 	if err != nil {
 		return category, err
 	}
 	defer rows.Close()
 
 	if rows.Next() {
-		err = rows.Scan(&category.Id, &category.Name)
-		return category, err
+		return scanCategory(rows)
 	}
 
 	return category, exception.NewNotFoundError("category not found")
 }
 
 func (repository *CategoryRepositoryImpl) Update(ctx context.Context, tx *sql.Tx, category domain.Category) (domain.Category, error) {
-	query := "UPDATE category SET name = ? WHERE id = ?"
-	result, err := tx.ExecContext(ctx, query, category.Name, category.Id)
+	tenantId, err := tenant.Require(ctx)
 	if err != nil {
 		return category, err
 	}
 
+	encodedAttributes, err := encodeAttributes(category.Attributes)
+	if err != nil {
+		return category, err
+	}
+
+	query := "UPDATE category SET name = ?, slug = ?, type = ?, attributes = ?, description = ?, image_url = ?, sort_order = ?, is_active = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"
+	result, err := tx.ExecContext(ctx, query, category.Name, category.Slug, category.Type, encodedAttributes, category.Description, category.ImageURL, category.SortOrder, category.IsActive, category.UpdatedAt, tenantId, category.Id)
+	if err != nil {
+		return category, duplicateError(err)
+	}
+
 	// check rows affected, if it's 0 then category not found
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -93,8 +253,13 @@ func (repository *CategoryRepositoryImpl) Update(ctx context.Context, tx *sql.Tx
 }
 
 func (repository *CategoryRepositoryImpl) DeleteById(ctx context.Context, tx *sql.Tx, categoryId int) error {
-	query := "DELETE FROM category WHERE id = ?"
-	result, err := tx.ExecContext(ctx, query, categoryId)
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM category WHERE tenant_id = ? AND id = ?"
+	result, err := tx.ExecContext(ctx, query, tenantId, categoryId)
 	if err != nil {
 		return err
 	}
@@ -110,3 +275,181 @@ func (repository *CategoryRepositoryImpl) DeleteById(ctx context.Context, tx *sq
 
 	return nil
 }
+
+func (repository *CategoryRepositoryImpl) FindBySlug(ctx context.Context, tx *sql.Tx, slug string) (domain.Category, error) {
+
+	category := domain.Category{}
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return category, err
+	}
+
+	query := "SELECT " + categoryColumns + " FROM category WHERE tenant_id = ? AND slug = ?"
+	rows, err := tx.QueryContext(ctx, query, tenantId, slug)
+	if err != nil {
+		return category, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return scanCategory(rows)
+	}
+
+	return category, exception.NewNotFoundError("category not found")
+}
+
+func (repository *CategoryRepositoryImpl) FindByRedirect(ctx context.Context, tx *sql.Tx, slug string) (domain.Category, error) {
+
+	category := domain.Category{}
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return category, err
+	}
+
+	query := "SELECT " + categoryColumns + " FROM category WHERE tenant_id = ? AND id =" +
+		" (SELECT category_id FROM category_slug_redirect WHERE tenant_id = ? AND slug = ?)"
+	rows, err := tx.QueryContext(ctx, query, tenantId, tenantId, slug)
+	if err != nil {
+		return category, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return scanCategory(rows)
+	}
+
+	return category, exception.NewNotFoundError("category not found")
+}
+
+// FindSlugs finds the slugs a new one derived from base could collide with, slugs
+// have no % or _ so base needs no escaping
+func (repository *CategoryRepositoryImpl) FindSlugs(ctx context.Context, tx *sql.Tx, base string) ([]string, error) {
+
+	slugs := []string{}
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return slugs, err
+	}
+
+	query := "SELECT slug FROM category WHERE tenant_id = ? AND (slug = ? OR slug LIKE ?)"
+	rows, err := tx.QueryContext(ctx, query, tenantId, base, base+"-%")
+	if err != nil {
+		return slugs, err
+	}
+	defer rows.Close()
+
+	var slug string
+	for rows.Next() {
+		err = rows.Scan(&slug)
+		if err != nil {
+			return slugs, err
+		}
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, rows.Err()
+}
+
+// SaveRedirect points slug at the category, taking it over from any category it pointed at before
+func (repository *CategoryRepositoryImpl) SaveRedirect(ctx context.Context, tx *sql.Tx, slug string, categoryId int) error {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO category_slug_redirect (tenant_id, slug, category_id) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE category_id = ?"
+	_, err = tx.ExecContext(ctx, query, tenantId, slug, categoryId, categoryId)
+	return err
+}
+
+// LockPositions locks every category of the tenant, so concurrent moves are ordered one after another
+func (repository *CategoryRepositoryImpl) LockPositions(ctx context.Context, tx *sql.Tx) ([]CategoryPosition, error) {
+
+	positions := []CategoryPosition{}
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return positions, err
+	}
+
+	query := "SELECT id, sort_order FROM category WHERE tenant_id = ? ORDER BY sort_order, id FOR UPDATE"
+	rows, err := tx.QueryContext(ctx, query, tenantId)
+	if err != nil {
+		return positions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		position := CategoryPosition{}
+		err = rows.Scan(&position.Id, &position.SortOrder)
+		if err != nil {
+			return positions, err
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, rows.Err()
+}
+
+// UpdatePositions sets the sort_order of the categories in one statement, updated_at is left alone
+func (repository *CategoryRepositoryImpl) UpdatePositions(ctx context.Context, tx *sql.Tx, positions []CategoryPosition) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE category SET sort_order = CASE id" + strings.Repeat(" WHEN ? THEN ?", len(positions)) +
+		" END WHERE tenant_id = ? AND id IN (?" + strings.Repeat(", ?", len(positions)-1) + ")"
+	args := make([]any, 0, 3*len(positions)+1)
+	for _, position := range positions {
+		args = append(args, position.Id, position.SortOrder)
+	}
+	args = append(args, tenantId)
+	for _, position := range positions {
+		args = append(args, position.Id)
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// categoryColumns are the columns scanCategory reads, in its order
+const categoryColumns = "id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at"
+
+func scanCategory(rows *sql.Rows) (domain.Category, error) {
+	category := domain.Category{}
+	var encodedAttributes []byte
+	err := rows.Scan(&category.Id, &category.Name, &category.Slug, &category.Type, &encodedAttributes, &category.Description, &category.ImageURL, &category.SortOrder, &category.IsActive, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil || encodedAttributes == nil {
+		return category, err
+	}
+	err = json.Unmarshal(encodedAttributes, &category.Attributes)
+	return category, err
+}
+
+// encodeAttributes stores a category without attributes as NULL
+func encodeAttributes(attributes map[string]any) (any, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(attributes)
+	return string(encoded), err
+}
+
+// duplicateError reports the unique (tenant_id, name) and (tenant_id, slug) keys as conflicts
+func duplicateError(err error) error {
+	var mysqlError *mysql.MySQLError
+	if !errors.As(err, &mysqlError) || mysqlError.Number != 1062 {
+		return err
+	}
+	if strings.Contains(mysqlError.Message, "category_tenant_slug") {
+		return ErrSlugTaken
+	}
+	return exception.NewConflictError("category name already exists")
+}
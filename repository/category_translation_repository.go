@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+)
+
+type CategoryTranslationRepository interface {
+	Save(ctx context.Context, tx *sql.Tx, translation domain.CategoryTranslation) (domain.CategoryTranslation, error)
+	Delete(ctx context.Context, tx *sql.Tx, categoryId int, locale string) error
+	FindByCategoryId(ctx context.Context, tx *sql.Tx, categoryId int) ([]domain.CategoryTranslation, error)
+	FindByLocales(ctx context.Context, tx *sql.Tx, categoryIds []int, locales []string) ([]domain.CategoryTranslation, error)
+	FindAll(ctx context.Context, tx *sql.Tx) ([]domain.CategoryTranslation, error)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+// CategoryTranslationRepositoryImpl reaches translations through their category,
+// so like the categories they are scoped to the tenant of the context
+type CategoryTranslationRepositoryImpl struct {
+}
+
+func NewCategoryTranslationRepository() CategoryTranslationRepository {
+	return &CategoryTranslationRepositoryImpl{}
+}
+
+// Save creates the translation or replaces its name. It only inserts for a category of the tenant,
+// callers check the category exists as MySQL reports no affected rows for an unchanged name.
+func (repository *CategoryTranslationRepositoryImpl) Save(ctx context.Context, tx *sql.Tx, translation domain.CategoryTranslation) (domain.CategoryTranslation, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return translation, err
+	}
+
+	query := "INSERT INTO category_translation (category_id, locale, name) SELECT id, ?, ? FROM category WHERE tenant_id = ? AND id = ? ON DUPLICATE KEY UPDATE name = ?"
+	_, err = tx.ExecContext(ctx, query, translation.Locale, translation.Name, tenantId, translation.CategoryId, translation.Name)
+	return translation, err
+}
+
+func (repository *CategoryTranslationRepositoryImpl) Delete(ctx context.Context, tx *sql.Tx, categoryId int, locale string) error {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE category_translation FROM category_translation JOIN category ON category.id = category_translation.category_id WHERE category.tenant_id = ? AND category_translation.category_id = ? AND category_translation.locale = ?"
+	result, err := tx.ExecContext(ctx, query, tenantId, categoryId, locale)
+	if err != nil {
+		return err
+	}
+
+	// check rows affected, if it's 0 then translation not found
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return exception.NewNotFoundError("translation not found")
+	}
+
+	return nil
+}
+
+func (repository *CategoryTranslationRepositoryImpl) FindByCategoryId(ctx context.Context, tx *sql.Tx, categoryId int) ([]domain.CategoryTranslation, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return []domain.CategoryTranslation{}, err
+	}
+
+	query := "SELECT category_translation.category_id, category_translation.locale, category_translation.name FROM category_translation JOIN category ON category.id = category_translation.category_id WHERE category.tenant_id = ? AND category_translation.category_id = ? ORDER BY category_translation.locale"
+	return repository.query(ctx, tx, query, tenantId, categoryId)
+}
+
+// FindByLocales loads the translations of several categories in the locales in one query
+func (repository *CategoryTranslationRepositoryImpl) FindByLocales(ctx context.Context, tx *sql.Tx, categoryIds []int, locales []string) ([]domain.CategoryTranslation, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return []domain.CategoryTranslation{}, err
+	}
+	if len(categoryIds) == 0 || len(locales) == 0 {
+		return []domain.CategoryTranslation{}, nil
+	}
+
+	args := make([]any, 0, len(categoryIds)+len(locales)+1)
+	args = append(args, tenantId)
+	for _, categoryId := range categoryIds {
+		args = append(args, categoryId)
+	}
+	for _, locale := range locales {
+		args = append(args, locale)
+	}
+	query := "SELECT category_translation.category_id, category_translation.locale, category_translation.name FROM category_translation JOIN category ON category.id = category_translation.category_id WHERE category.tenant_id = ?" +
+		" AND category_translation.category_id IN (?" + strings.Repeat(", ?", len(categoryIds)-1) + ")" +
+		" AND category_translation.locale IN (?" + strings.Repeat(", ?", len(locales)-1) + ")"
+	return repository.query(ctx, tx, query, args...)
+}
+
+// FindAll loads every translation of the tenant, the cache keeps them under one key
+func (repository *CategoryTranslationRepositoryImpl) FindAll(ctx context.Context, tx *sql.Tx) ([]domain.CategoryTranslation, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return []domain.CategoryTranslation{}, err
+	}
+
+	query := "SELECT category_translation.category_id, category_translation.locale, category_translation.name FROM category_translation JOIN category ON category.id = category_translation.category_id WHERE category.tenant_id = ?"
+	return repository.query(ctx, tx, query, tenantId)
+}
+
+func (repository *CategoryTranslationRepositoryImpl) query(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]domain.CategoryTranslation, error) {
+
+	translations := []domain.CategoryTranslation{}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return translations, err
+	}
+	defer rows.Close()
+
+	var translation domain.CategoryTranslation
+	for rows.Next() {
+		err = rows.Scan(&translation.CategoryId, &translation.Locale, &translation.Name)
+		if err != nil {
+			return translations, err
+		}
+		translations = append(translations, translation)
+	}
+
+	return translations, rows.Err()
+}
@@ -0,0 +1,235 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/proto/categorypb"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+	watchBuffer     = 64
+)
+
+// CategoryServer serves categorypb.CategoryService with the same service as the REST controller
+type CategoryServer struct {
+	categorypb.UnimplementedCategoryServiceServer
+	CategoryService service.CategoryService
+	Events          *service.CategoryEventBroker
+}
+
+func NewCategoryServer(categoryService service.CategoryService, events *service.CategoryEventBroker) *CategoryServer {
+	return &CategoryServer{
+		CategoryService: categoryService,
+		Events:          events,
+	}
+}
+
+func (server *CategoryServer) Create(ctx context.Context, request *categorypb.CreateCategoryRequest) (*categorypb.Category, error) {
+	categoryResponse, err := server.CategoryService.Create(ctx, web.CategoryCreateRequest{
+		Name:        request.GetName(),
+		Slug:        request.GetSlug(),
+		Type:        request.GetType(),
+		Attributes:  attributesOf(request.GetAttributes()),
+		Description: request.GetDescription(),
+		ImageURL:    request.GetImageUrl(),
+		SortOrder:   int(request.GetSortOrder()),
+		IsActive:    request.IsActive,
+	})
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toCategory(categoryResponse), nil
+}
+
+func (server *CategoryServer) Update(ctx context.Context, request *categorypb.UpdateCategoryRequest) (*categorypb.Category, error) {
+	updateRequest := web.CategoryUpdateRequest{
+		Id:          int(request.GetId()),
+		Name:        request.GetName(),
+		Slug:        request.GetSlug(),
+		Type:        request.Type,
+		Attributes:  attributesOf(request.GetAttributes()),
+		Description: request.Description,
+		ImageURL:    request.ImageUrl,
+		IsActive:    request.IsActive,
+	}
+	if request.SortOrder != nil {
+		sortOrder := int(request.GetSortOrder())
+		updateRequest.SortOrder = &sortOrder
+	}
+	categoryResponse, err := server.CategoryService.Update(ctx, updateRequest)
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toCategory(categoryResponse), nil
+}
+
+func (server *CategoryServer) Delete(ctx context.Context, request *categorypb.DeleteCategoryRequest) (*categorypb.DeleteCategoryResponse, error) {
+	if err := server.CategoryService.DeleteById(ctx, int(request.GetId())); err != nil {
+		return nil, Status(err)
+	}
+	return &categorypb.DeleteCategoryResponse{}, nil
+}
+
+func (server *CategoryServer) Get(ctx context.Context, request *categorypb.GetCategoryRequest) (*categorypb.Category, error) {
+	categoryResponse, err := server.CategoryService.FindById(ctx, int(request.GetId()))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toCategory(categoryResponse), nil
+}
+
+// List pages through the categories in the order of the list, by sort_order and id. The page
+// token holds the sort order and id of the last category of the previous page.
+func (server *CategoryServer) List(ctx context.Context, request *categorypb.ListCategoriesRequest) (*categorypb.ListCategoriesResponse, error) {
+	pageSize := int(request.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	afterSortOrder, afterId, err := decodePageToken(request.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+
+	// one more than the page tells whether there is a next one
+	categoryResponses, err := server.CategoryService.FindAll(ctx, web.CategoryListRequest{AfterSortOrder: afterSortOrder, AfterId: afterId, Limit: pageSize + 1})
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	response := &categorypb.ListCategoriesResponse{}
+	page := categoryResponses[:min(pageSize, len(categoryResponses))]
+	for _, categoryResponse := range page {
+		response.Categories = append(response.Categories, toCategory(categoryResponse))
+	}
+	if len(categoryResponses) > pageSize {
+		response.NextPageToken = encodePageToken(page[len(page)-1].SortOrder, page[len(page)-1].Id)
+	}
+	return response, nil
+}
+
+// Watch subscribes before listing the existing categories, so no change falls in between.
+// A watcher that falls behind is ended with RESOURCE_EXHAUSTED and has to watch again.
+func (server *CategoryServer) Watch(request *categorypb.WatchCategoriesRequest, stream categorypb.CategoryService_WatchServer) error {
+	// the watcher only gets the events of its own tenant
+	tenantId, err := tenant.Require(stream.Context())
+	if err != nil {
+		return Status(err)
+	}
+	events, cancel := server.Events.Subscribe(tenantId, watchBuffer)
+	defer cancel()
+
+	if request.GetIncludeExisting() {
+		categoryResponses, err := server.CategoryService.FindAll(stream.Context(), web.CategoryListRequest{})
+		if err != nil {
+			return Status(err)
+		}
+		for _, categoryResponse := range categoryResponses {
+			event := &categorypb.CategoryEvent{Type: categorypb.CategoryEvent_CREATED, Category: toCategory(categoryResponse)}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return Status(stream.Context().Err())
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind, watch again")
+			}
+			if err := stream.Send(toCategoryEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toCategory(categoryResponse web.CategoryResponse) *categorypb.Category {
+	category := &categorypb.Category{
+		Id:          int64(categoryResponse.Id),
+		Name:        categoryResponse.Name,
+		Slug:        categoryResponse.Slug,
+		Type:        categoryResponse.Type,
+		Description: categoryResponse.Description,
+		ImageUrl:    categoryResponse.ImageURL,
+		SortOrder:   int32(categoryResponse.SortOrder),
+		IsActive:    categoryResponse.IsActive,
+	}
+	// attributes hold what JSON can, so they always fit a Struct
+	if categoryResponse.Attributes != nil {
+		category.Attributes, _ = structpb.NewStruct(categoryResponse.Attributes)
+	}
+	if !categoryResponse.CreatedAt.IsZero() {
+		category.CreatedAt = timestamppb.New(categoryResponse.CreatedAt)
+	}
+	if !categoryResponse.UpdatedAt.IsZero() {
+		category.UpdatedAt = timestamppb.New(categoryResponse.UpdatedAt)
+	}
+	return category
+}
+
+// attributesOf keeps a missing Struct apart from an empty one, which clears the attributes on update
+func attributesOf(attributes *structpb.Struct) map[string]any {
+	if attributes == nil {
+		return nil
+	}
+	return attributes.AsMap()
+}
+
+func toCategoryEvent(event service.CategoryEvent) *categorypb.CategoryEvent {
+	eventType := categorypb.CategoryEvent_TYPE_UNSPECIFIED
+	switch event.Type {
+	case service.CategoryCreated:
+		eventType = categorypb.CategoryEvent_CREATED
+	case service.CategoryUpdated:
+		eventType = categorypb.CategoryEvent_UPDATED
+	case service.CategoryDeleted:
+		eventType = categorypb.CategoryEvent_DELETED
+	}
+	return &categorypb.CategoryEvent{Type: eventType, Category: toCategory(event.Category)}
+}
+
+func encodePageToken(sortOrder int, id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(sortOrder) + ":" + strconv.Itoa(id)))
+}
+
+func decodePageToken(token string) (sortOrder int, id int, err error) {
+	if token == "" {
+		return 0, 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, 0, err
+	}
+	sortOrderText, idText, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return 0, 0, errors.New("invalid page token")
+	}
+	if sortOrder, err = strconv.Atoi(sortOrderText); err != nil {
+		return 0, 0, err
+	}
+	if id, err = strconv.Atoi(idText); err != nil || id < 1 {
+		return 0, 0, errors.New("invalid page token")
+	}
+	return sortOrder, id, nil
+}
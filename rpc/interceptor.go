@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// AuthInterceptor is the AuthMiddleware check for gRPC, the API key comes from the
+// x-api-key metadata, bearer tokens from authorization, the tenant from x-tenant-id
+// and client certificates from the TLS connection
+type AuthInterceptor struct {
+	Auth *middleware.AuthMiddleware
+}
+
+func NewAuthInterceptor(auth *middleware.AuthMiddleware) *AuthInterceptor {
+	return &AuthInterceptor{
+		Auth: auth,
+	}
+}
+
+func (interceptor *AuthInterceptor) Unary(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := interceptor.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (interceptor *AuthInterceptor) Stream(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := interceptor.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(server, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate returns ctx with the principal and its tenant, like the context the REST handlers see
+func (interceptor *AuthInterceptor) authenticate(ctx context.Context) (context.Context, error) {
+	credentials := middleware.Credentials{
+		APIKey:   firstMetadata(ctx, "x-api-key"),
+		TenantId: firstMetadata(ctx, "x-tenant-id"),
+	}
+	if authorization := firstMetadata(ctx, "authorization"); strings.HasPrefix(strings.ToLower(authorization), "bearer ") {
+		credentials.BearerToken = strings.TrimSpace(authorization[len("bearer "):])
+	}
+	credentials.RemoteAddr = remoteAddr(ctx)
+	if peer, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := peer.AuthInfo.(grpccredentials.TLSInfo); ok {
+			credentials.ConnectionState = &tlsInfo.State
+		}
+	}
+
+	principal, err := interceptor.Auth.Authenticate(ctx, credentials)
+	switch {
+	case errors.Is(err, middleware.ErrUnauthenticated):
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	case errors.Is(err, middleware.ErrTooManyFailures):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, middleware.ErrTenantForbidden):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, middleware.ErrTenantInvalid), errors.Is(err, middleware.ErrTenantRequired):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, Status(err)
+	}
+	return tenant.WithID(middleware.WithPrincipal(ctx, principal), principal.TenantId), nil
+}
+
+// RateLimitInterceptor takes tokens from the buckets of the REST rate limit, so a client has
+// the same budget over both APIs. It runs after AuthInterceptor, like the middleware runs after
+// the auth middleware, and Config.Routes may name gRPC methods.
+type RateLimitInterceptor struct {
+	RateLimit *middleware.RateLimitMiddleware
+}
+
+func NewRateLimitInterceptor(rateLimit *middleware.RateLimitMiddleware) *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		RateLimit: rateLimit,
+	}
+}
+
+func (interceptor *RateLimitInterceptor) Unary(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := interceptor.allow(ctx, info.FullMethod, grpc.SetHeader); err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+// Stream takes a single token when a stream starts, not one per message
+func (interceptor *RateLimitInterceptor) Stream(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	setHeader := func(ctx context.Context, header metadata.MD) error {
+		return stream.SetHeader(header)
+	}
+	if err := interceptor.allow(stream.Context(), info.FullMethod, setHeader); err != nil {
+		return err
+	}
+	return handler(server, stream)
+}
+
+func (interceptor *RateLimitInterceptor) allow(ctx context.Context, method string, setHeader func(context.Context, metadata.MD) error) error {
+	allowed, retryAfter := interceptor.RateLimit.Allow(ctx, method, firstMetadata(ctx, "x-api-key"), remoteAddr(ctx))
+	if allowed {
+		return nil
+	}
+	setHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+// TimeoutInterceptor puts the deadlines of the REST API on unary calls, Config.Routes may name
+// gRPC methods. Streams such as Watch run until the client ends them and get no deadline.
+type TimeoutInterceptor struct {
+	Config middleware.TimeoutConfig
+}
+
+func NewTimeoutInterceptor(config middleware.TimeoutConfig) *TimeoutInterceptor {
+	return &TimeoutInterceptor{
+		Config: config,
+	}
+}
+
+func (interceptor *TimeoutInterceptor) Unary(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	timeout := interceptor.Config.Default
+	if methodTimeout, ok := interceptor.Config.Routes[info.FullMethod]; ok {
+		timeout = methodTimeout
+	}
+	if timeout <= 0 {
+		return handler(ctx, request)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return handler(ctx, request)
+}
+
+func remoteAddr(ctx context.Context) string {
+	if peer, ok := peer.FromContext(ctx); ok && peer.Addr != nil {
+		return peer.Addr.String()
+	}
+	return ""
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream replaces the context of a stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *contextStream) Context() context.Context {
+	return stream.ctx
+}
+
+// RecoverUnary and RecoverStream turn a panic into INTERNAL instead of crashing the server,
+// as exception.ErrorHandler does for the REST API
+func RecoverUnary(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("grpc: panic in %v: %v", info.FullMethod, recovered)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, request)
+}
+
+func RecoverStream(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("grpc: panic in %v: %v", info.FullMethod, recovered)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(server, stream)
+}
@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Status maps service errors to gRPC status codes the way exception.ErrorHandler maps them to HTTP
+func Status(err error) error {
+	var notFoundError exception.NotFoundError
+	var conflictError exception.ConflictError
+	var badRequestError exception.BadRequestError
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.As(err, &notFoundError):
+		return status.Error(codes.NotFound, notFoundError.Error())
+	case errors.As(err, &conflictError):
+		return status.Error(codes.AlreadyExists, conflictError.Error())
+	case errors.As(err, &badRequestError):
+		return status.Error(codes.InvalidArgument, badRequestError.Error())
+	case errors.As(err, &validationErrors):
+		return status.Error(codes.InvalidArgument, "invalid fields")
+	case errors.Is(err, tenant.ErrMissing):
+		return status.Error(codes.InvalidArgument, "a tenant is required")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
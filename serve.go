@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/gql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/openapi"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/rpc"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+)
+
+//go:embed apispec.json
+var apiSpec []byte
+
+func serve(cfg config.Config) {
+
+	db, err := app.NewDB(cfg.Database)
+	if err != nil {
+		panic(err)
+	}
+
+	// setup read replica, reads fall back to the primary while it is unhealthy
+	var replica *database.Replica
+	replicaDB, err := app.NewReplicaDB(cfg.Database)
+	if err != nil {
+		panic(err)
+	}
+	if replicaDB != nil {
+		replica = database.NewReplica(replicaDB)
+		replica.Check(context.Background())
+		go replica.Watch(context.Background(), cfg.Database.ReplicaCheckInterval)
+	}
+
+	// setup category cache, the counters are published at /debug/vars
+	cacheStats := &cache.Stats{}
+	transactionManager := app.NewTransactionManager(db, replica, cfg.Database)
+	categoryService := newCategoryService(cfg, transactionManager, cacheStats)
+
+	// setup category events, gRPC watchers see the changes made through either API
+	categoryEvents := service.NewCategoryEventBroker()
+	categoryService = service.NewCategoryServiceEvents(categoryService, categoryEvents)
+
+	// setup translations, names are read in the locales of Accept-Language
+	categoryTranslationService := service.NewCategoryTranslationService(repository.NewCategoryTranslationRepository(), repository.NewCategoryRepository(), transactionManager, app.NewValidator())
+	if translationCache := app.NewCache(cfg.Cache); translationCache != nil {
+		categoryTranslationService = service.NewCategoryTranslationServiceCache(categoryTranslationService, translationCache, cfg.Cache.TTL, cacheStats)
+	}
+	categoryService = service.NewCategoryServiceLocalized(categoryService, categoryTranslationService, cfg.Locale.Default)
+
+	// setup attribute schemas, the category service checks attributes against them
+	categoryAttributeSchemaService := service.NewCategoryAttributeSchemaService(repository.NewCategoryAttributeSchemaRepository(), transactionManager, app.NewValidator())
+
+	categoryController := controller.NewCategoryController(categoryService)
+	categoryTranslationController := controller.NewCategoryTranslationController(categoryTranslationService)
+	categoryAttributeSchemaController := controller.NewCategoryAttributeSchemaController(categoryAttributeSchemaService)
+
+	// setup endpoints, the api documentation is generated from them
+	router := app.NewRouter(categoryController, categoryTranslationController, categoryAttributeSchemaController)
+	router.Handler(http.MethodGet, "/debug/vars", cache.NewStatsHandler("category_cache", cacheStats))
+
+	// setup graphql endpoint, queries are limited in depth and complexity
+	graphqlHandler, err := gql.NewHandler(categoryService, gql.Config(cfg.GraphQL))
+	if err != nil {
+		panic(err)
+	}
+	router.Handler(http.MethodGet, "/graphql", graphqlHandler)
+	router.Handler(http.MethodPost, "/graphql", graphqlHandler)
+
+	// setup locale middleware, the locales of Accept-Language reach the services through the request context
+	localeMiddleware := middleware.NewLocaleMiddleware(router)
+
+	// setup openapi validation middleware, it checks traffic against the embedded apispec.json
+	openAPIValidationMiddleware, err := middleware.NewOpenAPIValidationMiddleware(localeMiddleware, apiSpec, middleware.OpenAPIValidationConfig(cfg.OpenAPI))
+	if err != nil {
+		panic(err)
+	}
+
+	// setup timeout middleware, the deadline is passed to the database through the request context
+	timeoutMiddleware := middleware.NewTimeoutMiddleware(openAPIValidationMiddleware, middleware.TimeoutConfig(cfg.Timeout))
+
+	// setup idempotency middleware, retried POST requests replay the first response
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(timeoutMiddleware, app.NewIdempotencyStore(cfg.Cache, cfg.Idempotency), middleware.IdempotencyConfig(cfg.Idempotency))
+
+	// setup rate limit middleware, behind auth so buckets belong to accepted credentials
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(idempotencyMiddleware, rateLimitConfig(cfg.RateLimit))
+
+	// setup auth middleware, idempotency keys are scoped to the authenticated principal
+	authMiddleware := middleware.NewAuthMiddleware(rateLimitMiddleware, cfg.Auth.APIKey)
+	authMiddleware.ClientPrincipals = cfg.Server.TLS.ClientPrincipals
+	authMiddleware.PublicPaths = []string{"/openapi.json", "/docs", openapi.BundlePath}
+	authMiddleware.Keys = service.NewAPIKeyService(repository.NewAPIKeyRepository(), transactionManager, app.NewValidator())
+	authMiddleware.DefaultTenant = cfg.Auth.DefaultTenant
+	authMiddleware.FailureLimit = middleware.RateLimit(cfg.RateLimit.AuthFailure)
+	if cfg.Auth.JWTSecret != "" {
+		authMiddleware.JWT = middleware.NewJWTVerifier(cfg.Auth.JWTSecret, cfg.Auth.JWTTenantClaim)
+	}
+
+	// setup cors middleware, preflight requests skip the auth middleware
+	corsMiddleware := middleware.NewCorsMiddleware(authMiddleware, router, middleware.CorsConfig(cfg.Cors))
+	router.GlobalOPTIONS = http.HandlerFunc(corsMiddleware.Preflight)
+
+	// setup body limit middleware, it sits inside the compression middleware so it caps decompressed bodies too
+	bodyLimitMiddleware := middleware.NewBodyLimitMiddleware(corsMiddleware, cfg.Server.MaxBodySize)
+
+	// setup compression middleware, compressed request bodies are capped once decompressed
+	compressionMiddleware := middleware.NewCompressionMiddleware(bodyLimitMiddleware, middleware.CompressionConfig(cfg.Compression))
+
+	server := app.NewServer(cfg.Server, compressionMiddleware)
+
+	// setup tls, certificates are reloaded when the files change
+	if cfg.Server.TLS.Enabled() {
+		reloader, err := app.NewCertificateReloader(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			panic(err)
+		}
+		if cfg.Server.TLS.ReloadInterval > 0 {
+			go reloader.Watch(context.Background(), cfg.Server.TLS.ReloadInterval)
+		}
+		server.TLSConfig, err = app.NewTLSConfig(cfg.Server.TLS, reloader)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// setup grpc server, it shares the category service, the auth check, the rate limit buckets,
+	// the deadlines and the tls settings
+	if cfg.GRPC.Port != 0 {
+		grpcServer := app.NewGRPCServer(rpc.NewCategoryServer(categoryService, categoryEvents), authMiddleware, rateLimitMiddleware, middleware.TimeoutConfig(cfg.Timeout), server.TLSConfig)
+		listener, err := net.Listen("tcp", fmt.Sprintf("%v:%v", cfg.Server.Host, cfg.GRPC.Port))
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Listening to gRPC on %v\n", listener.Addr())
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	if server.TLSConfig == nil {
+		fmt.Printf("Listening to http://%v\n", server.Addr)
+		if err = server.ListenAndServe(); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	fmt.Printf("Listening to https://%v\n", server.Addr)
+	if err = server.ListenAndServeTLS("", ""); err != nil {
+		panic(err)
+	}
+
+}
+
+// rateLimitConfig converts the limits of the configuration, the other middleware
+// configurations have the same fields as theirs and are converted in place
+func rateLimitConfig(rateLimit config.RateLimitConfig) middleware.RateLimitConfig {
+	limits := func(configured map[string]config.RateLimit) map[string]middleware.RateLimit {
+		converted := make(map[string]middleware.RateLimit, len(configured))
+		for name, limit := range configured {
+			converted[name] = middleware.RateLimit(limit)
+		}
+		return converted
+	}
+	return middleware.RateLimitConfig{
+		Default:     middleware.RateLimit(rateLimit.Default),
+		Routes:      limits(rateLimit.Routes),
+		Keys:        limits(rateLimit.Keys),
+		AuthFailure: middleware.RateLimit(rateLimit.AuthFailure),
+		IdleTimeout: rateLimit.IdleTimeout,
+	}
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+type APIKeyService interface {
+	Issue(ctx context.Context, request web.APIKeyIssueRequest) (web.APIKeyResponse, error)
+	Revoke(ctx context.Context, name string) error
+	FindAll(ctx context.Context) ([]web.APIKeyResponse, error)
+	Lookup(ctx context.Context, apiKey string) (web.APIKeyResponse, bool, error) // the auth middleware asks it about keys it does not know
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+type APIKeyServiceImpl struct {
+	APIKeyRepository repository.APIKeyRepository
+	Transaction      *database.TransactionManager
+	Validate         *validator.Validate
+}
+
+func NewAPIKeyService(apiKeyRepository repository.APIKeyRepository, transaction *database.TransactionManager, validate *validator.Validate) APIKeyService {
+	return &APIKeyServiceImpl{
+		APIKeyRepository: apiKeyRepository,
+		Transaction:      transaction,
+		Validate:         validate,
+	}
+}
+
+// Issue generates a random key, only its hash is stored so the response is the one place it is seen
+func (service *APIKeyServiceImpl) Issue(ctx context.Context, request web.APIKeyIssueRequest) (web.APIKeyResponse, error) {
+
+	var response web.APIKeyResponse
+
+	// validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
+	if request.TenantId != "" && !tenant.Valid(request.TenantId) {
+		return response, fmt.Errorf("invalid tenant id %q, use lowercase letters, digits, - and _", request.TenantId)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return response, err
+	}
+	key := hex.EncodeToString(secret)
+
+	apiKey := domain.APIKey{
+		Name:      request.Name,
+		TenantId:  request.TenantId,
+		KeyHash:   hashAPIKey(key),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	err = service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		apiKey, err = service.APIKeyRepository.Create(ctx, tx, apiKey)
+		return err
+	})
+	if err != nil {
+		return response, err
+	}
+
+	response = toAPIKeyResponse(apiKey)
+	response.Key = key
+	return response, nil
+}
+
+func (service *APIKeyServiceImpl) Revoke(ctx context.Context, name string) error {
+	return service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return service.APIKeyRepository.Revoke(ctx, tx, name)
+	})
+}
+
+func (service *APIKeyServiceImpl) FindAll(ctx context.Context) ([]web.APIKeyResponse, error) {
+
+	var apiKeys []domain.APIKey
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		apiKeys, err = service.APIKeyRepository.FindAll(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]web.APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		responses = append(responses, toAPIKeyResponse(apiKey))
+	}
+	return responses, nil
+}
+
+func (service *APIKeyServiceImpl) Lookup(ctx context.Context, key string) (web.APIKeyResponse, bool, error) {
+	var apiKey domain.APIKey
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		apiKey, err = service.APIKeyRepository.FindActiveByHash(ctx, tx, hashAPIKey(key))
+		return err
+	})
+	var notFoundError exception.NotFoundError
+	if errors.As(err, &notFoundError) {
+		return web.APIKeyResponse{}, false, nil
+	}
+	if err != nil {
+		return web.APIKeyResponse{}, false, err
+	}
+	return toAPIKeyResponse(apiKey), true, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIKeyResponse(apiKey domain.APIKey) web.APIKeyResponse {
+	return web.APIKeyResponse{
+		Id:        apiKey.Id,
+		Name:      apiKey.Name,
+		TenantId:  apiKey.TenantId,
+		CreatedAt: apiKey.CreatedAt,
+		RevokedAt: apiKey.RevokedAt,
+	}
+}
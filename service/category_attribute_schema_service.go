@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+// CategoryAttributeSchemaService manages the schemas CategoryService checks attributes against,
+// an empty category type is the schema of the tenant
+type CategoryAttributeSchemaService interface {
+	Save(ctx context.Context, request web.CategoryAttributeSchemaSaveRequest) (web.CategoryAttributeSchemaResponse, error)
+	Delete(ctx context.Context, categoryType string) error
+	FindByType(ctx context.Context, categoryType string) (web.CategoryAttributeSchemaResponse, error)
+}
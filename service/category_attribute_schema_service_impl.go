@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/attributes"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+)
+
+type CategoryAttributeSchemaServiceImpl struct {
+	CategoryAttributeSchemaRepository repository.CategoryAttributeSchemaRepository
+	Transaction                       *database.TransactionManager
+	Validate                          *validator.Validate
+}
+
+func NewCategoryAttributeSchemaService(categoryAttributeSchemaRepository repository.CategoryAttributeSchemaRepository, transaction *database.TransactionManager, validate *validator.Validate) CategoryAttributeSchemaService {
+	return &CategoryAttributeSchemaServiceImpl{
+		CategoryAttributeSchemaRepository: categoryAttributeSchemaRepository,
+		Transaction:                       transaction,
+		Validate:                          validate,
+	}
+}
+
+// Save only stores schemas attributes can be checked against, categories saved before keep
+// their attributes until they are updated
+func (service *CategoryAttributeSchemaServiceImpl) Save(ctx context.Context, request web.CategoryAttributeSchemaSaveRequest) (web.CategoryAttributeSchemaResponse, error) {
+
+	var response web.CategoryAttributeSchemaResponse
+
+	// validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
+	definition, err := json.Marshal(request.Schema)
+	if err != nil {
+		return response, err
+	}
+	_, err = attributes.ParseSchema(definition)
+	if err != nil {
+		return response, err
+	}
+
+	schema := domain.CategoryAttributeSchema{
+		CategoryType: request.CategoryType,
+		Definition:   definition,
+		UpdatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+	err = service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		schema, err = service.CategoryAttributeSchemaRepository.Save(ctx, tx, schema)
+		return err
+	})
+	if err != nil {
+		return response, err
+	}
+
+	return toCategoryAttributeSchemaResponse(schema)
+}
+
+func (service *CategoryAttributeSchemaServiceImpl) Delete(ctx context.Context, categoryType string) error {
+	return service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return service.CategoryAttributeSchemaRepository.Delete(ctx, tx, categoryType)
+	})
+}
+
+func (service *CategoryAttributeSchemaServiceImpl) FindByType(ctx context.Context, categoryType string) (web.CategoryAttributeSchemaResponse, error) {
+
+	var schema domain.CategoryAttributeSchema
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		schema, err = service.CategoryAttributeSchemaRepository.FindByType(ctx, tx, categoryType)
+		return err
+	})
+	if err != nil {
+		return web.CategoryAttributeSchemaResponse{}, err
+	}
+
+	return toCategoryAttributeSchemaResponse(schema)
+}
+
+func toCategoryAttributeSchemaResponse(schema domain.CategoryAttributeSchema) (web.CategoryAttributeSchemaResponse, error) {
+	response := web.CategoryAttributeSchemaResponse{
+		CategoryType: schema.CategoryType,
+		UpdatedAt:    schema.UpdatedAt,
+	}
+	err := json.Unmarshal(schema.Definition, &response.Schema)
+	return response, err
+}
@@ -9,7 +9,17 @@ import (
 type CategoryService interface {
 	Create(ctx context.Context, request web.CategoryCreateRequest) (web.CategoryResponse, error)
 	Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error)
+	Move(ctx context.Context, request web.CategoryMoveRequest) (CategoryMove, error) // next to another category of the tenant
 	DeleteById(ctx context.Context, categoryId int) error
 	FindById(ctx context.Context, categoryId int) (web.CategoryResponse, error)
-	FindAll(ctx context.Context) ([]web.CategoryResponse, error)
+	FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error)
+	Count(ctx context.Context, request web.CategoryListRequest) (int, error)          // what FindAll returns without paging
+	FindByIds(ctx context.Context, categoryIds []int) ([]web.CategoryResponse, error) // missing ids are left out
+	FindBySlug(ctx context.Context, slug string) (web.CategoryResponse, error)        // also by the slugs the category had before
+}
+
+// CategoryMove is the moved category and the ids of the others whose sort order changed to make room
+type CategoryMove struct {
+	Category   web.CategoryResponse
+	Renumbered []int
 }
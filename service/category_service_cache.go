@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"golang.org/x/sync/singleflight"
+)
+
+// CategoryServiceCache is a read-through cache in front of another CategoryService,
+// writes invalidate the affected entries. Keys are scoped to the tenant of the context,
+// calls without a tenant go straight to the wrapped service, which rejects them.
+type CategoryServiceCache struct {
+	CategoryService CategoryService
+	readThroughCache
+}
+
+// readThroughCache holds what the cache decorators share
+type readThroughCache struct {
+	Cache cache.Cache
+	TTL   time.Duration
+	Stats *cache.Stats
+
+	group      singleflight.Group
+	generation atomic.Uint64 // counts invalidations, loads overlapping one are not stored
+}
+
+// loadTimeout bounds a shared load, it no longer ends with the request that started it
+const loadTimeout = time.Minute
+
+func NewCategoryServiceCache(categoryService CategoryService, cache cache.Cache, ttl time.Duration, stats *cache.Stats) CategoryService {
+	return &CategoryServiceCache{
+		CategoryService:  categoryService,
+		readThroughCache: readThroughCache{Cache: cache, TTL: ttl, Stats: stats},
+	}
+}
+
+// FindAll only caches the unfiltered list, filtered ones go straight to the wrapped service
+func (service *CategoryServiceCache) FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok || !reflect.ValueOf(request).IsZero() {
+		return service.CategoryService.FindAll(ctx, request)
+	}
+	var categoryResponses []web.CategoryResponse
+	err := service.readThrough(ctx, categoryListCacheKey(tenantId), &categoryResponses, func(ctx context.Context) (any, error) {
+		return service.CategoryService.FindAll(ctx, request)
+	})
+	return categoryResponses, err
+}
+
+func (service *CategoryServiceCache) FindById(ctx context.Context, categoryId int) (web.CategoryResponse, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return service.CategoryService.FindById(ctx, categoryId)
+	}
+	var response web.CategoryResponse
+	err := service.readThrough(ctx, categoryCacheKey(tenantId, categoryId), &response, func(ctx context.Context) (any, error) {
+		return service.CategoryService.FindById(ctx, categoryId)
+	})
+	return response, err
+}
+
+// Count is not cached, it runs when a GraphQL query asks for totalCount
+func (service *CategoryServiceCache) Count(ctx context.Context, request web.CategoryListRequest) (int, error) {
+	return service.CategoryService.Count(ctx, request)
+}
+
+// FindByIds goes straight to the wrapped service, batches are rarely repeated
+func (service *CategoryServiceCache) FindByIds(ctx context.Context, categoryIds []int) ([]web.CategoryResponse, error) {
+	return service.CategoryService.FindByIds(ctx, categoryIds)
+}
+
+// FindBySlug goes straight to the wrapped service too, renames would have to invalidate the old slugs
+func (service *CategoryServiceCache) FindBySlug(ctx context.Context, slug string) (web.CategoryResponse, error) {
+	return service.CategoryService.FindBySlug(ctx, slug)
+}
+
+func (service *CategoryServiceCache) Create(ctx context.Context, request web.CategoryCreateRequest) (web.CategoryResponse, error) {
+	response, err := service.CategoryService.Create(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.invalidate(ctx, categoryListCacheKey(tenantId))
+	return response, nil
+}
+
+func (service *CategoryServiceCache) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+	response, err := service.CategoryService.Update(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.invalidate(ctx, categoryCacheKey(tenantId, request.Id), categoryListCacheKey(tenantId))
+	return response, nil
+}
+
+// Move drops the moved category, the categories renumbered to make room for it and
+// the list, so all of them are read again with their new sort_order
+func (service *CategoryServiceCache) Move(ctx context.Context, request web.CategoryMoveRequest) (CategoryMove, error) {
+	response, err := service.CategoryService.Move(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	keys := []string{categoryCacheKey(tenantId, request.Id), categoryListCacheKey(tenantId)}
+	for _, categoryId := range response.Renumbered {
+		keys = append(keys, categoryCacheKey(tenantId, categoryId))
+	}
+	service.invalidate(ctx, keys...)
+	return response, nil
+}
+
+func (service *CategoryServiceCache) DeleteById(ctx context.Context, categoryId int) error {
+	if err := service.CategoryService.DeleteById(ctx, categoryId); err != nil {
+		return err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.invalidate(ctx, categoryCacheKey(tenantId, categoryId), categoryListCacheKey(tenantId))
+	return nil
+}
+
+// readThrough decodes the cached value into target, or loads it once for all
+// concurrent callers of this process and stores it, other instances load it on their own. Cache failures fall back to the wrapped service.
+// The load does not end when the caller that started it goes away, every caller
+// only stops waiting for it when its own context is done.
+func (service *readThroughCache) readThrough(ctx context.Context, key string, target any, load func(ctx context.Context) (any, error)) error {
+	cached, ok, err := service.Cache.Get(ctx, key)
+	if err != nil {
+		service.Stats.Errors.Add(1)
+		log.Printf("cache: get %v: %v", key, err)
+	}
+	if ok && json.Unmarshal(cached, target) == nil {
+		service.Stats.Hits.Add(1)
+		return nil
+	}
+	service.Stats.Misses.Add(1)
+
+	loaded := service.group.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		generation := service.generation.Load()
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		// a write invalidating entries while the value was read may have changed it
+		if service.generation.Load() != generation {
+			return encoded, nil
+		}
+		if err := service.Cache.Set(ctx, key, encoded, service.TTL); err != nil {
+			service.Stats.Errors.Add(1)
+			log.Printf("cache: set %v: %v", key, err)
+		}
+		return encoded, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			return result.Err
+		}
+		return json.Unmarshal(result.Val.([]byte), target)
+	}
+}
+
+// invalidate only logs failures, the write itself has already been committed. Loads
+// running at the time are not stored and later callers do not join them.
+func (service *readThroughCache) invalidate(ctx context.Context, keys ...string) {
+	service.generation.Add(1)
+	for _, key := range keys {
+		service.group.Forget(key)
+	}
+	if err := service.Cache.Delete(ctx, keys...); err != nil {
+		service.Stats.Errors.Add(1)
+		log.Printf("cache: delete %v: %v", keys, err)
+	}
+}
+
+func categoryCacheKey(tenantId string, categoryId int) string {
+	return "category:" + tenantId + ":" + strconv.Itoa(categoryId)
+}
+
+func categoryListCacheKey(tenantId string) string {
+	return "categories:" + tenantId
+}
+
+func categoryTranslationsCacheKey(tenantId string) string {
+	return "category_translations:" + tenantId
+}
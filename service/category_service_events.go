@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+type CategoryEventType string
+
+const (
+	CategoryCreated CategoryEventType = "created"
+	CategoryUpdated CategoryEventType = "updated"
+	CategoryDeleted CategoryEventType = "deleted"
+)
+
+// CategoryEvent is a change made through this process, Category only has its id when deleted
+type CategoryEvent struct {
+	Type     CategoryEventType
+	TenantId string
+	Category web.CategoryResponse
+}
+
+// CategoryEventBroker fans events out to the subscribers of their tenant. A subscriber whose
+// buffer is full is dropped and its channel closed, so a slow watcher never blocks writes.
+type CategoryEventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan CategoryEvent]struct{} // by tenant
+}
+
+func NewCategoryEventBroker() *CategoryEventBroker {
+	return &CategoryEventBroker{
+		subscribers: map[string]map[chan CategoryEvent]struct{}{},
+	}
+}
+
+// Subscribe returns the events of the tenant published from now on, cancel stops the subscription.
+// The events of other tenants never take up room in the buffer.
+func (broker *CategoryEventBroker) Subscribe(tenantId string, buffer int) (events <-chan CategoryEvent, cancel func()) {
+	channel := make(chan CategoryEvent, buffer)
+	broker.mu.Lock()
+	if broker.subscribers[tenantId] == nil {
+		broker.subscribers[tenantId] = map[chan CategoryEvent]struct{}{}
+	}
+	broker.subscribers[tenantId][channel] = struct{}{}
+	broker.mu.Unlock()
+
+	return channel, func() {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		broker.remove(tenantId, channel)
+	}
+}
+
+func (broker *CategoryEventBroker) Publish(event CategoryEvent) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for channel := range broker.subscribers[event.TenantId] {
+		select {
+		case channel <- event:
+		default:
+			broker.remove(event.TenantId, channel)
+		}
+	}
+}
+
+// remove closes the channel of a subscriber that is still there, the caller holds mu
+func (broker *CategoryEventBroker) remove(tenantId string, channel chan CategoryEvent) {
+	subscribers := broker.subscribers[tenantId]
+	if _, ok := subscribers[channel]; !ok {
+		return
+	}
+	delete(subscribers, channel)
+	close(channel)
+	if len(subscribers) == 0 {
+		delete(broker.subscribers, tenantId)
+	}
+}
+
+// CategoryServiceEvents publishes the successful writes of another CategoryService
+type CategoryServiceEvents struct {
+	CategoryService CategoryService
+	Broker          *CategoryEventBroker
+}
+
+func NewCategoryServiceEvents(categoryService CategoryService, broker *CategoryEventBroker) CategoryService {
+	return &CategoryServiceEvents{
+		CategoryService: categoryService,
+		Broker:          broker,
+	}
+}
+
+func (service *CategoryServiceEvents) Create(ctx context.Context, request web.CategoryCreateRequest) (web.CategoryResponse, error) {
+	response, err := service.CategoryService.Create(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.Broker.Publish(CategoryEvent{Type: CategoryCreated, TenantId: tenantId, Category: response})
+	return response, nil
+}
+
+func (service *CategoryServiceEvents) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+	response, err := service.CategoryService.Update(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.Broker.Publish(CategoryEvent{Type: CategoryUpdated, TenantId: tenantId, Category: response})
+	return response, nil
+}
+
+// Move publishes the moved category as updated, the events carry no sort order so the
+// renumbered ones are left out
+func (service *CategoryServiceEvents) Move(ctx context.Context, request web.CategoryMoveRequest) (CategoryMove, error) {
+	response, err := service.CategoryService.Move(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.Broker.Publish(CategoryEvent{Type: CategoryUpdated, TenantId: tenantId, Category: response.Category})
+	return response, nil
+}
+
+func (service *CategoryServiceEvents) DeleteById(ctx context.Context, categoryId int) error {
+	if err := service.CategoryService.DeleteById(ctx, categoryId); err != nil {
+		return err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.Broker.Publish(CategoryEvent{Type: CategoryDeleted, TenantId: tenantId, Category: web.CategoryResponse{Id: categoryId}})
+	return nil
+}
+
+func (service *CategoryServiceEvents) FindById(ctx context.Context, categoryId int) (web.CategoryResponse, error) {
+	return service.CategoryService.FindById(ctx, categoryId)
+}
+
+func (service *CategoryServiceEvents) FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error) {
+	return service.CategoryService.FindAll(ctx, request)
+}
+
+func (service *CategoryServiceEvents) Count(ctx context.Context, request web.CategoryListRequest) (int, error) {
+	return service.CategoryService.Count(ctx, request)
+}
+
+func (service *CategoryServiceEvents) FindByIds(ctx context.Context, categoryIds []int) ([]web.CategoryResponse, error) {
+	return service.CategoryService.FindByIds(ctx, categoryIds)
+}
+
+func (service *CategoryServiceEvents) FindBySlug(ctx context.Context, slug string) (web.CategoryResponse, error) {
+	return service.CategoryService.FindBySlug(ctx, slug)
+}
@@ -3,58 +3,100 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"slices"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/attributes"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
 	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/slug"
 )
 
 type CategoryServiceImpl struct {
-	CategoryRepository repository.CategoryRepository
-	DB                 *sql.DB
-	Validate           *validator.Validate
+	CategoryRepository                repository.CategoryRepository
+	CategoryAttributeSchemaRepository repository.CategoryAttributeSchemaRepository
+	Transaction                       *database.TransactionManager
+	Validate                          *validator.Validate
 }
 
-func NewCategoryService(categoryRepository repository.CategoryRepository, db *sql.DB, validate *validator.Validate) CategoryService {
+func NewCategoryService(categoryRepository repository.CategoryRepository, categoryAttributeSchemaRepository repository.CategoryAttributeSchemaRepository, transaction *database.TransactionManager, validate *validator.Validate) CategoryService {
 	return &CategoryServiceImpl{
-		CategoryRepository: categoryRepository,
-		DB:                 db,
-		Validate:           validate,
+		CategoryRepository:                categoryRepository,
+		CategoryAttributeSchemaRepository: categoryAttributeSchemaRepository,
+		Transaction:                       transaction,
+		Validate:                          validate,
 	}
 }
 
-func (service *CategoryServiceImpl) FindAll(ctx context.Context) ([]web.CategoryResponse, error) {
+func (service *CategoryServiceImpl) FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error) {
 
 	var categoryResponses []web.CategoryResponse
 
-	tx, err := service.DB.Begin()
+	var categories []domain.Category
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		categories, err = service.CategoryRepository.FindAll(ctx, tx, toCategoryFilter(request))
+		return err
+	})
 	if err != nil {
 		return categoryResponses, err
 	}
 
-	// rollback if an error exists
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
+	categoryResponses = make([]web.CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		categoryResponses = append(categoryResponses, toCategoryResponse(category))
+	}
+	return categoryResponses, nil
+}
 
-	categories, err := service.CategoryRepository.FindAll(ctx, tx)
-	if err != nil {
-		return categoryResponses, err
+func (service *CategoryServiceImpl) Count(ctx context.Context, request web.CategoryListRequest) (int, error) {
+	var count int
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		count, err = service.CategoryRepository.Count(ctx, tx, toCategoryFilter(request))
+		return err
+	})
+	return count, err
+}
+
+func toCategoryFilter(request web.CategoryListRequest) repository.CategoryFilter {
+	return repository.CategoryFilter{
+		NameContains:   request.NameContains,
+		Type:           request.Type,
+		IsActive:       request.IsActive,
+		CreatedAfter:   request.CreatedAfter,
+		CreatedBefore:  request.CreatedBefore,
+		UpdatedAfter:   request.UpdatedAfter,
+		UpdatedBefore:  request.UpdatedBefore,
+		Attributes:     request.Attributes,
+		AfterSortOrder: request.AfterSortOrder,
+		AfterId:        request.AfterId,
+		Limit:          request.Limit,
 	}
+}
+
+func (service *CategoryServiceImpl) FindByIds(ctx context.Context, categoryIds []int) ([]web.CategoryResponse, error) {
 
-	if err = tx.Commit(); err != nil {
+	var categoryResponses []web.CategoryResponse
+
+	var categories []domain.Category
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		categories, err = service.CategoryRepository.FindByIds(ctx, tx, categoryIds)
+		return err
+	})
+	if err != nil {
 		return categoryResponses, err
 	}
 
 	categoryResponses = make([]web.CategoryResponse, 0, len(categories))
 	for _, category := range categories {
-		categoryResponses = append(categoryResponses, web.CategoryResponse{
-			Id:   category.Id,
-			Name: category.Name,
-		})
+		categoryResponses = append(categoryResponses, toCategoryResponse(category))
 	}
 	return categoryResponses, nil
 }
@@ -69,139 +111,383 @@ func (service *CategoryServiceImpl) Create(ctx context.Context, request web.Cate
 		return response, err
 	}
 
-	tx, err := service.DB.Begin()
+	categoryAttributes, err := attributes.Normalize(request.Attributes)
 	if err != nil {
 		return response, err
 	}
 
-	// rollback if an error exists
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
+	now := time.Now().UTC().Truncate(time.Second)
 	category := domain.Category{
-		Name: request.Name,
+		Name:        request.Name,
+		Slug:        slug.Make(request.Slug),
+		Type:        request.Type,
+		Attributes:  categoryAttributes,
+		Description: request.Description,
+		ImageURL:    request.ImageURL,
+		SortOrder:   request.SortOrder,
+		IsActive:    request.IsActive == nil || *request.IsActive,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
-	category, err = service.CategoryRepository.Create(ctx, tx, category)
-	if err != nil {
-		return response, err
+	if slices.Contains(slug.Reserved, category.Slug) {
+		return response, exception.NewConflictError("category slug is reserved")
 	}
+	err = service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := service.checkAttributes(ctx, tx, category)
+		if err != nil {
+			return err
+		}
 
-	if err = tx.Commit(); err != nil {
+		// a slug asked for is taken as is, a derived one gets a suffix when it is taken
+		if request.Slug == "" {
+			category.Slug, err = service.uniqueSlug(ctx, tx, request.Name, "", nil)
+			if err != nil {
+				return err
+			}
+			category, err = service.saveWithUniqueSlug(ctx, tx, category, request.Name, "", service.CategoryRepository.Create)
+			return err
+		}
+
+		category, err = service.CategoryRepository.Create(ctx, tx, category)
+		return err
+	})
+	if err != nil {
 		return response, err
 	}
 
-	response = web.CategoryResponse{
-		Id:   category.Id,
-		Name: category.Name,
-	}
-	return response, nil
+	return toCategoryResponse(category), nil
 }
 
 func (service *CategoryServiceImpl) FindById(ctx context.Context, categoryId int) (web.CategoryResponse, error) {
 
 	var response web.CategoryResponse
 
-	tx, err := service.DB.Begin()
+	var category domain.Category
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		category, err = service.CategoryRepository.FindById(ctx, tx, categoryId)
+		return err
+	})
 	if err != nil {
 		return response, err
 	}
 
-	// rollback if an error exists
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
+	return toCategoryResponse(category), nil
+}
+
+func (service *CategoryServiceImpl) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+
+	var response web.CategoryResponse
 
-	category, err := service.CategoryRepository.FindById(ctx, tx, categoryId)
+	// validate request
+	err := service.Validate.Struct(request)
 	if err != nil {
 		return response, err
 	}
 
-	if err = tx.Commit(); err != nil {
+	categoryAttributes, err := attributes.Normalize(request.Attributes)
+	if err != nil {
 		return response, err
 	}
 
-	response = web.CategoryResponse{
-		Id:   category.Id,
-		Name: category.Name,
+	var category domain.Category
+	err = service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+
+		// check if the category with that id exists or not
+		current, err := service.CategoryRepository.FindById(ctx, tx, request.Id)
+		if err != nil {
+			return err
+		}
+
+		// fields left out of the request keep their value
+		category = current
+		category.Name = request.Name
+		category.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		if request.Description != nil {
+			category.Description = *request.Description
+		}
+		if request.ImageURL != nil {
+			category.ImageURL = *request.ImageURL
+		}
+		if request.SortOrder != nil {
+			category.SortOrder = *request.SortOrder
+		}
+		if request.IsActive != nil {
+			category.IsActive = *request.IsActive
+		}
+
+		// attributes are checked when they or the type change, so a schema made stricter
+		// does not stop other changes to the categories saved before it
+		if request.Type != nil || request.Attributes != nil {
+			if request.Type != nil {
+				category.Type = *request.Type
+			}
+			if request.Attributes != nil {
+				category.Attributes = categoryAttributes
+			}
+			err = service.checkAttributes(ctx, tx, category)
+			if err != nil {
+				return err
+			}
+		}
+		derived := false
+		switch {
+		case request.Slug != "":
+			category.Slug = slug.Make(request.Slug)
+			if slices.Contains(slug.Reserved, category.Slug) {
+				return exception.NewConflictError("category slug is reserved")
+			}
+		case request.Name != current.Name && slug.Make(request.Name) != current.Slug:
+			category.Slug, err = service.uniqueSlug(ctx, tx, request.Name, current.Slug, nil)
+			if err != nil {
+				return err
+			}
+			derived = true
+		}
+
+		// the slug it had keeps leading to the category
+		if category.Slug != current.Slug {
+			err = service.CategoryRepository.SaveRedirect(ctx, tx, current.Slug, current.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		if derived {
+			category, err = service.saveWithUniqueSlug(ctx, tx, category, request.Name, current.Slug, service.CategoryRepository.Update)
+			return err
+		}
+		category, err = service.CategoryRepository.Update(ctx, tx, category)
+		return err
+	})
+	if err != nil {
+		return response, err
 	}
-	return response, nil
+
+	return toCategoryResponse(category), nil
 }
 
-func (service *CategoryServiceImpl) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+// Move places the category right before or after another one of the tenant. It gets a
+// sort_order between its new neighbours, when they leave no room every category of the
+// tenant is spread out again in the same transaction.
+func (service *CategoryServiceImpl) Move(ctx context.Context, request web.CategoryMoveRequest) (CategoryMove, error) {
 
-	var response web.CategoryResponse
+	var response CategoryMove
 
 	// validate request
 	err := service.Validate.Struct(request)
 	if err != nil {
 		return response, err
 	}
-
-	tx, err := service.DB.Begin()
-	if err != nil {
-		return response, err
+	if (request.BeforeId == nil) == (request.AfterId == nil) {
+		return response, exception.NewBadRequestError("exactly one of before_id and after_id is required")
+	}
+	targetId := request.BeforeId
+	if request.AfterId != nil {
+		targetId = request.AfterId
 	}
+	if *targetId == request.Id {
+		return response, exception.NewBadRequestError("a category cannot be moved next to itself")
+	}
+
+	var category domain.Category
+	var renumbered []int
+	err = service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
 
-	// rollback if an error exists
-	defer func() {
+		positions, err := service.CategoryRepository.LockPositions(ctx, tx)
 		if err != nil {
-			_ = tx.Rollback()
+			return err
 		}
-	}()
 
-	// check if the category with that id exists or not
-	category, err := service.CategoryRepository.FindById(ctx, tx, request.Id)
+		// take the category out and put it back next to the target
+		index := slices.IndexFunc(positions, func(position repository.CategoryPosition) bool { return position.Id == request.Id })
+		if index < 0 {
+			return exception.NewNotFoundError("category not found")
+		}
+		positions = slices.Delete(positions, index, index+1)
+		index = slices.IndexFunc(positions, func(position repository.CategoryPosition) bool { return position.Id == *targetId })
+		if index < 0 {
+			return exception.NewNotFoundError("target category not found")
+		}
+		if request.AfterId != nil {
+			index++
+		}
+		positions = slices.Insert(positions, index, repository.CategoryPosition{Id: request.Id})
+
+		// the others only change when they are spread out, the moved one is updated on its own
+		// so its updated_at changes too
+		changed := place(positions, index)
+		others := slices.DeleteFunc(changed, func(position repository.CategoryPosition) bool { return position.Id == request.Id })
+		err = service.CategoryRepository.UpdatePositions(ctx, tx, others)
+		if err != nil {
+			return err
+		}
+		renumbered = make([]int, 0, len(others))
+		for _, position := range others {
+			renumbered = append(renumbered, position.Id)
+		}
+
+		category, err = service.CategoryRepository.FindById(ctx, tx, request.Id)
+		if err != nil {
+			return err
+		}
+		category.SortOrder = positions[index].SortOrder
+		category.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		category, err = service.CategoryRepository.Update(ctx, tx, category)
+		return err
+	})
 	if err != nil {
 		return response, err
 	}
 
-	category = domain.Category{
-		Id:   request.Id,
-		Name: request.Name,
-	}
+	return CategoryMove{Category: toCategoryResponse(category), Renumbered: renumbered}, nil
+}
+
+// FindBySlug finds the category by its slug, by a slug it had before or by the slug the
+// one asked for is a spelling of, such as Électronique for electronique. The response has
+// the current slug, the caller can tell from it that the category has moved.
+func (service *CategoryServiceImpl) FindBySlug(ctx context.Context, categorySlug string) (web.CategoryResponse, error) {
 
-	category, err = service.CategoryRepository.Update(ctx, tx, category)
+	var response web.CategoryResponse
+
+	var category domain.Category
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		for _, candidate := range slices.Compact([]string{categorySlug, slug.Make(categorySlug)}) {
+			category, err = service.CategoryRepository.FindBySlug(ctx, tx, candidate)
+			if !errors.As(err, &exception.NotFoundError{}) {
+				return err
+			}
+			category, err = service.CategoryRepository.FindByRedirect(ctx, tx, candidate)
+			if !errors.As(err, &exception.NotFoundError{}) {
+				return err
+			}
+		}
+		return err
+	})
 	if err != nil {
 		return response, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return response, err
-	}
+	return toCategoryResponse(category), nil
+}
 
-	response = web.CategoryResponse{
-		Id:   category.Id,
-		Name: category.Name,
+// uniqueSlug derives a slug from name, suffixed with -2, -3 and so on when it is taken,
+// own is the slug of the category being renamed, which it may keep. tried are slugs
+// found taken after they were picked.
+func (service *CategoryServiceImpl) uniqueSlug(ctx context.Context, tx *sql.Tx, name string, own string, tried []string) (string, error) {
+	base := slug.Make(name)
+	taken, err := service.CategoryRepository.FindSlugs(ctx, tx, base)
+	if err != nil {
+		return "", err
+	}
+	taken = append(slices.DeleteFunc(taken, func(slug string) bool { return slug == own }), slug.Reserved...)
+	taken = append(taken, tried...)
+	candidate := base
+	for n := 2; slices.Contains(taken, candidate); n++ {
+		candidate = slug.WithSuffix(base, n)
 	}
-	return response, nil
+	return candidate, nil
 }
 
-func (service *CategoryServiceImpl) DeleteById(ctx context.Context, categoryId int) error {
+// maxSlugAttempts bounds how often a derived slug is picked again
+const maxSlugAttempts = 5
+
+// saveWithUniqueSlug saves the category with a slug uniqueSlug derived from name. A concurrent
+// write may have taken that slug first, the duplicate key then makes it try the next free one.
+// The snapshot of the transaction does not show that write, so the slugs tried are passed along.
+func (service *CategoryServiceImpl) saveWithUniqueSlug(ctx context.Context, tx *sql.Tx, category domain.Category, name string, own string, save func(ctx context.Context, tx *sql.Tx, category domain.Category) (domain.Category, error)) (domain.Category, error) {
+	var tried []string
+	for attempt := 1; ; attempt++ {
+		saved, err := save(ctx, tx, category)
+		if !errors.Is(err, repository.ErrSlugTaken) || attempt == maxSlugAttempts {
+			return saved, err
+		}
+		tried = append(tried, category.Slug)
+		category.Slug, err = service.uniqueSlug(ctx, tx, name, own, tried)
+		if err != nil {
+			return category, err
+		}
+	}
+}
 
-	tx, err := service.DB.Begin()
+// checkAttributes checks the attributes against the schema of the category type, or the
+// one of the tenant when the type has none. Without a schema any attributes are accepted.
+func (service *CategoryServiceImpl) checkAttributes(ctx context.Context, tx *sql.Tx, category domain.Category) error {
+	schema, err := service.CategoryAttributeSchemaRepository.FindApplicable(ctx, tx, category.Type)
+	if errors.As(err, &exception.NotFoundError{}) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	parsed, err := attributes.ParseSchema(schema.Definition)
 	if err != nil {
 		return err
 	}
+	return attributes.Check(parsed, category.Attributes)
+}
 
-	// rollback if an error exists
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
+func (service *CategoryServiceImpl) DeleteById(ctx context.Context, categoryId int) error {
+	return service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return service.CategoryRepository.DeleteById(ctx, tx, categoryId)
+	})
+}
 
-	if err = service.CategoryRepository.DeleteById(ctx, tx, categoryId); err != nil {
-		return err
+// positionGap is how far apart categories are spread, so most moves only change the moved one
+const positionGap = 1024
+
+// maxSortOrder is the largest sort_order requests may set, spreading out stays within it
+const maxSortOrder = 1000000
+
+// place gives the category at index a sort_order halfway between its neighbours, or spreads
+// every category out again when they leave no room. It returns the positions that changed.
+func place(positions []repository.CategoryPosition, index int) []repository.CategoryPosition {
+	var lower, upper int
+	switch {
+	case index == 0:
+		upper = positions[1].SortOrder
+		lower = upper - 2*positionGap
+	case index == len(positions)-1:
+		lower = positions[index-1].SortOrder
+		upper = lower + 2*positionGap
+	default:
+		lower, upper = positions[index-1].SortOrder, positions[index+1].SortOrder
+	}
+	sortOrder := lower + (upper-lower)/2
+	if lower < sortOrder && sortOrder < upper && -maxSortOrder <= sortOrder && sortOrder <= maxSortOrder {
+		positions[index].SortOrder = sortOrder
+		return []repository.CategoryPosition{positions[index]}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return err
+	gap := max(min(positionGap, maxSortOrder/len(positions)), 1)
+	changed := []repository.CategoryPosition{}
+	for i := range positions {
+		sortOrder = (i + 1) * gap
+		if positions[i].SortOrder != sortOrder || i == index {
+			positions[i].SortOrder = sortOrder
+			changed = append(changed, positions[i])
+		}
 	}
+	return changed
+}
 
-	return nil
+// toCategoryResponse writes no attributes as an empty object, so clients need not handle null
+func toCategoryResponse(category domain.Category) web.CategoryResponse {
+	if category.Attributes == nil {
+		category.Attributes = map[string]any{}
+	}
+	return web.CategoryResponse{
+		Id:          category.Id,
+		Name:        category.Name,
+		Slug:        category.Slug,
+		Type:        category.Type,
+		Attributes:  category.Attributes,
+		Description: category.Description,
+		ImageURL:    category.ImageURL,
+		SortOrder:   category.SortOrder,
+		IsActive:    category.IsActive,
+		CreatedAt:   category.CreatedAt,
+		UpdatedAt:   category.UpdatedAt,
+	}
 }
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+// CategoryServiceLocalized translates the names read through another CategoryService into the
+// locales preferred in the context. It wraps the cache, which keeps the names in DefaultLocale.
+type CategoryServiceLocalized struct {
+	CategoryService CategoryService
+	Translations    CategoryTranslationService
+	DefaultLocale   string // the locale of the names themselves
+}
+
+func NewCategoryServiceLocalized(categoryService CategoryService, translations CategoryTranslationService, defaultLocale string) CategoryService {
+	return &CategoryServiceLocalized{
+		CategoryService: categoryService,
+		Translations:    translations,
+		DefaultLocale:   defaultLocale,
+	}
+}
+
+func (service *CategoryServiceLocalized) FindById(ctx context.Context, categoryId int) (web.CategoryResponse, error) {
+	response, err := service.CategoryService.FindById(ctx, categoryId)
+	if err != nil {
+		return response, err
+	}
+	responses, err := service.translate(ctx, []web.CategoryResponse{response})
+	if err != nil {
+		return response, err
+	}
+	return responses[0], nil
+}
+
+func (service *CategoryServiceLocalized) FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error) {
+	responses, err := service.CategoryService.FindAll(ctx, request)
+	if err != nil {
+		return responses, err
+	}
+	return service.translate(ctx, responses)
+}
+
+// Count matches the stored names, the translations are not counted
+func (service *CategoryServiceLocalized) Count(ctx context.Context, request web.CategoryListRequest) (int, error) {
+	return service.CategoryService.Count(ctx, request)
+}
+
+func (service *CategoryServiceLocalized) FindByIds(ctx context.Context, categoryIds []int) ([]web.CategoryResponse, error) {
+	responses, err := service.CategoryService.FindByIds(ctx, categoryIds)
+	if err != nil {
+		return responses, err
+	}
+	return service.translate(ctx, responses)
+}
+
+func (service *CategoryServiceLocalized) FindBySlug(ctx context.Context, slug string) (web.CategoryResponse, error) {
+	response, err := service.CategoryService.FindBySlug(ctx, slug)
+	if err != nil {
+		return response, err
+	}
+	responses, err := service.translate(ctx, []web.CategoryResponse{response})
+	if err != nil {
+		return response, err
+	}
+	return responses[0], nil
+}
+
+// writes answer with the names as written
+func (service *CategoryServiceLocalized) Create(ctx context.Context, request web.CategoryCreateRequest) (web.CategoryResponse, error) {
+	return service.CategoryService.Create(ctx, request)
+}
+
+func (service *CategoryServiceLocalized) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+	return service.CategoryService.Update(ctx, request)
+}
+
+func (service *CategoryServiceLocalized) Move(ctx context.Context, request web.CategoryMoveRequest) (CategoryMove, error) {
+	return service.CategoryService.Move(ctx, request)
+}
+
+func (service *CategoryServiceLocalized) DeleteById(ctx context.Context, categoryId int) error {
+	return service.CategoryService.DeleteById(ctx, categoryId)
+}
+
+func (service *CategoryServiceLocalized) translate(ctx context.Context, responses []web.CategoryResponse) ([]web.CategoryResponse, error) {
+	locales := locale.Fallbacks(locale.Preferences(ctx), service.DefaultLocale)
+	if len(locales) == 0 {
+		return responses, nil
+	}
+	return service.Translations.Translate(ctx, responses, locales)
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+)
+
+type CategoryTranslationService interface {
+	Save(ctx context.Context, request web.CategoryTranslationSaveRequest) (web.CategoryTranslationResponse, error)
+	Delete(ctx context.Context, categoryId int, locale string) error
+	FindAll(ctx context.Context, categoryId int) ([]web.CategoryTranslationResponse, error)
+
+	// Translate replaces the names of categories with their translation in the first of locales having one
+	Translate(ctx context.Context, categories []web.CategoryResponse, locales []string) ([]web.CategoryResponse, error)
+
+	// Names returns every translated name of the tenant by category id and locale
+	Names(ctx context.Context) (map[int]map[string]string, error)
+}
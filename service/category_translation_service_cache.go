@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+)
+
+// CategoryTranslationServiceCache keeps all translations of a tenant under one key, so
+// translating cached categories does not query the database either. Saves and deletes drop the key.
+type CategoryTranslationServiceCache struct {
+	CategoryTranslationService CategoryTranslationService
+	readThroughCache
+}
+
+func NewCategoryTranslationServiceCache(categoryTranslationService CategoryTranslationService, cache cache.Cache, ttl time.Duration, stats *cache.Stats) CategoryTranslationService {
+	return &CategoryTranslationServiceCache{
+		CategoryTranslationService: categoryTranslationService,
+		readThroughCache:           readThroughCache{Cache: cache, TTL: ttl, Stats: stats},
+	}
+}
+
+func (service *CategoryTranslationServiceCache) Save(ctx context.Context, request web.CategoryTranslationSaveRequest) (web.CategoryTranslationResponse, error) {
+	response, err := service.CategoryTranslationService.Save(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.invalidate(ctx, categoryTranslationsCacheKey(tenantId))
+	return response, nil
+}
+
+func (service *CategoryTranslationServiceCache) Delete(ctx context.Context, categoryId int, locale string) error {
+	if err := service.CategoryTranslationService.Delete(ctx, categoryId, locale); err != nil {
+		return err
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	service.invalidate(ctx, categoryTranslationsCacheKey(tenantId))
+	return nil
+}
+
+func (service *CategoryTranslationServiceCache) FindAll(ctx context.Context, categoryId int) ([]web.CategoryTranslationResponse, error) {
+	return service.CategoryTranslationService.FindAll(ctx, categoryId)
+}
+
+// Translate picks the names from the cached translations of the tenant
+func (service *CategoryTranslationServiceCache) Translate(ctx context.Context, categories []web.CategoryResponse, locales []string) ([]web.CategoryResponse, error) {
+	if len(categories) == 0 || len(locales) == 0 {
+		return categories, nil
+	}
+	names, err := service.Names(ctx)
+	if err != nil {
+		return categories, err
+	}
+	return translateNames(categories, names, locales), nil
+}
+
+func (service *CategoryTranslationServiceCache) Names(ctx context.Context) (map[int]map[string]string, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return service.CategoryTranslationService.Names(ctx)
+	}
+	var names map[int]map[string]string
+	err := service.readThrough(ctx, categoryTranslationsCacheKey(tenantId), &names, func(ctx context.Context) (any, error) {
+		return service.CategoryTranslationService.Names(ctx)
+	})
+	return names, err
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+)
+
+type CategoryTranslationServiceImpl struct {
+	CategoryTranslationRepository repository.CategoryTranslationRepository
+	CategoryRepository            repository.CategoryRepository
+	Transaction                   *database.TransactionManager
+	Validate                      *validator.Validate
+}
+
+func NewCategoryTranslationService(categoryTranslationRepository repository.CategoryTranslationRepository, categoryRepository repository.CategoryRepository, transaction *database.TransactionManager, validate *validator.Validate) CategoryTranslationService {
+	return &CategoryTranslationServiceImpl{
+		CategoryTranslationRepository: categoryTranslationRepository,
+		CategoryRepository:            categoryRepository,
+		Transaction:                   transaction,
+		Validate:                      validate,
+	}
+}
+
+// Save stores the locale in its canonical form, so en-us and en-US are the same translation
+func (service *CategoryTranslationServiceImpl) Save(ctx context.Context, request web.CategoryTranslationSaveRequest) (web.CategoryTranslationResponse, error) {
+
+	var response web.CategoryTranslationResponse
+
+	// validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
+	canonical, err := locale.Canonical(request.Locale)
+	if err != nil {
+		return response, err
+	}
+
+	translation := domain.CategoryTranslation{
+		CategoryId: request.CategoryId,
+		Locale:     canonical,
+		Name:       request.Name,
+	}
+	err = service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+
+		// check if the category with that id exists or not
+		_, err := service.CategoryRepository.FindById(ctx, tx, request.CategoryId)
+		if err != nil {
+			return err
+		}
+
+		translation, err = service.CategoryTranslationRepository.Save(ctx, tx, translation)
+		return err
+	})
+	if err != nil {
+		return response, err
+	}
+
+	response = web.CategoryTranslationResponse{
+		CategoryId: translation.CategoryId,
+		Locale:     translation.Locale,
+		Name:       translation.Name,
+	}
+	return response, nil
+}
+
+func (service *CategoryTranslationServiceImpl) Delete(ctx context.Context, categoryId int, localeTag string) error {
+	canonical, err := locale.Canonical(localeTag)
+	if err != nil {
+		return exception.NewNotFoundError("translation not found")
+	}
+
+	return service.Transaction.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return service.CategoryTranslationRepository.Delete(ctx, tx, categoryId, canonical)
+	})
+}
+
+func (service *CategoryTranslationServiceImpl) FindAll(ctx context.Context, categoryId int) ([]web.CategoryTranslationResponse, error) {
+
+	var responses []web.CategoryTranslationResponse
+
+	var translations []domain.CategoryTranslation
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+
+		// a category without translations is told apart from a missing one
+		_, err := service.CategoryRepository.FindById(ctx, tx, categoryId)
+		if err != nil {
+			return err
+		}
+
+		translations, err = service.CategoryTranslationRepository.FindByCategoryId(ctx, tx, categoryId)
+		return err
+	})
+	if err != nil {
+		return responses, err
+	}
+
+	responses = make([]web.CategoryTranslationResponse, 0, len(translations))
+	for _, translation := range translations {
+		responses = append(responses, web.CategoryTranslationResponse{
+			CategoryId: translation.CategoryId,
+			Locale:     translation.Locale,
+			Name:       translation.Name,
+		})
+	}
+	return responses, nil
+}
+
+func (service *CategoryTranslationServiceImpl) Translate(ctx context.Context, categories []web.CategoryResponse, locales []string) ([]web.CategoryResponse, error) {
+	if len(categories) == 0 || len(locales) == 0 {
+		return categories, nil
+	}
+
+	categoryIds := make([]int, 0, len(categories))
+	for _, category := range categories {
+		categoryIds = append(categoryIds, category.Id)
+	}
+
+	var translations []domain.CategoryTranslation
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		translations, err = service.CategoryTranslationRepository.FindByLocales(ctx, tx, categoryIds, locales)
+		return err
+	})
+	if err != nil {
+		return categories, err
+	}
+
+	return translateNames(categories, translationNames(translations), locales), nil
+}
+
+func (service *CategoryTranslationServiceImpl) Names(ctx context.Context) (map[int]map[string]string, error) {
+	var translations []domain.CategoryTranslation
+	err := service.Transaction.Read(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		translations, err = service.CategoryTranslationRepository.FindAll(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return translationNames(translations), nil
+}
+
+// translationNames indexes the names by category and locale
+func translationNames(translations []domain.CategoryTranslation) map[int]map[string]string {
+	names := make(map[int]map[string]string, len(translations))
+	for _, translation := range translations {
+		if names[translation.CategoryId] == nil {
+			names[translation.CategoryId] = map[string]string{}
+		}
+		names[translation.CategoryId][translation.Locale] = translation.Name
+	}
+	return names
+}
+
+// translateNames gives every category the name of the first of locales it has one in
+func translateNames(categories []web.CategoryResponse, names map[int]map[string]string, locales []string) []web.CategoryResponse {
+	translated := make([]web.CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		for _, localeTag := range locales {
+			if name, ok := names[category.Id][localeTag]; ok {
+				category.Name = name
+				category.Locale = localeTag
+				break
+			}
+		}
+		translated = append(translated, category)
+	}
+	return translated
+}
@@ -0,0 +1,79 @@
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength leaves room in the 200 characters of the column for a collision suffix
+const MaxLength = 190
+
+// Fallback is the slug of names without a letter or a digit
+const Fallback = "category"
+
+// Reserved slugs cannot be looked up, GET /api/categories/by-slug/translations is the
+// translations of a category with the id by-slug. Every route below /api/categories/:categoryId
+// takes its first segment, a method it does not have answers 405 before slugs are tried.
+var Reserved = []string{"translations", "move"}
+
+// letters that do not decompose into a Latin letter and a mark, and the Cyrillic and
+// Greek alphabets, other scripts are kept as they are
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i", 'ħ': "h", 'ŧ': "t", 'ŋ': "ng",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ђ': "dj",
+
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l",
+	'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f",
+	'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Make derives a slug from a name: lowercase letters and digits separated by single dashes.
+// Latin letters lose their accents, Cyrillic and Greek are transliterated and the letters
+// of other scripts are kept, so "Crème Brûlée" is creme-brulee and "Книги" is knigi.
+func Make(name string) string {
+	words := []string{}
+	var word strings.Builder
+	for _, r := range strings.ToLower(norm.NFC.String(name)) {
+		// й and ё are letters of their own, other letters are looked up without their accents
+		// and ligatures such as ﬁ are split up
+		decomposed := string(r)
+		if _, ok := transliterations[r]; !ok {
+			decomposed = strings.ToLower(norm.NFKD.String(decomposed))
+		}
+		for _, r := range decomposed {
+			text, ok := transliterations[r]
+			switch {
+			case ok:
+				word.WriteString(text)
+			case unicode.Is(unicode.Mn, r):
+			case unicode.IsLetter(r) || unicode.IsNumber(r):
+				word.WriteRune(r)
+			case word.Len() > 0:
+				words = append(words, word.String())
+				word.Reset()
+			}
+		}
+	}
+	words = append(words, word.String())
+
+	slug := []rune(strings.Trim(strings.Join(words, "-"), "-"))
+	if len(slug) > MaxLength {
+		slug = []rune(strings.TrimRight(string(slug[:MaxLength]), "-"))
+	}
+	if len(slug) == 0 {
+		return Fallback
+	}
+	return string(slug)
+}
+
+// WithSuffix is the slug tried when base is taken, n counts from 2
+func WithSuffix(base string, n int) string {
+	return base + "-" + strconv.Itoa(n)
+}
@@ -0,0 +1,37 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// ErrMissing is returned by Require, a query without a tenant fails instead of seeing every tenant
+var ErrMissing = errors.New("tenant: no tenant in the context")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Valid reports whether id is a tenant id: lowercase letters, digits, - and _, at most 64 characters
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type contextKey struct{}
+
+// WithID returns ctx scoped to the tenant, the auth middleware and the admin commands set it
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+func Require(ctx context.Context) (string, error) {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrMissing
+	}
+	return id, nil
+}
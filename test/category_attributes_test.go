@@ -0,0 +1,209 @@
+package test
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/attributes"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+const colorSchema = `{"type": "object", "required": ["color"], "properties": {"color": {"type": "string", "enum": ["black", "white"]}, "size": {"type": "object", "properties": {"width": {"type": "integer", "minimum": 1}}}}}`
+
+func TestAttributeSchemas(t *testing.T) {
+	schema, err := attributes.ParseSchema([]byte(colorSchema))
+	assert.NoError(t, err)
+
+	assert.NoError(t, attributes.Check(schema, map[string]any{"color": "black", "size": map[string]any{"width": float64(2)}}))
+	assert.Equal(t, exception.NewBadRequestError(`attributes.color: value is not one of the allowed values ["black","white"]; attributes.size.width: number must be at least 1`),
+		attributes.Check(schema, map[string]any{"color": "red", "size": map[string]any{"width": float64(0)}}))
+
+	// required attributes are enforced on categories without any
+	assert.Equal(t, exception.NewBadRequestError(`attributes.color: property "color" is missing`), attributes.Check(schema, nil))
+
+	_, err = attributes.ParseSchema([]byte(`{"type": "objekt"}`))
+	assert.IsType(t, exception.BadRequestError{}, err)
+	_, err = attributes.ParseSchema([]byte(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object"}`))
+	assert.NoError(t, err)
+
+	// integers decoded from MessagePack or CBOR become JSON numbers
+	normalized, err := attributes.Normalize(map[string]any{"width": int8(3)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"width": float64(3)}, normalized)
+
+	path, err := attributes.Path("size.width")
+	assert.NoError(t, err)
+	assert.Equal(t, `$."size"."width"`, path)
+	for _, invalid := range []string{"", "size.", `a"b`, "a b", "color[0]", "*"} {
+		_, err = attributes.Path(invalid)
+		assert.IsType(t, exception.BadRequestError{}, err, invalid)
+	}
+}
+
+func TestCategoryAttributesCheckedAgainstSchema(t *testing.T) {
+	fake, db := newFakeDB()
+	var schemaArgs []driver.NamedValue
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT category_type, definition, updated_at ") {
+			schemaArgs = args
+			return attributeSchemaColumns, [][]driver.Value{{"product", []byte(colorSchema), categoryCreatedAt}}, nil
+		}
+		return categoryRows("Gadget")(query, args)
+	}
+	var executed [][]driver.NamedValue
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		executed = append(executed, args)
+		return fakeResult{lastInsertId: 2, rowsAffected: 1}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(categoryService), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	send := func(method string, target string, body string) (*httptest.ResponseRecorder, map[string]any) {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var response map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder, response
+	}
+
+	recorder, response := send(http.MethodPost, "/api/categories", `{"name": "Phone", "type": "product", "attributes": {"color": "red"}}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, `attributes.color: value is not one of the allowed values ["black","white"]`, response["data"])
+	assert.Equal(t, []any{"acme", "product"}, []any{schemaArgs[0].Value, schemaArgs[1].Value})
+	assert.Empty(t, executed)
+
+	recorder, response = send(http.MethodPost, "/api/categories", `{"name": "Phone", "type": "product", "attributes": {"color": "black"}}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, map[string]any{"color": "black"}, response["data"].(map[string]any)["attributes"])
+	assert.Equal(t, []any{"product", `{"color":"black"}`}, []any{executed[0][3].Value, executed[0][4].Value})
+
+	// a change leaving the type and attributes alone is not checked, the stored category has none
+	schemaArgs = nil
+	recorder, _ = send(http.MethodPut, "/api/categories/1", `{"name": "Gadgets"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Nil(t, schemaArgs)
+	assert.Nil(t, executed[len(executed)-1][3].Value)
+
+	recorder, _ = send(http.MethodPut, "/api/categories/1", `{"name": "Gadgets", "type": "product"}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestListCategoriesAttributeFilters(t *testing.T) {
+	fake, db := newFakeDB()
+	var query string
+	var args []driver.NamedValue
+	fake.query = func(q string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		query, args = q, a
+		return categoryColumns, [][]driver.Value{categoryRow(1, "Gadget", "gadget")}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(categoryService), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	send := func(target string) (*httptest.ResponseRecorder, map[string]any) {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var body map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder, body
+	}
+
+	recorder, _ := send("/api/categories?type=product&attr.color=black&attr.color=white&attr.size.width=2")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? AND type = ?"+
+		" AND JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) IN (?, ?) AND JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) IN (?) ORDER BY sort_order, id", query)
+	values := []any{}
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	assert.Equal(t, []any{"acme", "product", `$."color"`, "black", "white", `$."size"."width"`, "2"}, values)
+
+	// an empty type matches the categories without one
+	recorder, _ = send("/api/categories?type=")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "", args[1].Value)
+
+	recorder, body := send(`/api/categories?attr.color')%20OR%201=1%20--=x`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, body["data"], "is not an attribute path")
+
+	target := "/api/categories?"
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"} {
+		target += "attr." + key + "=1&"
+	}
+	recorder, body = send(target)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "at most 10 attribute filters are allowed", body["data"])
+}
+
+func TestAttributeSchemaEndpoints(t *testing.T) {
+	fake, db := newFakeDB()
+	var schemaRows [][]driver.Value
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return attributeSchemaColumns, schemaRows, nil
+	}
+	var executed [][]driver.NamedValue
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		executed = append(executed, args)
+		return fakeResult{rowsAffected: int64(len(schemaRows))}, nil
+	}
+	transactionManager := database.NewTransactionManager(db, nil)
+	schemaService := service.NewCategoryAttributeSchemaService(repository.NewCategoryAttributeSchemaRepository(), transactionManager, validator.New())
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(nil), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(schemaService)))
+
+	send := func(method string, target string, body string) (*httptest.ResponseRecorder, map[string]any) {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var response map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder, response
+	}
+
+	recorder, response := send(http.MethodPut, "/api/category-types/product/schema", `{"schema": `+colorSchema+`}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "product", response["data"].(map[string]any)["category_type"])
+	assert.Equal(t, []any{"color"}, response["data"].(map[string]any)["schema"].(map[string]any)["required"])
+	assert.Equal(t, []any{"acme", "product"}, []any{executed[0][0].Value, executed[0][1].Value})
+
+	// the schema of the tenant has no type
+	recorder, _ = send(http.MethodPut, "/api/category-schema", `{"schema": {"type": "object"}}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "", executed[1][1].Value)
+
+	recorder, response = send(http.MethodPut, "/api/category-schema", `{"schema": {"type": "objekt"}}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, response["data"], "invalid schema")
+	recorder, _ = send(http.MethodPut, "/api/category-schema", `{}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder, _ = send(http.MethodGet, "/api/category-schema", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder, _ = send(http.MethodDelete, "/api/category-types/product/schema", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	schemaRows = [][]driver.Value{{"product", []byte(colorSchema), categoryCreatedAt}}
+	recorder, response = send(http.MethodGet, "/api/category-types/product/schema", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "2026-01-02T03:04:05Z", response["data"].(map[string]any)["updated_at"])
+	recorder, _ = send(http.MethodDelete, "/api/category-types/product/schema", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,209 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/client"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyHandler fails the first failures requests with statusCode before passing them on
+type flakyHandler struct {
+	Handler    http.Handler
+	statusCode int
+	failures   int
+	retryAfter string // "0" when empty
+
+	mu              sync.Mutex
+	attempts        int
+	idempotencyKeys []string
+}
+
+func (handler *flakyHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	handler.mu.Lock()
+	handler.attempts++
+	handler.idempotencyKeys = append(handler.idempotencyKeys, request.Header.Get("Idempotency-Key"))
+	fail := handler.attempts <= handler.failures
+	handler.mu.Unlock()
+
+	if fail {
+		retryAfter := handler.retryAfter
+		if retryAfter == "" {
+			retryAfter = "0"
+		}
+		writer.Header().Set("Retry-After", retryAfter)
+		exception.WriteErrorResponse(writer, request, handler.statusCode, "FAILURE", "try again")
+		return
+	}
+	handler.Handler.ServeHTTP(writer, request)
+}
+
+// newClientTester serves the real router behind the auth and idempotency middleware
+func newClientTester(t *testing.T, flaky *flakyHandler) (*client.CategoryClient, *fakeCategoryService) {
+	fake := newFakeCategoryService()
+	var handler http.Handler = app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	handler = middleware.NewIdempotencyMiddleware(handler, cache.NewLRUCache(100), middleware.IdempotencyConfig{TTL: time.Minute, Size: 100})
+	if flaky != nil {
+		flaky.Handler = handler
+		handler = flaky
+	}
+	handler = middleware.NewAuthMiddleware(handler, "RAHASIA")
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	categoryClient := client.NewCategoryClient(server.URL, client.APIKey("RAHASIA"))
+	categoryClient.RetryBackoff = time.Millisecond
+	return categoryClient, fake
+}
+
+func TestCategoryClientCRUD(t *testing.T) {
+	categoryClient, _ := newClientTester(t, nil)
+	ctx := context.Background()
+
+	created, err := categoryClient.Create(ctx, web.CategoryCreateRequest{Name: "Gadget"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadget", created.Name)
+
+	updated, err := categoryClient.Update(ctx, web.CategoryUpdateRequest{Id: created.Id, Name: "Gadgets"})
+	assert.NoError(t, err)
+	assert.Equal(t, web.CategoryResponse{Id: created.Id, Name: "Gadgets", Slug: "gadgets", Attributes: map[string]any{}, IsActive: true}, updated)
+
+	found, err := categoryClient.Get(ctx, created.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, found)
+
+	categoryClient.Create(ctx, web.CategoryCreateRequest{Name: "Book"})
+	categories, err := categoryClient.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+
+	var names []string
+	for category, err := range categoryClient.Categories(ctx) {
+		assert.NoError(t, err)
+		names = append(names, category.Name)
+	}
+	assert.ElementsMatch(t, []string{"Gadgets", "Book"}, names)
+
+	assert.NoError(t, categoryClient.Delete(ctx, created.Id))
+	_, err = categoryClient.Get(ctx, created.Id)
+	assert.True(t, client.IsNotFound(err))
+	assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+}
+
+func TestCategoryClientTypedErrors(t *testing.T) {
+	categoryClient, _ := newClientTester(t, nil)
+
+	err := categoryClient.Delete(context.Background(), 404)
+	notFoundError := client.NotFoundError{}
+	assert.ErrorAs(t, err, &notFoundError)
+	assert.Equal(t, "category not found", notFoundError.Message)
+
+	_, err = categoryClient.Create(context.Background(), web.CategoryCreateRequest{})
+	apiError := &client.APIError{}
+	assert.ErrorAs(t, err, &apiError)
+	assert.Equal(t, http.StatusBadRequest, apiError.StatusCode)
+	assert.Equal(t, "BAD REQUEST", apiError.Status)
+
+	categoryClient.Credentials = client.APIKey("SALAH")
+	_, err = categoryClient.List(context.Background())
+	assert.Equal(t, http.StatusUnauthorized, client.StatusCode(err))
+}
+
+func TestCategoryClientRetries(t *testing.T) {
+	for _, statusCode := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		flaky := &flakyHandler{statusCode: statusCode, failures: 2}
+		categoryClient, fake := newClientTester(t, flaky)
+
+		created, err := categoryClient.Create(context.Background(), web.CategoryCreateRequest{Name: "Gadget"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Gadget", created.Name)
+		assert.Equal(t, 3, flaky.attempts)
+		assert.Len(t, fake.categories, 1)
+
+		// every attempt of a create carries the same key
+		assert.NotEmpty(t, flaky.idempotencyKeys[0])
+		assert.Equal(t, flaky.idempotencyKeys[0], flaky.idempotencyKeys[2])
+	}
+}
+
+func TestCategoryClientGivesUpAfterMaxRetries(t *testing.T) {
+	flaky := &flakyHandler{statusCode: http.StatusInternalServerError, failures: 10}
+	categoryClient, _ := newClientTester(t, flaky)
+	categoryClient.MaxRetries = 2
+
+	_, err := categoryClient.List(context.Background())
+	assert.Equal(t, http.StatusInternalServerError, client.StatusCode(err))
+	assert.Equal(t, 3, flaky.attempts)
+
+	// client errors are not retried
+	flaky = &flakyHandler{statusCode: http.StatusConflict, failures: 10}
+	categoryClient, _ = newClientTester(t, flaky)
+	_, err = categoryClient.List(context.Background())
+	assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+	assert.Equal(t, 1, flaky.attempts)
+}
+
+func TestCategoryClientContext(t *testing.T) {
+	flaky := &flakyHandler{statusCode: http.StatusServiceUnavailable, failures: 10}
+	categoryClient, _ := newClientTester(t, flaky)
+	categoryClient.RetryBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := categoryClient.Get(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, flaky.attempts)
+}
+
+func TestCategoryClientRetryAfterIsCapped(t *testing.T) {
+	// a Retry-After longer than MaxRetryAfter is not waited for
+	flaky := &flakyHandler{statusCode: http.StatusTooManyRequests, failures: 10, retryAfter: "3600"}
+	categoryClient, _ := newClientTester(t, flaky)
+	start := time.Now()
+	_, err := categoryClient.List(context.Background())
+	assert.Equal(t, http.StatusTooManyRequests, client.StatusCode(err))
+	assert.Equal(t, 1, flaky.attempts)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// nor one ending after the deadline of the context
+	flaky = &flakyHandler{statusCode: http.StatusServiceUnavailable, failures: 10, retryAfter: "30"}
+	categoryClient, _ = newClientTester(t, flaky)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = categoryClient.List(ctx)
+	assert.Equal(t, http.StatusServiceUnavailable, client.StatusCode(err))
+	assert.Equal(t, 1, flaky.attempts)
+}
+
+func TestCategoryClientBearerToken(t *testing.T) {
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		authorization = append(authorization, request.Header.Get("Authorization"))
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(`{"code":200,"status":"OK","data":[]}`))
+	}))
+	defer server.Close()
+
+	tokens := 0
+	categoryClient := client.NewCategoryClient(server.URL, client.TokenSource(func(ctx context.Context) (string, error) {
+		tokens++
+		return "token-" + strconv.Itoa(tokens), nil
+	}))
+	categoryClient.List(context.Background())
+	categoryClient.List(context.Background())
+
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, authorization)
+}
@@ -16,35 +16,51 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
 	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/fixtures"
 	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
 	"github.com/rozanlaudzai/go-mysql-restful-api/model/domain"
 	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
 	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
 	"github.com/stretchr/testify/assert"
 )
 
-func truncateCategory(db *sql.DB) error {
-	_, err := db.Exec("TRUNCATE category")
-	return err
-}
+// newDBTester sets the category table to a dataset of testdata, loaded through the category service
+func newDBTester(dataset string) (*sql.DB, fixtures.Report, error) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := app.NewDB(cfg.Database)
+	if err != nil {
+		return db, nil, err
+	}
 
-func newDBTester() (*sql.DB, error) {
-	// set up db tester
-	db, err := app.NewDB()
+	scenario, err := fixtures.Named(os.DirFS("testdata"), dataset)
 	if err != nil {
-		return db, err
+		return db, nil, err
 	}
-	err = truncateCategory(db)
-	return db, err
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	report, err := fixtures.NewLoader(categoryService).Replace(tenant.WithID(context.Background(), "default"), scenario)
+	return db, report, err
 }
 
-func newRouterTester(db *sql.DB) (http.Handler, error) {
+// newRouterTester checks every response against apispec.json
+func newRouterTester(t *testing.T, db *sql.DB) (http.Handler, error) {
 	validate := validator.New()
 	categoryRepository := repository.NewCategoryRepository()
-	categoryService := service.NewCategoryService(categoryRepository, db, validate)
+	transactionManager := database.NewTransactionManager(db, nil)
+	categoryAttributeSchemaRepository := repository.NewCategoryAttributeSchemaRepository()
+	categoryService := service.NewCategoryService(categoryRepository, categoryAttributeSchemaRepository, transactionManager, validate)
+	categoryTranslationService := service.NewCategoryTranslationService(repository.NewCategoryTranslationRepository(), categoryRepository, transactionManager, validate)
+	categoryAttributeSchemaService := service.NewCategoryAttributeSchemaService(categoryAttributeSchemaRepository, transactionManager, validate)
 	categoryController := controller.NewCategoryController(categoryService)
-	router := app.NewRouter(categoryController)
+	categoryTranslationController := controller.NewCategoryTranslationController(categoryTranslationService)
+	categoryAttributeSchemaController := controller.NewCategoryAttributeSchemaController(categoryAttributeSchemaService)
+	router := newResponseValidationTester(t, app.NewRouter(categoryController, categoryTranslationController, categoryAttributeSchemaController))
 	// set auth middleware
 	apiKey := os.Getenv("API_KEY")
 	authMiddleware := middleware.NewAuthMiddleware(router, apiKey)
@@ -55,11 +71,11 @@ func TestCreateCategorySuccess(t *testing.T) {
 	if err := godotenv.Load("../.env.test"); err != nil {
 		panic(err)
 	}
-	db, err := newDBTester()
+	db, _, err := newDBTester("empty")
 	if err != nil {
 		panic(err)
 	}
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -96,11 +112,11 @@ func TestCreateCategoryBadRequest(t *testing.T) {
 	if err := godotenv.Load("../.env.test"); err != nil {
 		panic(err)
 	}
-	db, err := newDBTester()
+	db, _, err := newDBTester("empty")
 	if err != nil {
 		panic(err)
 	}
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -137,28 +153,13 @@ func TestUpdateCategorySuccess(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
-	if err != nil {
-		panic(err)
-	}
-
-	// add a category to db
-	tx, err := db.Begin()
-	if err != nil {
-		panic(err)
-	}
-	categoryRepository := repository.NewCategoryRepository()
-	category, err := categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
+	db, report, err := newDBTester("one_category")
 	if err != nil {
 		panic(err)
 	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
+	category := domain.Category{Id: report.Id("Electronics"), Name: "Electronics"}
 
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -208,28 +209,13 @@ func TestUpdateCategoryBadRequest(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
+	db, report, err := newDBTester("one_category")
 	if err != nil {
 		panic(err)
 	}
+	category := domain.Category{Id: report.Id("Electronics"), Name: "Electronics"}
 
-	// add a category to db
-	tx, err := db.Begin()
-	if err != nil {
-		panic(err)
-	}
-	categoryRepository := repository.NewCategoryRepository()
-	category, err := categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
-	if err != nil {
-		panic(err)
-	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
-
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -267,28 +253,13 @@ func TestFindCategoryByIdSuccess(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
-	if err != nil {
-		panic(err)
-	}
-
-	// add a category to db
-	tx, err := db.Begin()
-	if err != nil {
-		panic(err)
-	}
-	categoryRepository := repository.NewCategoryRepository()
-	category, err := categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
+	db, report, err := newDBTester("one_category")
 	if err != nil {
 		panic(err)
 	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
+	category := domain.Category{Id: report.Id("Electronics"), Name: "Electronics"}
 
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -330,28 +301,12 @@ func TestCategoryByIdNotFound(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
+	db, _, err := newDBTester("one_category")
 	if err != nil {
 		panic(err)
 	}
 
-	// add a category to db
-	tx, err := db.Begin()
-	if err != nil {
-		panic(err)
-	}
-	categoryRepository := repository.NewCategoryRepository()
-	_, err = categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
-	if err != nil {
-		panic(err)
-	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
-
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -387,28 +342,13 @@ func TestDeleteCategorySuccess(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
-	if err != nil {
-		panic(err)
-	}
-
-	// add a category to db
-	tx, err := db.Begin()
-	if err != nil {
-		panic(err)
-	}
-	categoryRepository := repository.NewCategoryRepository()
-	category, err := categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
+	db, report, err := newDBTester("one_category")
 	if err != nil {
 		panic(err)
 	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
+	category := domain.Category{Id: report.Id("Electronics"), Name: "Electronics"}
 
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -443,28 +383,12 @@ func TestDeleteCategoryNotFound(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
-	if err != nil {
-		panic(err)
-	}
-
-	// add a category to db
-	tx, err := db.Begin()
+	db, _, err := newDBTester("one_category")
 	if err != nil {
 		panic(err)
 	}
-	categoryRepository := repository.NewCategoryRepository()
-	_, err = categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
-	if err != nil {
-		panic(err)
-	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
 
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -499,34 +423,14 @@ func TestGetAllCategories(t *testing.T) {
 		panic(err)
 	}
 
-	db, err := newDBTester()
+	db, report, err := newDBTester("two_categories")
 	if err != nil {
 		panic(err)
 	}
+	category1 := domain.Category{Id: report.Id("Electronics"), Name: "Electronics"}
+	category2 := domain.Category{Id: report.Id("Fashion"), Name: "Fashion"}
 
-	// add 2 categories to db
-	tx, err := db.Begin()
-	if err != nil {
-		panic(err)
-	}
-	categoryRepository := repository.NewCategoryRepository()
-	category1, err := categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Electronics",
-	})
-	if err != nil {
-		panic(err)
-	}
-	category2, err := categoryRepository.Create(context.Background(), tx, domain.Category{
-		Name: "Fashion",
-	})
-	if err != nil {
-		panic(err)
-	}
-	if err = tx.Commit(); err != nil {
-		panic(err)
-	}
-
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -571,11 +475,11 @@ func TestUnauthorized(t *testing.T) {
 	if err := godotenv.Load("../.env.test"); err != nil {
 		panic(err)
 	}
-	db, err := newDBTester()
+	db, _, err := newDBTester("empty")
 	if err != nil {
 		panic(err)
 	}
-	router, err := newRouterTester(db)
+	router, err := newRouterTester(t, db)
 	if err != nil {
 		panic(err)
 	}
@@ -606,3 +510,43 @@ func TestUnauthorized(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, int(responseBody["code"].(float64)))
 	assert.Equal(t, "UNAUTHORIZED", responseBody["status"])
 }
+
+func TestTenantIsolation(t *testing.T) {
+	if err := godotenv.Load("../.env.test"); err != nil {
+		panic(err)
+	}
+	db, report, err := newDBTester("one_category")
+	if err != nil {
+		panic(err)
+	}
+	router, err := newRouterTester(t, db)
+	if err != nil {
+		panic(err)
+	}
+
+	// another tenant may use the same name
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	_, err = fixtures.NewLoader(categoryService).Replace(tenant.WithID(context.Background(), "acme"), fixtures.Dataset{Categories: []fixtures.Category{{Name: "Electronics"}}})
+	assert.Nil(t, err)
+
+	send := func(method string, path string, body string, tenantId string) int {
+		request := httptest.NewRequest(method, "http://localhost/api/categories"+path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-API-Key", os.Getenv("API_KEY"))
+		request.Header.Set("X-Tenant-ID", tenantId)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// the category of the default tenant cannot be read, changed or deleted as acme
+	path := fmt.Sprintf("/%d", report.Id("Electronics"))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path, "", "acme"))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPut, path, `{"name": "Gadget"}`, "acme"))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, path, "", "acme"))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, path, "", "default"))
+
+	// names are unique within a tenant
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "", `{"name": "Electronics"}`, "default"))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "", `{"name": "Fashion"}`, "acme"))
+}
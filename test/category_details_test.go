@@ -0,0 +1,146 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryDetailsValidation(t *testing.T) {
+	validate := validator.New()
+	empty, ftp := "", "ftp://cdn.example.com/a.png"
+
+	assert.NoError(t, validate.Struct(web.CategoryCreateRequest{Name: "Gadget", ImageURL: "https://cdn.example.com/a.png", SortOrder: -5}))
+	assert.Error(t, validate.Struct(web.CategoryCreateRequest{Name: "Gadget", ImageURL: ftp}))
+	assert.Error(t, validate.Struct(web.CategoryCreateRequest{Name: "Gadget", Description: strings.Repeat("a", 2001)}))
+	assert.Error(t, validate.Struct(web.CategoryCreateRequest{Name: "Gadget", SortOrder: 1000001}))
+
+	// an empty image url clears it
+	assert.NoError(t, validate.Struct(web.CategoryUpdateRequest{Id: 1, Name: "Gadget", ImageURL: &empty}))
+	assert.Error(t, validate.Struct(web.CategoryUpdateRequest{Id: 1, Name: "Gadget", ImageURL: &ftp}))
+}
+
+func TestCategoryDetailsCreateAndUpdate(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT category_type, definition, updated_at ") {
+			return attributeSchemaColumns, nil, nil
+		}
+		if strings.HasPrefix(query, "SELECT slug ") {
+			return []string{"slug"}, nil, nil
+		}
+		return categoryColumns, [][]driver.Value{
+			{int64(1), "Gadget", "gadget", "", nil, "Small things", "https://cdn.example.com/gadget.png", int64(3), true, categoryCreatedAt, categoryCreatedAt},
+		}, nil
+	}
+	var executed [][]driver.NamedValue
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		executed = append(executed, args)
+		return fakeResult{lastInsertId: 2, rowsAffected: 1}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	// categories are active unless asked otherwise, both timestamps are the time of creation
+	before := time.Now().UTC().Truncate(time.Second)
+	created, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Book", Description: "Paper", SortOrder: 7})
+	assert.NoError(t, err)
+	assert.True(t, created.IsActive)
+	assert.Equal(t, "Paper", created.Description)
+	assert.Equal(t, 7, created.SortOrder)
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+	assert.False(t, created.CreatedAt.Before(before))
+	assert.Equal(t, []any{"Paper", "", int64(7), true}, []any{executed[0][5].Value, executed[0][6].Value, executed[0][7].Value, executed[0][8].Value})
+
+	// details left out keep their value, created_at never changes
+	updated, err := categoryService.Update(ctx, web.CategoryUpdateRequest{Id: 1, Name: "Gadgets"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Small things", updated.Description)
+	assert.Equal(t, "https://cdn.example.com/gadget.png", updated.ImageURL)
+	assert.Equal(t, 3, updated.SortOrder)
+	assert.True(t, updated.IsActive)
+	assert.Equal(t, categoryCreatedAt, updated.CreatedAt)
+	assert.False(t, updated.UpdatedAt.Before(before))
+
+	empty, inactive := "", false
+	updated, err = categoryService.Update(ctx, web.CategoryUpdateRequest{Id: 1, Name: "Gadgets", ImageURL: &empty, IsActive: &inactive})
+	assert.NoError(t, err)
+	assert.Equal(t, "Small things", updated.Description)
+	assert.Empty(t, updated.ImageURL)
+	assert.False(t, updated.IsActive)
+}
+
+func TestListCategoriesFilters(t *testing.T) {
+	fake, db := newFakeDB()
+	var query string
+	var args []driver.NamedValue
+	fake.query = func(q string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		query, args = q, a
+		return categoryColumns, [][]driver.Value{categoryRow(1, "Gadget", "gadget")}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(categoryService), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	send := func(target string) (*httptest.ResponseRecorder, map[string]any) {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var body map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder, body
+	}
+
+	recorder, body := send("/api/categories")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? ORDER BY sort_order, id", query)
+	assert.Equal(t, map[string]any{
+		"id": float64(1), "name": "Gadget", "slug": "gadget", "type": "", "attributes": map[string]any{}, "description": "", "image_url": "", "sort_order": float64(0), "is_active": true,
+		"created_at": "2026-01-02T03:04:05Z", "updated_at": "2026-01-02T03:04:05Z",
+	}, body["data"].([]any)[0])
+
+	// times in other zones are compared in UTC
+	recorder, _ = send("/api/categories?is_active=false&created_after=2026-01-01T00:00:00%2B02:00&updated_before=2026-02-01T00:00:00Z")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? AND is_active = ? AND created_at >= ? AND updated_at < ? ORDER BY sort_order, id", query)
+	assert.Equal(t, []any{"acme", false, time.Date(2025, 12, 31, 22, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		[]any{args[0].Value, args[1].Value, args[2].Value, args[3].Value})
+
+	recorder, body = send("/api/categories?is_active=maybe")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "is_active must be true or false", body["data"])
+	recorder, body = send("/api/categories?created_before=yesterday")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, body["data"], "created_before must be an RFC 3339 date and time")
+}
+
+func TestCategoryServiceCacheSkipsFilteredLists(t *testing.T) {
+	fake := newFakeCategoryService()
+	categoryService := service.NewCategoryServiceCache(fake, cache.NewLRUCache(100), time.Minute, &cache.Stats{})
+	ctx := tenant.WithID(context.Background(), "acme")
+	active := true
+
+	for range 2 {
+		_, err := categoryService.FindAll(ctx, web.CategoryListRequest{IsActive: &active})
+		assert.NoError(t, err)
+		_, err = categoryService.FindAll(ctx, web.CategoryListRequest{Attributes: map[string][]string{"color": {"black"}}})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(4), fake.reads.Load())
+}
@@ -0,0 +1,298 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/proto/categorypb"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/rpc"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newGRPCTester serves the category service over an in-memory connection
+func newGRPCTester(t *testing.T) (categorypb.CategoryServiceClient, service.CategoryService) {
+	return newGRPCTesterWith(t, newFakeCategoryService(), middleware.RateLimitConfig{}, middleware.TimeoutConfig{})
+}
+
+// newGRPCTesterWith serves categoryService with the given rate limits and deadlines
+func newGRPCTesterWith(t *testing.T, fake service.CategoryService, rateLimit middleware.RateLimitConfig, timeout middleware.TimeoutConfig) (categorypb.CategoryServiceClient, service.CategoryService) {
+	events := service.NewCategoryEventBroker()
+	categoryService := service.NewCategoryServiceEvents(fake, events)
+	auth := middleware.NewAuthMiddleware(nil, "RAHASIA")
+	server := app.NewGRPCServer(rpc.NewCategoryServer(categoryService, events), auth, middleware.NewRateLimitMiddleware(nil, rateLimit), timeout, nil)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	connection, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { connection.Close() })
+	return categorypb.NewCategoryServiceClient(connection), categoryService
+}
+
+func grpcContext(apiKey string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", apiKey)
+}
+
+func TestGRPCCategoryCRUD(t *testing.T) {
+	categoryClient, _ := newGRPCTester(t)
+	ctx := grpcContext("RAHASIA")
+
+	created, err := categoryClient.Create(ctx, &categorypb.CreateCategoryRequest{Name: "Gadget"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadget", created.GetName())
+
+	updated, err := categoryClient.Update(ctx, &categorypb.UpdateCategoryRequest{Id: created.GetId(), Name: "Gadgets"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadgets", updated.GetName())
+
+	found, err := categoryClient.Get(ctx, &categorypb.GetCategoryRequest{Id: created.GetId()})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadgets", found.GetName())
+
+	_, err = categoryClient.Delete(ctx, &categorypb.DeleteCategoryRequest{Id: created.GetId()})
+	assert.NoError(t, err)
+
+	_, err = categoryClient.Get(ctx, &categorypb.GetCategoryRequest{Id: created.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "category not found", status.Convert(err).Message())
+}
+
+func TestGRPCCategoryFields(t *testing.T) {
+	fake := newFakeCategoryService()
+	categoryClient, _ := newGRPCTesterWith(t, fake, middleware.RateLimitConfig{}, middleware.TimeoutConfig{})
+	ctx := grpcContext("RAHASIA")
+
+	attributes, err := structpb.NewStruct(map[string]any{"color": "black", "warranty_years": 2})
+	assert.NoError(t, err)
+	created, err := categoryClient.Create(ctx, &categorypb.CreateCategoryRequest{
+		Name:        "Phones",
+		Slug:        "smart-phones",
+		Type:        "product",
+		Attributes:  attributes,
+		Description: "Phones and tablets",
+		ImageUrl:    "https://cdn.example.com/phones.png",
+		SortOrder:   10,
+		IsActive:    proto.Bool(false),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "smart-phones", created.GetSlug())
+	assert.Equal(t, "product", created.GetType())
+	assert.Equal(t, map[string]any{"color": "black", "warranty_years": 2.0}, created.GetAttributes().AsMap())
+	assert.Equal(t, "Phones and tablets", created.GetDescription())
+	assert.Equal(t, "https://cdn.example.com/phones.png", created.GetImageUrl())
+	assert.Equal(t, int32(10), created.GetSortOrder())
+	assert.False(t, created.GetIsActive())
+
+	// fields left out keep their value, an empty string clears one and an empty struct clears the attributes
+	updated, err := categoryClient.Update(ctx, &categorypb.UpdateCategoryRequest{
+		Id:          created.GetId(),
+		Name:        "Phones",
+		Slug:        "smart-phones",
+		Description: proto.String(""),
+		Attributes:  &structpb.Struct{},
+		IsActive:    proto.Bool(true),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "product", updated.GetType())
+	assert.Empty(t, updated.GetDescription())
+	assert.Equal(t, "https://cdn.example.com/phones.png", updated.GetImageUrl())
+	assert.Equal(t, int32(10), updated.GetSortOrder())
+	assert.Empty(t, updated.GetAttributes().AsMap())
+	assert.True(t, updated.GetIsActive())
+
+	// the timestamps of the category are carried over
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	category := fake.categories[int(created.GetId())]
+	category.CreatedAt, category.UpdatedAt = createdAt, createdAt.Add(time.Hour)
+	fake.categories[category.Id] = category
+	found, err := categoryClient.Get(ctx, &categorypb.GetCategoryRequest{Id: created.GetId()})
+	assert.NoError(t, err)
+	assert.Equal(t, createdAt, found.GetCreatedAt().AsTime())
+	assert.Equal(t, createdAt.Add(time.Hour), found.GetUpdatedAt().AsTime())
+}
+
+func TestGRPCCategoryStatusCodes(t *testing.T) {
+	categoryClient, _ := newGRPCTester(t)
+
+	_, err := categoryClient.Create(grpcContext("RAHASIA"), &categorypb.CreateCategoryRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = categoryClient.Get(grpcContext("SALAH"), &categorypb.GetCategoryRequest{Id: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = categoryClient.List(grpcContext("RAHASIA"), &categorypb.ListCategoriesRequest{PageToken: "!"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stream, err := categoryClient.Watch(grpcContext("SALAH"), &categorypb.WatchCategoriesRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCCategoryListPages(t *testing.T) {
+	categoryClient, categoryService := newGRPCTester(t)
+	ctx := grpcContext("RAHASIA")
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		categoryService.Create(context.Background(), web.CategoryCreateRequest{Name: name})
+	}
+
+	var names []string
+	pages := 0
+	request := &categorypb.ListCategoriesRequest{PageSize: 2}
+	for {
+		response, err := categoryClient.List(ctx, request)
+		assert.NoError(t, err)
+		pages++
+		for _, category := range response.GetCategories() {
+			names = append(names, category.GetName())
+		}
+		if response.GetNextPageToken() == "" {
+			break
+		}
+		request.PageToken = response.GetNextPageToken()
+	}
+	assert.Equal(t, []string{"A", "B", "C", "D", "E"}, names)
+	assert.Equal(t, 3, pages)
+
+	// a deleted category does not shift the next page
+	first, _ := categoryClient.List(ctx, &categorypb.ListCategoriesRequest{PageSize: 2})
+	categoryService.DeleteById(context.Background(), 2)
+	second, _ := categoryClient.List(ctx, &categorypb.ListCategoriesRequest{PageSize: 2, PageToken: first.GetNextPageToken()})
+	assert.Equal(t, "C", second.GetCategories()[0].GetName())
+}
+
+func TestGRPCListQueriesOnePage(t *testing.T) {
+	fake, db := newFakeDB()
+	var query string
+	var args []driver.NamedValue
+	fake.query = func(q string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		query, args = q, a
+		return categoryColumns, [][]driver.Value{categoryRow(3, "C", "c"), categoryRow(4, "D", "d"), categoryRow(5, "E", "e")}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	server := rpc.NewCategoryServer(categoryService, nil)
+
+	// the database pages by sort order and id, one row more than the page tells there is a next one
+	response, err := server.List(tenant.WithID(context.Background(), "acme"), &categorypb.ListCategoriesRequest{PageSize: 2, PageToken: base64.RawURLEncoding.EncodeToString([]byte("-5:2"))})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? AND (sort_order > ? OR (sort_order = ? AND id > ?)) ORDER BY sort_order, id LIMIT ?", query)
+	assert.Equal(t, []any{"acme", int64(-5), int64(-5), int64(2), int64(3)}, []any{args[0].Value, args[1].Value, args[2].Value, args[3].Value, args[4].Value})
+	assert.Len(t, response.GetCategories(), 2)
+	assert.NotEmpty(t, response.GetNextPageToken())
+}
+
+func TestGRPCCategoryWatch(t *testing.T) {
+	categoryClient, categoryService := newGRPCTester(t)
+	categoryService.Create(context.Background(), web.CategoryCreateRequest{Name: "Existing"})
+
+	ctx, cancel := context.WithTimeout(grpcContext("RAHASIA"), 5*time.Second)
+	defer cancel()
+	stream, err := categoryClient.Watch(ctx, &categorypb.WatchCategoriesRequest{IncludeExisting: true})
+	assert.NoError(t, err)
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, categorypb.CategoryEvent_CREATED, event.GetType())
+	assert.Equal(t, "Existing", event.GetCategory().GetName())
+
+	// changes through the unary calls reach the watcher in order
+	created, _ := categoryClient.Create(ctx, &categorypb.CreateCategoryRequest{Name: "Gadget"})
+	categoryClient.Update(ctx, &categorypb.UpdateCategoryRequest{Id: created.GetId(), Name: "Gadgets"})
+	categoryClient.Delete(ctx, &categorypb.DeleteCategoryRequest{Id: created.GetId()})
+
+	for _, expected := range []categorypb.CategoryEvent_Type{categorypb.CategoryEvent_CREATED, categorypb.CategoryEvent_UPDATED, categorypb.CategoryEvent_DELETED} {
+		event, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, event.GetType())
+		assert.Equal(t, created.GetId(), event.GetCategory().GetId())
+	}
+}
+
+func TestCategoryEventBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := service.NewCategoryEventBroker()
+	slow, _ := broker.Subscribe("acme", 1)
+	fast, cancel := broker.Subscribe("acme", 10)
+	defer cancel()
+
+	for id := 1; id <= 3; id++ {
+		broker.Publish(service.CategoryEvent{Type: service.CategoryCreated, TenantId: "acme", Category: web.CategoryResponse{Id: id}})
+	}
+
+	// the slow subscriber keeps what it buffered and is then closed
+	assert.Equal(t, 1, (<-slow).Category.Id)
+	_, open := <-slow
+	assert.False(t, open)
+	assert.Len(t, fast, 3)
+}
+
+func TestCategoryEventBrokerIsPerTenant(t *testing.T) {
+	broker := service.NewCategoryEventBroker()
+	acme, cancel := broker.Subscribe("acme", 1)
+	defer cancel()
+
+	// a busy tenant does not fill the buffer of the watchers of another one
+	for id := 1; id <= 3; id++ {
+		broker.Publish(service.CategoryEvent{Type: service.CategoryCreated, TenantId: "globex", Category: web.CategoryResponse{Id: id}})
+	}
+	broker.Publish(service.CategoryEvent{Type: service.CategoryCreated, TenantId: "acme", Category: web.CategoryResponse{Id: 4}})
+
+	event, open := <-acme
+	assert.True(t, open)
+	assert.Equal(t, 4, event.Category.Id)
+	assert.Empty(t, acme)
+}
+
+func TestGRPCRateLimitAndTimeout(t *testing.T) {
+	fake := newFakeCategoryService()
+	fake.delay = time.Second
+	categoryClient, _ := newGRPCTesterWith(t, fake,
+		middleware.RateLimitConfig{Routes: map[string]middleware.RateLimit{categorypb.CategoryService_Get_FullMethodName: {Rate: 0.1, Burst: 2}}},
+		middleware.TimeoutConfig{Routes: map[string]time.Duration{categorypb.CategoryService_Get_FullMethodName: 10 * time.Millisecond}},
+	)
+	ctx := grpcContext("RAHASIA")
+
+	// the deadline of the method cancels the slow read
+	_, err := categoryClient.Get(ctx, &categorypb.GetCategoryRequest{Id: 1})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	_, err = categoryClient.Get(ctx, &categorypb.GetCategoryRequest{Id: 1})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	// and its bucket is empty after two calls
+	var header metadata.MD
+	_, err = categoryClient.Get(ctx, &categorypb.GetCategoryRequest{Id: 1}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"10"}, header.Get("retry-after"))
+
+	// other methods are not limited
+	_, err = categoryClient.List(ctx, &categorypb.ListCategoriesRequest{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveCategory(t *testing.T) {
+	fake, db := newFakeDB()
+	var positions [][]driver.Value
+	var lockQuery string
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT id, sort_order ") {
+			lockQuery = query
+			return []string{"id", "sort_order"}, positions, nil
+		}
+		return categoryColumns, [][]driver.Value{categoryRow(args[1].Value.(int64), "Gadget", "gadget")}, nil
+	}
+	var executed []string
+	var executedArgs [][]any
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		values := []any{}
+		for _, arg := range args {
+			values = append(values, arg.Value)
+		}
+		executed = append(executed, query)
+		executedArgs = append(executedArgs, values)
+		return fakeResult{rowsAffected: 1}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(categoryService), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	send := func(target string, body string) (*httptest.ResponseRecorder, map[string]any) {
+		executed, executedArgs = nil, nil
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var response map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder, response
+	}
+
+	// with room between the neighbours only the moved category changes
+	positions = [][]driver.Value{{int64(1), int64(1024)}, {int64(2), int64(2048)}, {int64(3), int64(3072)}}
+	recorder, response := send("/api/categories/3/move", `{"before_id": 2}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, float64(1536), response["data"].(map[string]any)["sort_order"])
+	assert.Equal(t, "SELECT id, sort_order FROM category WHERE tenant_id = ? ORDER BY sort_order, id FOR UPDATE", lockQuery)
+	assert.Len(t, executed, 1)
+	columns := columnArgs(executed[0], executedArgs[0])
+	assert.Equal(t, []any{int64(1536), "acme", int64(3)}, []any{columns["sort_order"], columns["tenant_id"], columns["id"]})
+
+	// to the ends the category goes a gap past the first or last one
+	recorder, response = send("/api/categories/1/move", `{"after_id": 3}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, float64(4096), response["data"].(map[string]any)["sort_order"])
+	recorder, response = send("/api/categories/3/move", `{"before_id": 1}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, float64(0), response["data"].(map[string]any)["sort_order"])
+
+	// without room every category is spread out again, in one statement besides the moved one
+	positions = [][]driver.Value{{int64(1), int64(0)}, {int64(2), int64(0)}, {int64(3), int64(0)}}
+	recorder, response = send("/api/categories/3/move", `{"after_id": 1}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, float64(2048), response["data"].(map[string]any)["sort_order"])
+	assert.Len(t, executed, 2)
+	assert.Equal(t, "UPDATE category SET sort_order = CASE id WHEN ? THEN ? WHEN ? THEN ? END WHERE tenant_id = ? AND id IN (?, ?)", executed[0])
+	assert.Equal(t, []any{int64(1), int64(1024), int64(2), int64(3072), "acme", int64(1), int64(2)}, executedArgs[0])
+	assert.Equal(t, int64(2048), columnArgs(executed[1], executedArgs[1])["sort_order"])
+
+	// and they are reported, so the cache can drop them
+	afterId := 1
+	move, err := categoryService.Move(tenant.WithID(context.Background(), "acme"), web.CategoryMoveRequest{Id: 3, AfterId: &afterId})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, move.Renumbered)
+
+	for _, body := range []string{`{}`, `{"before_id": 1, "after_id": 2}`, `{"before_id": 3}`, `{"after_id": 0}`} {
+		recorder, _ = send("/api/categories/3/move", body)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+	recorder, response = send("/api/categories/3/move", `{"before_id": 9}`)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "target category not found", response["data"])
+	recorder, _ = send("/api/categories/9/move", `{"before_id": 1}`)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Empty(t, executed)
+}
+
+// columnArgs pairs the "column = ?" placeholders of a statement with their arguments
+func columnArgs(query string, args []any) map[string]any {
+	columns := map[string]any{}
+	for i, match := range regexp.MustCompile(`(\w+) = \?`).FindAllStringSubmatch(query, -1) {
+		columns[match[1]] = args[i]
+	}
+	return columns
+}
@@ -0,0 +1,317 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+// startRedisStandIn serves GET, SET (with NX) and DEL over the Redis protocol from a map, expiry is ignored
+func startRedisStandIn(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mutex sync.Mutex
+	values := map[string]string{}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			// every command is an array of bulk strings
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			args := make([]string, count)
+			for i := range args {
+				line, _ = reader.ReadString('\n')
+				length, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				arg := make([]byte, length+2)
+				if _, err := io.ReadFull(reader, arg); err != nil {
+					return
+				}
+				args[i] = string(arg[:length])
+			}
+
+			mutex.Lock()
+			switch strings.ToUpper(args[0]) {
+			case "GET":
+				if value, ok := values[args[1]]; ok {
+					fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+				} else {
+					fmt.Fprint(conn, "$-1\r\n")
+				}
+			case "SET":
+				if _, ok := values[args[1]]; ok && slices.ContainsFunc(args[3:], func(arg string) bool { return strings.EqualFold(arg, "NX") }) {
+					fmt.Fprint(conn, "$-1\r\n")
+					break
+				}
+				values[args[1]] = args[2]
+				fmt.Fprint(conn, "+OK\r\n")
+			case "DEL":
+				deleted := 0
+				for _, key := range args[1:] {
+					if _, ok := values[key]; ok {
+						delete(values, key)
+						deleted++
+					}
+				}
+				fmt.Fprintf(conn, ":%d\r\n", deleted)
+			default:
+				fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+			}
+			mutex.Unlock()
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func testCategoryServiceCache(t *testing.T, categoryCache cache.Cache) {
+	ctx := tenant.WithID(context.Background(), "default")
+	fake := newFakeCategoryService()
+	stats := &cache.Stats{}
+	categoryService := service.NewCategoryServiceCache(fake, categoryCache, time.Minute, stats)
+
+	created, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Electronics"})
+	assert.Nil(t, err)
+
+	// the second read is served from the cache
+	for i := 0; i < 2; i++ {
+		category, err := categoryService.FindById(ctx, created.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, "Electronics", category.Name)
+	}
+	assert.Equal(t, int64(1), fake.reads.Load())
+	assert.Equal(t, int64(1), stats.Hits.Load())
+	assert.Equal(t, int64(1), stats.Misses.Load())
+
+	categories, err := categoryService.FindAll(ctx, web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Len(t, categories, 1)
+
+	// writes invalidate the entry and the list
+	_, err = categoryService.Update(ctx, web.CategoryUpdateRequest{Id: created.Id, Name: "Gadgets"})
+	assert.Nil(t, err)
+	category, err := categoryService.FindById(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadgets", category.Name)
+	categories, err = categoryService.FindAll(ctx, web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadgets", categories[0].Name)
+
+	_, err = categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Books"})
+	assert.Nil(t, err)
+	categories, err = categoryService.FindAll(ctx, web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Len(t, categories, 2)
+
+	assert.Nil(t, categoryService.DeleteById(ctx, created.Id))
+	_, err = categoryService.FindById(ctx, created.Id)
+	assert.IsType(t, exception.NotFoundError{}, err)
+	categories, err = categoryService.FindAll(ctx, web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Len(t, categories, 1)
+}
+
+func TestCategoryServiceLRUCache(t *testing.T) {
+	testCategoryServiceCache(t, cache.NewLRUCache(100))
+}
+
+func TestCategoryServiceRedisCache(t *testing.T) {
+	redisCache := cache.NewRedisCache(startRedisStandIn(t), "", 0, 2)
+	defer redisCache.Close()
+	testCategoryServiceCache(t, redisCache)
+}
+
+func testCacheAdd(t *testing.T, store cache.Cache) {
+	ctx := context.Background()
+
+	added, err := store.Add(ctx, "claim", []byte("first"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, added)
+
+	// the key is taken, the value is left alone
+	added, err = store.Add(ctx, "claim", []byte("second"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, added)
+	value, _, _ := store.Get(ctx, "claim")
+	assert.Equal(t, "first", string(value))
+
+	assert.Nil(t, store.Delete(ctx, "claim"))
+	added, err = store.Add(ctx, "claim", []byte("third"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, added)
+}
+
+func TestCacheAdd(t *testing.T) {
+	testCacheAdd(t, cache.NewLRUCache(100))
+
+	redisCache := cache.NewRedisCache(startRedisStandIn(t), "", 0, 2)
+	defer redisCache.Close()
+	testCacheAdd(t, redisCache)
+
+	// an expired entry counts as missing
+	lruCache := cache.NewLRUCache(100)
+	lruCache.Set(context.Background(), "claim", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	added, err := lruCache.Add(context.Background(), "claim", []byte("new"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, added)
+}
+
+func TestCategoryServiceCacheCollapsesMisses(t *testing.T) {
+	fake := newFakeCategoryService()
+	fake.delay = 50 * time.Millisecond
+	categoryService := service.NewCategoryServiceCache(fake, cache.NewLRUCache(100), time.Minute, &cache.Stats{})
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			_, err := categoryService.FindAll(tenant.WithID(context.Background(), "default"), web.CategoryListRequest{})
+			assert.Nil(t, err)
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, int64(1), fake.reads.Load())
+}
+
+func TestCategoryServiceCacheLoadOutlivesCaller(t *testing.T) {
+	fake := newFakeCategoryService()
+	categoryService := service.NewCategoryServiceCache(fake, cache.NewLRUCache(100), time.Minute, &cache.Stats{})
+	ctx := tenant.WithID(context.Background(), "default")
+	created, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Electronics"})
+	assert.Nil(t, err)
+	fake.delay = 50 * time.Millisecond
+
+	// the caller starting the load gives up, the one waiting for it still gets the category
+	impatient, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+		_, err := categoryService.FindById(impatient, created.Id)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}()
+	time.Sleep(time.Millisecond)
+	category, err := categoryService.FindById(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Electronics", category.Name)
+	waitGroup.Wait()
+	assert.Equal(t, int64(1), fake.reads.Load())
+}
+
+func TestCategoryServiceCacheSkipsLoadsOverlappingWrites(t *testing.T) {
+	fake := newFakeCategoryService()
+	categoryService := service.NewCategoryServiceCache(fake, cache.NewLRUCache(100), time.Minute, &cache.Stats{})
+	ctx := tenant.WithID(context.Background(), "default")
+	created, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Electronics"})
+	assert.Nil(t, err)
+	fake.delay = 20 * time.Millisecond
+
+	// the load reads the category before the rename and ends after it
+	loaded := make(chan web.CategoryResponse)
+	go func() {
+		category, _ := categoryService.FindById(ctx, created.Id)
+		loaded <- category
+	}()
+	time.Sleep(5 * time.Millisecond)
+	_, err = categoryService.Update(ctx, web.CategoryUpdateRequest{Id: created.Id, Name: "Gadgets"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Electronics", (<-loaded).Name)
+
+	// so what it read is not stored
+	fake.delay = 0
+	category, err := categoryService.FindById(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadgets", category.Name)
+}
+
+// renumberingService reports every other category as renumbered by a move
+type renumberingService struct {
+	*fakeCategoryService
+}
+
+func (renumbering renumberingService) Move(ctx context.Context, request web.CategoryMoveRequest) (service.CategoryMove, error) {
+	move, err := renumbering.fakeCategoryService.Move(ctx, request)
+	for categoryId := range renumbering.categories {
+		if categoryId != request.Id {
+			move.Renumbered = append(move.Renumbered, categoryId)
+		}
+	}
+	return move, err
+}
+
+func TestCategoryServiceCacheMoveInvalidatesRenumbered(t *testing.T) {
+	fake := newFakeCategoryService()
+	categoryService := service.NewCategoryServiceCache(renumberingService{fake}, cache.NewLRUCache(100), time.Minute, &cache.Stats{})
+	ctx := tenant.WithID(context.Background(), "default")
+	first, _ := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Electronics"})
+	second, _ := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Books"})
+	categoryService.FindById(ctx, first.Id)
+	categoryService.FindById(ctx, second.Id)
+	reads := fake.reads.Load()
+
+	// the other category got a new sort order, so it is read again
+	_, err := categoryService.Move(ctx, web.CategoryMoveRequest{Id: second.Id, BeforeId: &first.Id})
+	assert.Nil(t, err)
+	fake.categories[first.Id] = web.CategoryResponse{Id: first.Id, Name: first.Name, SortOrder: 1024}
+	category, err := categoryService.FindById(ctx, first.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1024, category.SortOrder)
+	assert.Equal(t, reads+1, fake.reads.Load())
+}
+
+func TestLRUCacheEvictionAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	lruCache := cache.NewLRUCache(2)
+
+	lruCache.Set(ctx, "a", []byte("1"), 0)
+	lruCache.Set(ctx, "b", []byte("2"), 0)
+	lruCache.Get(ctx, "a") // "b" is now the least recently used
+	lruCache.Set(ctx, "c", []byte("3"), 0)
+
+	_, ok, _ := lruCache.Get(ctx, "b")
+	assert.False(t, ok)
+	value, ok, _ := lruCache.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+
+	lruCache.Set(ctx, "d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = lruCache.Get(ctx, "d")
+	assert.False(t, ok)
+}
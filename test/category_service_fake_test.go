@@ -0,0 +1,237 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/slug"
+)
+
+// fakeCategoryService keeps categories in memory for tests that do not need MySQL
+type fakeCategoryService struct {
+	mutex      sync.Mutex
+	categories map[int]web.CategoryResponse
+	lastId     int
+	validate   *validator.Validate
+	delay      time.Duration
+	reads      atomic.Int64
+}
+
+func newFakeCategoryService() *fakeCategoryService {
+	return &fakeCategoryService{
+		categories: map[int]web.CategoryResponse{},
+		validate:   validator.New(),
+	}
+}
+
+func (service *fakeCategoryService) Create(ctx context.Context, request web.CategoryCreateRequest) (web.CategoryResponse, error) {
+	if err := service.validate.Struct(request); err != nil {
+		return web.CategoryResponse{}, err
+	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	service.lastId++
+	category := web.CategoryResponse{
+		Id:          service.lastId,
+		Name:        request.Name,
+		Slug:        fakeSlug(request.Name, request.Slug),
+		Type:        request.Type,
+		Attributes:  map[string]any{},
+		Description: request.Description,
+		ImageURL:    request.ImageURL,
+		SortOrder:   request.SortOrder,
+		IsActive:    request.IsActive == nil || *request.IsActive,
+	}
+	if request.Attributes != nil {
+		category.Attributes = request.Attributes
+	}
+	service.categories[category.Id] = category
+	return category, nil
+}
+
+func (service *fakeCategoryService) Update(ctx context.Context, request web.CategoryUpdateRequest) (web.CategoryResponse, error) {
+	if err := service.validate.Struct(request); err != nil {
+		return web.CategoryResponse{}, err
+	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	category, ok := service.categories[request.Id]
+	if !ok {
+		return web.CategoryResponse{}, exception.NewNotFoundError("category not found")
+	}
+	category.Name, category.Slug = request.Name, fakeSlug(request.Name, request.Slug)
+	if request.Type != nil {
+		category.Type = *request.Type
+	}
+	if request.Description != nil {
+		category.Description = *request.Description
+	}
+	if request.ImageURL != nil {
+		category.ImageURL = *request.ImageURL
+	}
+	if request.SortOrder != nil {
+		category.SortOrder = *request.SortOrder
+	}
+	if request.IsActive != nil {
+		category.IsActive = *request.IsActive
+	}
+	if request.Attributes != nil {
+		category.Attributes = request.Attributes
+	}
+	service.categories[category.Id] = category
+	return category, nil
+}
+
+// Move only places the category next to the target, it does not renumber the others.
+func (fake *fakeCategoryService) Move(ctx context.Context, request web.CategoryMoveRequest) (service.CategoryMove, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	category, ok := fake.categories[request.Id]
+	if !ok {
+		return service.CategoryMove{}, exception.NewNotFoundError("category not found")
+	}
+	if request.BeforeId != nil {
+		category.SortOrder = fake.categories[*request.BeforeId].SortOrder - 1
+	}
+	if request.AfterId != nil {
+		category.SortOrder = fake.categories[*request.AfterId].SortOrder + 1
+	}
+	fake.categories[category.Id] = category
+	return service.CategoryMove{Category: category}, nil
+}
+
+func (service *fakeCategoryService) DeleteById(ctx context.Context, categoryId int) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if _, ok := service.categories[categoryId]; !ok {
+		return exception.NewNotFoundError("category not found")
+	}
+	delete(service.categories, categoryId)
+	return nil
+}
+
+// FindById sees the category as it was when the read started, like a query does
+func (service *fakeCategoryService) FindById(ctx context.Context, categoryId int) (web.CategoryResponse, error) {
+	service.mutex.Lock()
+	category, ok := service.categories[categoryId]
+	service.mutex.Unlock()
+
+	if err := service.read(ctx); err != nil {
+		return web.CategoryResponse{}, err
+	}
+	if !ok {
+		return category, exception.NewNotFoundError("category not found")
+	}
+	return category, nil
+}
+
+// FindAll pages and filters by name, type and activity like the repository, the other filters are ignored
+func (service *fakeCategoryService) FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error) {
+	if err := service.read(ctx); err != nil {
+		return nil, err
+	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	categories := make([]web.CategoryResponse, 0, len(service.categories))
+	for _, category := range service.categories {
+		if fakeMatches(category, request) {
+			categories = append(categories, category)
+		}
+	}
+	// in the order of the repository, pages follow the one after AfterSortOrder and AfterId
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].SortOrder != categories[j].SortOrder {
+			return categories[i].SortOrder < categories[j].SortOrder
+		}
+		return categories[i].Id < categories[j].Id
+	})
+	if request.Limit > 0 {
+		categories = slices.DeleteFunc(categories, func(category web.CategoryResponse) bool {
+			return request.AfterId > 0 && (category.SortOrder < request.AfterSortOrder || category.SortOrder == request.AfterSortOrder && category.Id <= request.AfterId)
+		})
+		categories = categories[:min(request.Limit, len(categories))]
+	}
+	return categories, nil
+}
+
+func (service *fakeCategoryService) Count(ctx context.Context, request web.CategoryListRequest) (int, error) {
+	request.AfterSortOrder, request.AfterId, request.Limit = 0, 0, 0
+	categories, err := service.FindAll(ctx, request)
+	return len(categories), err
+}
+
+func fakeMatches(category web.CategoryResponse, request web.CategoryListRequest) bool {
+	switch {
+	case request.NameContains != nil && !strings.Contains(strings.ToLower(category.Name), strings.ToLower(*request.NameContains)):
+		return false
+	case request.Type != nil && category.Type != *request.Type:
+		return false
+	case request.IsActive != nil && category.IsActive != *request.IsActive:
+		return false
+	}
+	return true
+}
+
+func (service *fakeCategoryService) FindByIds(ctx context.Context, categoryIds []int) ([]web.CategoryResponse, error) {
+	if err := service.read(ctx); err != nil {
+		return nil, err
+	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	categories := []web.CategoryResponse{}
+	for _, categoryId := range categoryIds {
+		if category, ok := service.categories[categoryId]; ok {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func (service *fakeCategoryService) FindBySlug(ctx context.Context, categorySlug string) (web.CategoryResponse, error) {
+	if err := service.read(ctx); err != nil {
+		return web.CategoryResponse{}, err
+	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	for _, category := range service.categories {
+		if category.Slug == categorySlug {
+			return category, nil
+		}
+	}
+	return web.CategoryResponse{}, exception.NewNotFoundError("category not found")
+}
+
+// fakeSlug derives slugs without collision suffixes or redirects
+func fakeSlug(name string, requested string) string {
+	if requested != "" {
+		return slug.Make(requested)
+	}
+	return slug.Make(name)
+}
+
+// read counts queries and simulates their latency, which ends early when ctx is done
+func (service *fakeCategoryService) read(ctx context.Context) error {
+	service.reads.Add(1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(service.delay):
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/slug"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+// categoryColumns and categoryRow answer the queries of CategoryRepositoryImpl
+var categoryColumns = []string{"id", "name", "slug", "type", "attributes", "description", "image_url", "sort_order", "is_active", "created_at", "updated_at"}
+
+var categoryCreatedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func categoryRow(id int64, name string, slug string) []driver.Value {
+	return []driver.Value{id, name, slug, "", nil, "", "", int64(0), true, categoryCreatedAt, categoryCreatedAt}
+}
+
+// attributeSchemaColumns answer the lookups of attribute schemas, with no rows unless a test has a schema
+var attributeSchemaColumns = []string{"category_type", "definition", "updated_at"}
+
+// categoryRows answers every category query with one category, and lookups of the slugs in use and of
+// attribute schemas with none
+func categoryRows(name string) func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+	return func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT category_type, definition, updated_at ") {
+			return attributeSchemaColumns, nil, nil
+		}
+		if strings.HasPrefix(query, "SELECT slug ") {
+			return []string{"slug"}, nil, nil
+		}
+		return categoryColumns, [][]driver.Value{categoryRow(1, name, slug.Make(name))}, nil
+	}
+}
+
+func TestReadsGoToReplica(t *testing.T) {
+	primary, primaryDB := newFakeDB()
+	replica, replicaDB := newFakeDB()
+	primary.query = categoryRows("from primary")
+	replica.query = categoryRows("from replica")
+	primary.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		return fakeResult{lastInsertId: 2, rowsAffected: 1}, nil
+	}
+
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(primaryDB, database.NewReplica(replicaDB)), validator.New())
+
+	categories, err := categoryService.FindAll(tenant.WithID(context.Background(), "default"), web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, "from replica", categories[0].Name)
+
+	category, err := categoryService.FindById(tenant.WithID(context.Background(), "default"), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "from replica", category.Name)
+
+	_, err = categoryService.Create(tenant.WithID(context.Background(), "default"), web.CategoryCreateRequest{Name: "Books"})
+	assert.Nil(t, err)
+
+	// reads are read-only transactions, writes stay on the primary
+	assert.Equal(t, []string{
+		"begin read-only", "query SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? ORDER BY sort_order, id", "commit",
+		"begin read-only", "query SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? AND id = ?", "commit",
+	}, replica.Events())
+	assert.Equal(t, []string{
+		"begin",
+		"query SELECT category_type, definition, updated_at FROM category_attribute_schema WHERE tenant_id = ? AND category_type IN (?, '') ORDER BY category_type DESC LIMIT 1",
+		"query SELECT slug FROM category WHERE tenant_id = ? AND (slug = ? OR slug LIKE ?)",
+		"exec INSERT INTO category (tenant_id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		"commit",
+	}, primary.Events())
+}
+
+func TestReadsFallBackToPrimary(t *testing.T) {
+	primary, primaryDB := newFakeDB()
+	replica, replicaDB := newFakeDB()
+	primary.query = categoryRows("from primary")
+	replica.query = categoryRows("from replica")
+	replica.setBeginErr(errors.New("connection refused"))
+
+	replicaDatabase := database.NewReplica(replicaDB)
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(primaryDB, replicaDatabase), validator.New())
+
+	// a failing replica is marked unhealthy and the read goes to the primary
+	categories, err := categoryService.FindAll(tenant.WithID(context.Background(), "default"), web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, "from primary", categories[0].Name)
+	assert.False(t, replicaDatabase.Healthy())
+
+	// once the health check passes, reads go back to the replica
+	replica.setBeginErr(nil)
+	assert.True(t, replicaDatabase.Check(context.Background()))
+	categories, err = categoryService.FindAll(tenant.WithID(context.Background(), "default"), web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, "from replica", categories[0].Name)
+}
+
+func TestReadsWithoutReplica(t *testing.T) {
+	primary, primaryDB := newFakeDB()
+	primary.query = categoryRows("from primary")
+
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(primaryDB, nil), validator.New())
+
+	categories, err := categoryService.FindAll(tenant.WithID(context.Background(), "default"), web.CategoryListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, "from primary", categories[0].Name)
+	assert.Equal(t, "begin read-only", primary.Events()[0])
+}
@@ -0,0 +1,247 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cli"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCLITester(categoryService service.CategoryService, apiKeyService service.APIKeyService) (*cli.CLI, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	commands := cli.NewCLI(categoryService, apiKeyService, nil)
+	commands.Stdout = stdout
+	commands.Stderr = &bytes.Buffer{}
+	commands.Stdin = strings.NewReader("")
+	return commands, stdout
+}
+
+func TestCLICategories(t *testing.T) {
+	fake := newFakeCategoryService()
+	commands, stdout := newCLITester(fake, nil)
+
+	assert.NoError(t, commands.Run(context.Background(), []string{"categories", "create", "Gadget", "Book"}))
+	stdout.Reset()
+	assert.NoError(t, commands.Run(context.Background(), []string{"categories", "list"}))
+	assert.Equal(t, "ID  NAME    SLUG\n1   Gadget  gadget\n2   Book    book\n", stdout.String())
+
+	stdout.Reset()
+	assert.NoError(t, commands.Run(context.Background(), []string{"categories", "list", "-o", "json"}))
+	categories := []web.CategoryResponse{}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &categories))
+	assert.Equal(t, []web.CategoryResponse{{Id: 1, Name: "Gadget", Slug: "gadget", Attributes: map[string]any{}, IsActive: true}, {Id: 2, Name: "Book", Slug: "book", Attributes: map[string]any{}, IsActive: true}}, categories)
+
+	assert.NoError(t, commands.Run(context.Background(), []string{"categories", "delete", "1"}))
+	assert.Len(t, fake.categories, 1)
+
+	// the commands are validated like the API
+	err := commands.Run(context.Background(), []string{"categories", "create", ""})
+	assert.ErrorAs(t, err, &validator.ValidationErrors{})
+	assert.Len(t, fake.categories, 1)
+}
+
+func TestCLISeedImportExport(t *testing.T) {
+	fake := newFakeCategoryService()
+	commands, stdout := newCLITester(fake, nil)
+
+	assert.NoError(t, commands.Run(context.Background(), []string{"seed"}))
+	seeded := len(fake.categories)
+	assert.NotZero(t, seeded)
+
+	// seeding again skips what exists
+	stdout.Reset()
+	assert.NoError(t, commands.Run(context.Background(), []string{"seed", "-o", "json"}))
+	assert.Len(t, fake.categories, seeded)
+	assert.NotContains(t, stdout.String(), `"created"`)
+
+	// a dataset file, replacing what is there
+	stdout.Reset()
+	assert.NoError(t, commands.Run(context.Background(), []string{"seed", "-file", "testdata/one_category.yaml", "-replace"}))
+	assert.Len(t, fake.categories, 1)
+	assert.Contains(t, stdout.String(), "deleted")
+
+	err := commands.Run(context.Background(), []string{"seed", "-dataset", "missing"})
+	assert.ErrorContains(t, err, `unknown dataset "missing"`)
+
+	assert.NoError(t, commands.Run(context.Background(), []string{"seed"}))
+	stdout.Reset()
+	assert.NoError(t, commands.Run(context.Background(), []string{"export"}))
+	exported := stdout.String()
+
+	target := newFakeCategoryService()
+	target.Create(context.Background(), web.CategoryCreateRequest{Name: "Books"})
+	importer, stdout := newCLITester(target, nil)
+	importer.Stdin = strings.NewReader(exported)
+	assert.NoError(t, importer.Run(context.Background(), []string{"import"}))
+	assert.Len(t, target.categories, seeded)
+	assert.Contains(t, stdout.String(), "Books")
+	assert.Contains(t, stdout.String(), "skipped")
+
+	// the details of the categories travel with them
+	imported, _ := target.FindById(context.Background(), 2)
+	assert.Equal(t, "Phones, laptops and accessories", imported.Description)
+	assert.Equal(t, 10, imported.SortOrder)
+}
+
+func TestCLIUsageErrors(t *testing.T) {
+	commands, _ := newCLITester(newFakeCategoryService(), nil)
+
+	for _, args := range [][]string{
+		{},
+		{"frobnicate"},
+		{"categories"},
+		{"categories", "rename"},
+		{"categories", "list", "-o", "yaml"},
+		{"categories", "delete", "abc"},
+		{"categories", "list", "-tenant", "Acme Corp"},
+		{"keys", "issue"},
+		{"keys", "issue", "deploy-bot"},
+		{"keys", "issue", "-tenant", "acme", "-all-tenants", "deploy-bot"},
+		{"migrate", "down", "-steps", "0"},
+	} {
+		err := commands.Run(context.Background(), args)
+		assert.ErrorAs(t, err, &cli.UsageError{}, "%v", args)
+	}
+}
+
+func TestCLIKeys(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		return fakeResult{lastInsertId: 7, rowsAffected: 1}, nil
+	}
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(), database.NewTransactionManager(db, nil), validator.New())
+	commands, stdout := newCLITester(nil, apiKeyService)
+
+	assert.NoError(t, commands.Run(context.Background(), []string{"keys", "issue", "-all-tenants", "-o", "json", "deploy-bot"}))
+	issued := web.APIKeyResponse{}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &issued))
+	assert.Equal(t, 7, issued.Id)
+	assert.Equal(t, "deploy-bot", issued.Name)
+	assert.Len(t, issued.Key, 64)
+
+	// only the hash is stored
+	var stored []driver.NamedValue
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		stored = args
+		return driver.RowsAffected(1), nil
+	}
+	assert.NoError(t, commands.Run(context.Background(), []string{"keys", "revoke", "deploy-bot"}))
+	assert.Equal(t, "deploy-bot", stored[1].Value)
+	assert.Contains(t, fake.Events(), "exec UPDATE api_key SET revoked_at = ? WHERE name = ? AND revoked_at IS NULL")
+
+	// the issued key is found by its hash
+	var lookedUp any
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		lookedUp = args[0].Value
+		return []string{"id", "name", "tenant_id", "created_at"}, [][]driver.Value{{int64(7), "deploy-bot", "acme", time.Now()}}, nil
+	}
+	apiKey, ok, err := apiKeyService.Lookup(context.Background(), issued.Key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "deploy-bot", apiKey.Name)
+	assert.Equal(t, "acme", apiKey.TenantId)
+	assert.Len(t, lookedUp, 64)
+	assert.NotEqual(t, issued.Key, lookedUp)
+
+	fake.query = nil
+	_, ok, err = apiKeyService.Lookup(context.Background(), "unknown")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// stubAPIKeys accepts the keys it holds, err fails every lookup
+type stubAPIKeys struct {
+	keys map[string]web.APIKeyResponse
+	err  error
+}
+
+func (stub stubAPIKeys) Lookup(ctx context.Context, apiKey string) (web.APIKeyResponse, bool, error) {
+	issued, ok := stub.keys[apiKey]
+	return issued, ok && stub.err == nil, stub.err
+}
+
+func TestAuthMiddlewareIssuedKeys(t *testing.T) {
+	var principal middleware.Principal
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		principal, _ = middleware.PrincipalFromContext(request.Context())
+	})
+	authMiddleware := middleware.NewAuthMiddleware(handler, "RAHASIA")
+	authMiddleware.Keys = stubAPIKeys{keys: map[string]web.APIKeyResponse{"issued": {Name: "deploy-bot"}}}
+
+	send := func(apiKey string) int {
+		request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+		request.Header.Set("X-API-Key", apiKey)
+		recorder := httptest.NewRecorder()
+		authMiddleware.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("issued"))
+	assert.Equal(t, middleware.Principal{Name: "deploy-bot", Method: "api_key", TenantId: "default"}, principal)
+	assert.Equal(t, http.StatusOK, send("RAHASIA"))
+	assert.Equal(t, "api-key", principal.Name)
+	assert.Equal(t, http.StatusUnauthorized, send("revoked"))
+
+	// a failing lookup is an error, not a rejected key
+	authMiddleware.Keys = stubAPIKeys{err: context.DeadlineExceeded}
+	assert.Equal(t, http.StatusGatewayTimeout, send("issued"))
+}
+
+// countingAPIKeys counts the lookups that reach the api_key table
+type countingAPIKeys struct {
+	lookups *int
+}
+
+func (stub countingAPIKeys) Lookup(ctx context.Context, apiKey string) (web.APIKeyResponse, bool, error) {
+	*stub.lookups++
+	return web.APIKeyResponse{Name: "deploy-bot"}, apiKey == "issued", nil
+}
+
+func TestAuthMiddlewareFailureLimit(t *testing.T) {
+	lookups := 0
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {})
+	authMiddleware := middleware.NewAuthMiddleware(handler, "RAHASIA")
+	authMiddleware.Keys = countingAPIKeys{lookups: &lookups}
+	authMiddleware.FailureLimit = middleware.RateLimit{Rate: 0.001, Burst: 3}
+
+	send := func(apiKey string, remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("X-API-Key", apiKey)
+		recorder := httptest.NewRecorder()
+		authMiddleware.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// successes cost nothing, every made up key costs the client a token
+	assert.Equal(t, http.StatusOK, send("issued", "192.0.2.1:1000").Code)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, send(fmt.Sprintf("made-up-%d", i), "192.0.2.1:1000").Code)
+	}
+	assert.Equal(t, 4, lookups)
+
+	// then the client is turned away before its key is looked up, from any port
+	recorder := send("made-up-3", "192.0.2.1:2000")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, send("issued", "192.0.2.1:2000").Code)
+	assert.Equal(t, 4, lookups)
+
+	// other clients are not affected
+	assert.Equal(t, http.StatusOK, send("issued", "198.51.100.7:1000").Code)
+}
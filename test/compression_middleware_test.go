@@ -0,0 +1,154 @@
+package test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCompressionTester(categories int) (http.Handler, *fakeCategoryService) {
+	fake := newFakeCategoryService()
+	for i := 0; i < categories; i++ {
+		fake.categories[i+1] = web.CategoryResponse{Id: i + 1, Name: fmt.Sprintf("Category number %d", i+1)}
+		fake.lastId = i + 1
+	}
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	return middleware.NewCompressionMiddleware(router, middleware.CompressionConfig{
+		Encodings:           []string{"br", "gzip", "deflate"},
+		MinSize:             1024,
+		MaxDecompressedSize: 1024,
+	}), fake
+}
+
+func compressionRequest(handler http.Handler, method string, body io.Reader, header map[string]string) *http.Response {
+	request := httptest.NewRequest(method, "http://localhost/api/categories", body)
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Result()
+}
+
+func TestResponseCompression(t *testing.T) {
+	handler, _ := newCompressionTester(100)
+
+	decoders := map[string]func(io.Reader) io.Reader{
+		"gzip": func(reader io.Reader) io.Reader {
+			gzipReader, err := gzip.NewReader(reader)
+			if err != nil {
+				panic(err)
+			}
+			return gzipReader
+		},
+		"deflate": func(reader io.Reader) io.Reader { return flate.NewReader(reader) },
+		"br":      func(reader io.Reader) io.Reader { return brotli.NewReader(reader) },
+	}
+	for encoding, decoder := range decoders {
+		response := compressionRequest(handler, http.MethodGet, nil, map[string]string{"Accept-Encoding": encoding})
+		assert.Equal(t, encoding, response.Header.Get("Content-Encoding"))
+		assert.Contains(t, response.Header.Values("Vary"), "Accept-Encoding")
+
+		body, err := io.ReadAll(decoder(response.Body))
+		assert.Nil(t, err)
+		assert.Contains(t, string(body), "Category number 100")
+	}
+
+	// the server preference breaks ties, quality wins otherwise
+	response := compressionRequest(handler, http.MethodGet, nil, map[string]string{"Accept-Encoding": "gzip, br"})
+	assert.Equal(t, "br", response.Header.Get("Content-Encoding"))
+	response = compressionRequest(handler, http.MethodGet, nil, map[string]string{"Accept-Encoding": "gzip, br;q=0.5"})
+	assert.Equal(t, "gzip", response.Header.Get("Content-Encoding"))
+	response = compressionRequest(handler, http.MethodGet, nil, map[string]string{"Accept-Encoding": "identity"})
+	assert.Empty(t, response.Header.Get("Content-Encoding"))
+}
+
+func TestSmallResponsesAreNotCompressed(t *testing.T) {
+	handler, _ := newCompressionTester(1)
+
+	response := compressionRequest(handler, http.MethodGet, nil, map[string]string{"Accept-Encoding": "gzip"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Empty(t, response.Header.Get("Content-Encoding"))
+	assert.Contains(t, response.Header.Values("Vary"), "Accept-Encoding")
+
+	body, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "Category number 1")
+}
+
+func TestCompressedRequestBody(t *testing.T) {
+	handler, fake := newCompressionTester(0)
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	gzipWriter.Write([]byte(`{"name":"Books"}`))
+	gzipWriter.Close()
+
+	response := compressionRequest(handler, http.MethodPost, &compressed, map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "Books", fake.categories[1].Name)
+
+	response = compressionRequest(handler, http.MethodPost, strings.NewReader(`{"name":"Books"}`), map[string]string{"Content-Encoding": "compress"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, response.StatusCode)
+
+	response = compressionRequest(handler, http.MethodPost, strings.NewReader(`{"name":"Books"}`), map[string]string{"Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func TestCorruptCompressedRequestBody(t *testing.T) {
+	handler, fake := newCompressionTester(0)
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	gzipWriter.Write([]byte(`{"name":"Books"}`))
+	gzipWriter.Close()
+	valid := compressed.Bytes()
+
+	// a wrong CRC-32 in the trailer
+	badChecksum := bytes.Clone(valid)
+	badChecksum[len(badChecksum)-8] ^= 0xff
+	response := compressionRequest(handler, http.MethodPost, bytes.NewReader(badChecksum), map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	// the stream stops before its trailer
+	response = compressionRequest(handler, http.MethodPost, bytes.NewReader(valid[:len(valid)-6]), map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	// a deflate block type that does not exist
+	response = compressionRequest(handler, http.MethodPost, bytes.NewReader([]byte{0xff, 0xff, 0xff}), map[string]string{"Content-Type": "application/json", "Content-Encoding": "deflate"})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response = compressionRequest(handler, http.MethodPost, strings.NewReader("not brotli at all"), map[string]string{"Content-Type": "application/json", "Content-Encoding": "br"})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Empty(t, fake.categories)
+}
+
+func TestCompressedRequestBodyIsCapped(t *testing.T) {
+	handler, fake := newCompressionTester(0)
+
+	// a few bytes of gzip expanding far beyond the cap
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	gzipWriter.Write([]byte(`{"name":"`))
+	gzipWriter.Write(bytes.Repeat([]byte("a"), 1<<20))
+	gzipWriter.Write([]byte(`"}`))
+	gzipWriter.Close()
+	assert.Less(t, compressed.Len(), 4096)
+
+	response := compressionRequest(handler, http.MethodPost, &compressed, map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+	assert.Empty(t, fake.categories)
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// clearConfigEnv hides variables that other tests load from .env.test
+func clearConfigEnv(t *testing.T) {
+	for _, key := range []string{"DB_USERNAME", "DB_PASSWORD", "DB_HOST", "DB_PORT", "DB_NAME", "SERVER_PORT", "API_KEY", "JWT_SECRET", "DEFAULT_TENANT", "DEFAULT_LOCALE", "CONFIG_FILE", "ENV_FILE"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	clearConfigEnv(t)
+	configFile := writeConfigFile(t, "config.yaml", `
+server:
+  port: 4000
+  read_timeout: 5s
+database:
+  username: file-user
+  host: db.internal
+  name: go_restful_api
+  max_open_conns: 50
+auth:
+  api_key: file-key
+rate_limit:
+  routes:
+    POST /api/categories: "1:5"
+cors:
+  allowed_origins:
+    - https://admin.example.com
+`)
+	envFile := writeConfigFile(t, ".env", "DB_USERNAME=dotenv-user\nDB_NAME=dotenv_db\n")
+
+	// the environment wins over the .env file, flags win over everything
+	t.Setenv("DB_NAME", "env_db")
+	t.Setenv("API_KEY", "env-key")
+
+	cfg, err := config.Load([]string{
+		"-config", configFile,
+		"-env-file", envFile,
+		"-auth.api_key", "flag-key",
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 4000, cfg.Server.Port)
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, "dotenv-user", cfg.Database.Username)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, "env_db", cfg.Database.Name)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, "flag-key", cfg.Auth.APIKey)
+	assert.Equal(t, []string{"https://admin.example.com"}, cfg.Cors.AllowedOrigins)
+	assert.Equal(t, config.RateLimit{Rate: 1, Burst: 5}, cfg.RateLimit.Routes["POST /api/categories"])
+
+	// defaults
+	assert.Equal(t, 5, cfg.Database.MaxIdleConns)
+	assert.Equal(t, 10*time.Minute, cfg.Database.ConnMaxIdleTime)
+	assert.Equal(t, time.Hour, cfg.Database.ConnMaxLifetime)
+}
+
+func TestConfigJSONFile(t *testing.T) {
+	clearConfigEnv(t)
+	configFile := writeConfigFile(t, "config.json", `{
+		"database": {"username": "root", "name": "go_restful_api"},
+		"auth": {"api_key": "json-key"},
+		"rate_limit": {"default": "10:20"}
+	}`)
+
+	cfg, err := config.Load([]string{"-config", configFile, "-env-file", ""})
+	assert.Nil(t, err)
+	assert.Equal(t, "json-key", cfg.Auth.APIKey)
+	assert.Equal(t, config.RateLimit{Rate: 10, Burst: 20}, cfg.RateLimit.Default)
+}
+
+func TestConfigValidation(t *testing.T) {
+	clearConfigEnv(t)
+	_, err := config.Load([]string{
+		"-env-file", "",
+		"-server.port", "70000",
+		"-database.max_idle_conns", "30",
+		"-database.isolation_level", "snapshot",
+		"-auth.jwt_secret", "short",
+		"-auth.default_tenant", "Acme Corp",
+		"-locale.default", "en-us",
+		"-cors.allowed_origins", "https://admin.example.com,*",
+		"-cors.allow_credentials",
+		"-compression.max_decompressed_size", "10485760",
+		"-idempotency.lock_ttl", "10s",
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "server.port (SERVER_PORT): must be between 1 and 65535, got 70000")
+	assert.Contains(t, err.Error(), "database.username (DB_USERNAME): is required")
+	assert.Contains(t, err.Error(), "database.max_idle_conns (DB_MAX_IDLE_CONNS): must not exceed database.max_open_conns (20), got 30")
+	assert.Contains(t, err.Error(), `database.isolation_level (DB_ISOLATION_LEVEL): must be one of default, read_uncommitted, read_committed, repeatable_read or serializable, got "snapshot"`)
+	assert.Contains(t, err.Error(), "auth.api_key (API_KEY): is required")
+	assert.Contains(t, err.Error(), "auth.jwt_secret (JWT_SECRET): must be at least 32 bytes")
+	assert.Contains(t, err.Error(), `auth.default_tenant (DEFAULT_TENANT): must be lowercase letters, digits, - and _, got "Acme Corp"`)
+	assert.Contains(t, err.Error(), `locale.default (DEFAULT_LOCALE): must be a canonical BCP 47 language tag such as en or pt-BR, got "en-us"`)
+	assert.Contains(t, err.Error(), "cors.allow_credentials (CORS_ALLOW_CREDENTIALS): cannot be true while cors.allowed_origins (CORS_ALLOWED_ORIGINS) has *")
+	assert.Contains(t, err.Error(), "timeout.default (REQUEST_TIMEOUT): must be shorter than idempotency.lock_ttl (10s), got 15s")
+	assert.Contains(t, err.Error(), "compression.max_decompressed_size (COMPRESSION_MAX_DECOMPRESSED_SIZE): must not exceed server.max_body_size (1048576), got 10485760")
+}
+
+func TestConfigInvalidValue(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("DB_PORT", "not-a-number")
+
+	_, err := config.Load([]string{"-env-file", ""})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `environment variable DB_PORT: invalid value "not-a-number"`)
+
+	configFile := writeConfigFile(t, "config.yaml", "server:\n  prot: 3000\n")
+	_, err = config.Load([]string{"-config", configFile, "-env-file", ""})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `unknown setting "server.prot"`)
+}
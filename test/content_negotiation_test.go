@@ -0,0 +1,139 @@
+package test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/codec"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func negotiationRequest(handler http.Handler, method string, target string, body io.Reader, contentType string, accept string) (*http.Response, []byte) {
+	request := httptest.NewRequest(method, "http://localhost"+target, body)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(err)
+	}
+	return response, responseBody
+}
+
+func TestNegotiateAccept(t *testing.T) {
+	for accept, expected := range map[string]codec.Codec{
+		"":                                   codec.JSON,
+		"*/*":                                codec.JSON,
+		"application/xml":                    codec.XML,
+		"text/xml; charset=utf-8":            codec.XML,
+		"application/msgpack":                codec.MessagePack,
+		"application/cbor, application/json": codec.CBOR,
+		"application/json;q=0.5, application/cbor": codec.CBOR,
+		"text/html, application/xml;q=0.9":         codec.XML,
+	} {
+		negotiated, err := codec.Negotiate(accept)
+		assert.Nil(t, err, accept)
+		assert.Equal(t, expected, negotiated, accept)
+	}
+
+	_, err := codec.Negotiate("text/html, application/json;q=0")
+	assert.ErrorIs(t, err, codec.ErrNotAcceptable)
+}
+
+func TestResponseFormats(t *testing.T) {
+	fake := newFakeCategoryService()
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+
+	response, body := negotiationRequest(router, http.MethodPost, "/api/categories", strings.NewReader(`{"name":"Books"}`), "application/json", "application/xml")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/xml", response.Header.Get("Content-Type"))
+	assert.Equal(t, "Accept", response.Header.Get("Vary"))
+	var xmlResponse struct {
+		Code int    `xml:"code"`
+		Name string `xml:"data>name"`
+	}
+	assert.Nil(t, xml.Unmarshal(body, &xmlResponse))
+	assert.Equal(t, 200, xmlResponse.Code)
+	assert.Equal(t, "Books", xmlResponse.Name)
+
+	response, body = negotiationRequest(router, http.MethodGet, "/api/categories/1", nil, "", "application/msgpack")
+	assert.Equal(t, "application/msgpack", response.Header.Get("Content-Type"))
+	var msgpackResponse map[string]any
+	assert.Nil(t, msgpack.Unmarshal(body, &msgpackResponse))
+	assert.Equal(t, "OK", msgpackResponse["status"])
+	assert.Equal(t, "Books", msgpackResponse["data"].(map[string]any)["name"])
+
+	response, body = negotiationRequest(router, http.MethodGet, "/api/categories", nil, "", "application/cbor")
+	assert.Equal(t, "application/cbor", response.Header.Get("Content-Type"))
+	var cborResponse struct {
+		Code int `json:"code"`
+		Data []struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	assert.Nil(t, cbor.Unmarshal(body, &cborResponse))
+	assert.Equal(t, 200, cborResponse.Code)
+	assert.Equal(t, "Books", cborResponse.Data[0].Name)
+
+	// errors follow the Accept header too
+	response, body = negotiationRequest(router, http.MethodGet, "/api/categories/404", nil, "", "application/xml")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, "application/xml", response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "<status>NOT FOUND</status>")
+}
+
+func TestRequestFormats(t *testing.T) {
+	fake := newFakeCategoryService()
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+
+	response, _ := negotiationRequest(router, http.MethodPost, "/api/categories", strings.NewReader(`<category><name>Books</name></category>`), "application/xml", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	msgpackBody, err := msgpack.Marshal(map[string]string{"name": "Games"})
+	assert.Nil(t, err)
+	response, _ = negotiationRequest(router, http.MethodPost, "/api/categories", bytes.NewReader(msgpackBody), "application/msgpack", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	cborBody, err := cbor.Marshal(map[string]string{"name": "Music"})
+	assert.Nil(t, err)
+	response, _ = negotiationRequest(router, http.MethodPut, "/api/categories/1", bytes.NewReader(cborBody), "application/cbor", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	names := []string{fake.categories[1].Name, fake.categories[2].Name}
+	assert.Equal(t, []string{"Music", "Games"}, names)
+}
+
+func TestUnsupportedMediaTypes(t *testing.T) {
+	fake := newFakeCategoryService()
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+
+	response, body := negotiationRequest(router, http.MethodGet, "/api/categories", nil, "", "text/html")
+	assert.Equal(t, http.StatusNotAcceptable, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `"status":"NOT ACCEPTABLE"`)
+
+	response, body = negotiationRequest(router, http.MethodPost, "/api/categories", strings.NewReader("name=Books"), "application/x-www-form-urlencoded", "")
+	assert.Equal(t, http.StatusUnsupportedMediaType, response.StatusCode)
+	assert.Contains(t, string(body), `"status":"UNSUPPORTED MEDIA TYPE"`)
+
+	// nothing is created when the response cannot be rendered
+	response, _ = negotiationRequest(router, http.MethodPost, "/api/categories", strings.NewReader(`{"name":"Books"}`), "application/json", "text/html")
+	assert.Equal(t, http.StatusNotAcceptable, response.StatusCode)
+	assert.Empty(t, fake.categories)
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCorsTester() http.Handler {
+	// preflight requests never reach the controller
+	router := app.NewRouter(controller.NewCategoryController(nil), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	authMiddleware := middleware.NewAuthMiddleware(router, "test-api-key")
+	corsMiddleware := middleware.NewCorsMiddleware(authMiddleware, router, middleware.CorsConfig{
+		AllowedOrigins:   []string{"https://admin.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "X-API-Key"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	router.GlobalOPTIONS = http.HandlerFunc(corsMiddleware.Preflight)
+	return corsMiddleware
+}
+
+func TestCorsPreflightSkipsAuth(t *testing.T) {
+	handler := newCorsTester()
+
+	request := httptest.NewRequest(http.MethodOptions, "http://localhost/api/categories/1", nil)
+	request.Header.Set("Origin", "https://shop.example.org")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "content-type, x-api-key")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Equal(t, "https://shop.example.org", response.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", response.Header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "content-type, x-api-key", response.Header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", response.Header.Get("Access-Control-Max-Age"))
+	assert.Contains(t, response.Header.Get("Access-Control-Allow-Methods"), "PUT")
+}
+
+func TestCorsPreflightOfSlugRoute(t *testing.T) {
+	handler := newCorsTester()
+
+	// the lookups by slug are served by a router of their own
+	request := httptest.NewRequest(http.MethodOptions, "http://localhost/api/categories/by-slug/gadget", nil)
+	request.Header.Set("Origin", "https://admin.example.com")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+	request.Header.Set("Access-Control-Request-Headers", "x-api-key")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Equal(t, "https://admin.example.com", response.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, response.Header.Get("Access-Control-Allow-Methods"), "GET")
+	assert.Equal(t, "x-api-key", response.Header.Get("Access-Control-Allow-Headers"))
+
+	// methods the slug route does not have are refused
+	request = httptest.NewRequest(http.MethodOptions, "http://localhost/api/categories/by-slug/gadget", nil)
+	request.Header.Set("Origin", "https://admin.example.com")
+	request.Header.Set("Access-Control-Request-Method", "DELETE")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+}
+
+func TestCorsPreflightRejected(t *testing.T) {
+	handler := newCorsTester()
+
+	// unknown origin
+	request := httptest.NewRequest(http.MethodOptions, "http://localhost/api/categories", nil)
+	request.Header.Set("Origin", "https://evil.example.com")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+	assert.Empty(t, recorder.Result().Header.Get("Access-Control-Allow-Origin"))
+
+	// method that the route does not have
+	request = httptest.NewRequest(http.MethodOptions, "http://localhost/api/categories", nil)
+	request.Header.Set("Origin", "https://admin.example.com")
+	request.Header.Set("Access-Control-Request-Method", "DELETE")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+
+	// header that is not allowed
+	request = httptest.NewRequest(http.MethodOptions, "http://localhost/api/categories", nil)
+	request.Header.Set("Origin", "https://admin.example.com")
+	request.Header.Set("Access-Control-Request-Method", "POST")
+	request.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+}
+
+func TestCorsActualRequest(t *testing.T) {
+	handler := newCorsTester()
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost/api/categories", nil)
+	request.Header.Set("Origin", "https://admin.example.com")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	// authentication still applies, but the browser can read the error
+	response := recorder.Result()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Equal(t, "https://admin.example.com", response.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "RateLimit-Remaining", response.Header.Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", response.Header.Get("Vary"))
+}
+
+func TestCorsWildcardWithoutCredentials(t *testing.T) {
+	handler := middleware.NewCorsMiddleware(http.NotFoundHandler(), http.NotFoundHandler(), middleware.CorsConfig{
+		AllowedOrigins:   []string{"*", "https://admin.example.com"},
+		AllowCredentials: true,
+	})
+
+	send := func(origin string) *http.Response {
+		request := httptest.NewRequest(http.MethodGet, "http://localhost/api/categories", nil)
+		request.Header.Set("Origin", origin)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Result()
+	}
+
+	// any site may read responses, only the listed one with the visitor's credentials
+	response := send("https://evil.example.net")
+	assert.Equal(t, "https://evil.example.net", response.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, response.Header.Get("Access-Control-Allow-Credentials"))
+	response = send("https://admin.example.com")
+	assert.Equal(t, "true", response.Header.Get("Access-Control-Allow-Credentials"))
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// fakeDB is a scriptable database/sql driver, so services can be tested without MySQL.
+// Every call is recorded in events.
+type fakeDB struct {
+	mutex    sync.Mutex
+	events   []string
+	beginErr error
+	pingErr  error
+	query    func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error)
+	exec     func(query string, args []driver.NamedValue) (driver.Result, error)
+}
+
+var (
+	fakeDBs      sync.Map
+	fakeDBNextId atomic.Int64
+)
+
+func init() {
+	sql.Register("fake", fakeDriver{})
+}
+
+func newFakeDB() (*fakeDB, *sql.DB) {
+	fake := &fakeDB{}
+	name := fmt.Sprintf("fake-%d", fakeDBNextId.Add(1))
+	fakeDBs.Store(name, fake)
+
+	db, err := sql.Open("fake", name)
+	if err != nil {
+		panic(err)
+	}
+	return fake, db
+}
+
+func (fake *fakeDB) record(event string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.events = append(fake.events, event)
+}
+
+func (fake *fakeDB) Events() []string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return append([]string{}, fake.events...)
+}
+
+func (fake *fakeDB) setBeginErr(err error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.beginErr = err
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("fake database %q does not exist", name)
+	}
+	return &fakeConn{db: fake.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver does not support prepared statements")
+}
+
+func (conn *fakeConn) Close() error {
+	return nil
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	return conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (conn *fakeConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	conn.db.mutex.Lock()
+	beginErr := conn.db.beginErr
+	conn.db.mutex.Unlock()
+	if beginErr != nil {
+		return nil, beginErr
+	}
+
+	event := "begin"
+	if options.ReadOnly {
+		event += " read-only"
+	}
+	if isolation := sql.IsolationLevel(options.Isolation); isolation != sql.LevelDefault {
+		event += " " + strings.ToLower(isolation.String())
+	}
+	conn.db.record(event)
+	return &fakeTx{db: conn.db}, nil
+}
+
+func (conn *fakeConn) Ping(ctx context.Context) error {
+	conn.db.mutex.Lock()
+	defer conn.db.mutex.Unlock()
+	return conn.db.pingErr
+}
+
+func (conn *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn.db.record("query " + query)
+	if conn.db.query == nil {
+		return &fakeRows{}, nil
+	}
+	columns, values, err := conn.db.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, values: values}, nil
+}
+
+func (conn *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn.db.record("exec " + query)
+	if conn.db.exec == nil {
+		return driver.RowsAffected(1), nil
+	}
+	return conn.db.exec(query, args)
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.record("commit")
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.record("rollback")
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	index   int
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if rows.index >= len(rows.values) {
+		return io.EOF
+	}
+	copy(dest, rows.values[rows.index])
+	rows.index++
+	return nil
+}
+
+// fakeResult is returned by exec for inserts
+type fakeResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (result fakeResult) LastInsertId() (int64, error) {
+	return result.lastInsertId, nil
+}
+
+func (result fakeResult) RowsAffected() (int64, error) {
+	return result.rowsAffected, nil
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/fixtures"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixturesParse(t *testing.T) {
+	dataset, err := fixtures.Named(os.DirFS("testdata"), "two_categories")
+	assert.NoError(t, err)
+	assert.Equal(t, fixtures.Dataset{Name: "two_categories", Categories: []fixtures.Category{{Name: "Electronics"}, {Name: "Fashion"}}}, dataset)
+
+	dataset, err = fixtures.Parse("blank", ".yaml", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, dataset.Categories)
+
+	_, err = fixtures.Parse("typo", ".yaml", []byte("categories:\n  - nmae: Books\n"))
+	assert.Error(t, err)
+	_, err = fixtures.Parse("twice", ".json", []byte(`{"categories": [{"name": "Books"}, {"name": "Books"}]}`))
+	assert.EqualError(t, err, `dataset twice: category "Books" is listed twice`)
+	_, err = fixtures.Parse("csv", ".csv", nil)
+	assert.Error(t, err)
+
+	// the shipped datasets parse
+	for _, name := range fixtures.Names(fixtures.FS) {
+		dataset, err := fixtures.Named(fixtures.FS, name)
+		assert.NoError(t, err, name)
+		assert.NotEmpty(t, dataset.Categories, name)
+	}
+	_, err = fixtures.Named(fixtures.FS, "missing")
+	assert.ErrorContains(t, err, "the datasets are default, demo")
+}
+
+func TestFixturesLoadIsIdempotent(t *testing.T) {
+	fake := newFakeCategoryService()
+	fake.Create(context.Background(), web.CategoryCreateRequest{Name: "Fashion"})
+	loader := fixtures.NewLoader(fake)
+	dataset := fixtures.Dataset{Name: "shop", Categories: []fixtures.Category{{Name: "Electronics"}, {Name: "Fashion"}}}
+
+	report, err := loader.Load(context.Background(), dataset)
+	assert.NoError(t, err)
+	assert.Equal(t, fixtures.Report{
+		{Id: 2, Name: "Electronics", Action: "created"},
+		{Id: 1, Name: "Fashion", Action: "skipped"},
+	}, report)
+	assert.Equal(t, 2, report.Id("Electronics"))
+
+	report, err = loader.Load(context.Background(), dataset)
+	assert.NoError(t, err)
+	assert.Len(t, fake.categories, 2)
+	for _, result := range report {
+		assert.Equal(t, "skipped", result.Action)
+	}
+
+	// datasets are validated by the service
+	report, err = loader.Load(context.Background(), fixtures.Dataset{Name: "bad", Categories: []fixtures.Category{{Name: "Books"}, {Name: ""}}})
+	assert.ErrorAs(t, err, &validator.ValidationErrors{})
+	assert.Equal(t, "created", report[0].Action)
+}
+
+func TestFixturesReplace(t *testing.T) {
+	fake := newFakeCategoryService()
+	for _, name := range []string{"Fashion", "Toys", "Fashion"} {
+		fake.Create(context.Background(), web.CategoryCreateRequest{Name: name})
+	}
+
+	report, err := fixtures.NewLoader(fake).Replace(context.Background(), fixtures.Dataset{Categories: []fixtures.Category{{Name: "Electronics"}, {Name: "Fashion"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, fixtures.Report{
+		{Id: 2, Name: "Toys", Action: "deleted"},
+		{Id: 3, Name: "Fashion", Action: "deleted"},
+		{Id: 4, Name: "Electronics", Action: "created"},
+		{Id: 1, Name: "Fashion", Action: "skipped"},
+	}, report)
+	assert.Equal(t, map[int]web.CategoryResponse{1: {Id: 1, Name: "Fashion", Slug: "fashion", Attributes: map[string]any{}, IsActive: true}, 4: {Id: 4, Name: "Electronics", Slug: "electronics", Attributes: map[string]any{}, IsActive: true}}, fake.categories)
+}
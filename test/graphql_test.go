@@ -0,0 +1,331 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/gql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+type graphqlResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newGraphQLTester(names ...string) (*gql.Handler, *fakeCategoryService) {
+	fake := newFakeCategoryService()
+	for _, name := range names {
+		fake.Create(context.Background(), web.CategoryCreateRequest{Name: name})
+	}
+	handler, err := gql.NewHandler(fake, gql.Config{MaxDepth: 10, MaxComplexity: 2500})
+	if err != nil {
+		panic(err)
+	}
+	return handler, fake
+}
+
+func graphqlRequest(handler http.Handler, query string, variables map[string]any) (*http.Response, graphqlResponse) {
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	response, responseBody := negotiationRequest(handler, http.MethodPost, "/graphql", strings.NewReader(string(body)), "application/json", "")
+
+	decoded := graphqlResponse{}
+	if err := json.Unmarshal(responseBody, &decoded); err != nil {
+		panic(err)
+	}
+	return response, decoded
+}
+
+func TestGraphQLCategoryQueries(t *testing.T) {
+	handler, _ := newGraphQLTester("Gadget", "Book")
+
+	response, body := graphqlRequest(handler, `{ category(id: 1) { id name slug } missing: category(id: 404) { id } }`, nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"id": float64(1), "name": "Gadget", "slug": "gadget"}, body.Data["category"])
+	assert.Nil(t, body.Data["missing"])
+}
+
+func TestGraphQLBatchesCategoryLookups(t *testing.T) {
+	handler, fake := newGraphQLTester("A", "B", "C")
+
+	reads := fake.reads.Load()
+	_, body := graphqlRequest(handler, `{ a: category(id: 1) { name } b: category(id: 2) { name } c: category(id: 3) { name } again: category(id: 1) { id } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, "C", body.Data["c"].(map[string]any)["name"])
+
+	// four fields, one FindByIds
+	assert.Equal(t, int64(1), fake.reads.Load()-reads)
+}
+
+func TestGraphQLCategoriesPagination(t *testing.T) {
+	handler, _ := newGraphQLTester("Apple", "Banana", "Avocado", "Cherry", "Apricot")
+	query := `query ($after: String) {
+		categories(nameContains: "a", first: 2, after: $after) {
+			nodes { name }
+			totalCount
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	var names []string
+	var after any
+	for pages := 0; pages < 10; pages++ {
+		_, body := graphqlRequest(handler, query, map[string]any{"after": after})
+		assert.Empty(t, body.Errors)
+		categories := body.Data["categories"].(map[string]any)
+		assert.Equal(t, float64(4), categories["totalCount"])
+		for _, node := range categories["nodes"].([]any) {
+			names = append(names, node.(map[string]any)["name"].(string))
+		}
+		pageInfo := categories["pageInfo"].(map[string]any)
+		if !pageInfo["hasNextPage"].(bool) {
+			break
+		}
+		after = pageInfo["endCursor"]
+	}
+	assert.Equal(t, []string{"Apple", "Banana", "Avocado", "Apricot"}, names)
+
+	_, body := graphqlRequest(handler, `{ categories(ids: [4, 404, 2]) { nodes { name } } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, []any{map[string]any{"name": "Banana"}, map[string]any{"name": "Cherry"}}, body.Data["categories"].(map[string]any)["nodes"])
+}
+
+func TestGraphQLCategoriesFollowSortOrder(t *testing.T) {
+	handler, fake := newGraphQLTester("Apple", "Banana", "Cherry", "Durian")
+	ctx := context.Background()
+	beforeId, afterId := 1, 4
+	fake.Move(ctx, web.CategoryMoveRequest{Id: 3, BeforeId: &beforeId})
+	fake.Move(ctx, web.CategoryMoveRequest{Id: 1, AfterId: &afterId})
+
+	// pages continue from the sort order and id of the cursor, as moved categories are listed
+	query := `query ($after: String) { categories(first: 1, after: $after) { nodes { name } pageInfo { hasNextPage endCursor } } }`
+	var names []string
+	var after any
+	for pages := 0; pages < 10; pages++ {
+		_, body := graphqlRequest(handler, query, map[string]any{"after": after})
+		assert.Empty(t, body.Errors)
+		categories := body.Data["categories"].(map[string]any)
+		for _, node := range categories["nodes"].([]any) {
+			names = append(names, node.(map[string]any)["name"].(string))
+		}
+		pageInfo := categories["pageInfo"].(map[string]any)
+		if !pageInfo["hasNextPage"].(bool) {
+			break
+		}
+		after = pageInfo["endCursor"]
+	}
+	assert.Equal(t, []string{"Cherry", "Banana", "Durian", "Apple"}, names)
+
+	// so are the pages of categories asked for by id
+	_, body := graphqlRequest(handler, `{ categories(ids: [1, 2, 3], first: 2) { nodes { name } pageInfo { endCursor } } }`, nil)
+	assert.Empty(t, body.Errors)
+	categories := body.Data["categories"].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"name": "Cherry"}, map[string]any{"name": "Banana"}}, categories["nodes"])
+	_, body = graphqlRequest(handler, `query ($after: String) { categories(ids: [1, 2, 3], first: 2, after: $after) { nodes { name } } }`, map[string]any{"after": categories["pageInfo"].(map[string]any)["endCursor"]})
+	assert.Equal(t, []any{map[string]any{"name": "Apple"}}, body.Data["categories"].(map[string]any)["nodes"])
+
+	_, body = graphqlRequest(handler, `{ categories(after: "Mg") { totalCount } }`, nil)
+	assert.Equal(t, "invalid after cursor", body.Errors[0].Message)
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	handler, fake := newGraphQLTester()
+
+	_, body := graphqlRequest(handler, `mutation { createCategory(input: {name: "Gadget"}) { id name } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, "Gadget", body.Data["createCategory"].(map[string]any)["name"])
+
+	_, body = graphqlRequest(handler, `mutation ($name: String!) { updateCategory(id: 1, input: {name: $name}) { name } }`, map[string]any{"name": "Gadgets"})
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, "Gadgets", fake.categories[1].Name)
+
+	_, body = graphqlRequest(handler, `mutation { createCategory(input: {name: ""}) { id } }`, nil)
+	assert.Len(t, body.Errors, 1)
+	assert.Equal(t, "invalid fields", body.Errors[0].Message)
+	assert.Equal(t, "BAD_USER_INPUT", body.Errors[0].Extensions["code"])
+
+	_, body = graphqlRequest(handler, `mutation { deleteCategory(id: 1) }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, true, body.Data["deleteCategory"])
+
+	_, body = graphqlRequest(handler, `mutation { deleteCategory(id: 1) }`, nil)
+	assert.Equal(t, "NOT_FOUND", body.Errors[0].Extensions["code"])
+	assert.Empty(t, fake.categories)
+}
+
+func TestGraphQLCategoryDetails(t *testing.T) {
+	handler, _ := newGraphQLTester("Gadget")
+
+	_, body := graphqlRequest(handler, `mutation { createCategory(input: {name: "Book", description: "Paper", sortOrder: 2, isActive: false}) { description sortOrder isActive createdAt } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"description": "Paper", "sortOrder": float64(2), "isActive": false, "createdAt": "0001-01-01T00:00:00Z"}, body.Data["createCategory"])
+
+	// updates leave out what they do not change
+	_, body = graphqlRequest(handler, `mutation { updateCategory(id: 2, input: {name: "Books"}) { name isActive } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"name": "Books", "isActive": false}, body.Data["updateCategory"])
+
+	_, body = graphqlRequest(handler, `{ categories(isActive: true) { nodes { name } totalCount } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"nodes": []any{map[string]any{"name": "Gadget"}}, "totalCount": float64(1)}, body.Data["categories"])
+}
+
+func TestGraphQLCategoryAttributes(t *testing.T) {
+	handler, _ := newGraphQLTester("Gadget")
+
+	_, body := graphqlRequest(handler, `mutation { createCategory(input: {name: "Phone", type: "product", attributes: {color: "black", sizes: [1, 2.5], refurbished: false}}) { type attributes } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"type": "product", "attributes": map[string]any{"color": "black", "sizes": []any{float64(1), 2.5}, "refurbished": false}}, body.Data["createCategory"])
+
+	// variables carry attributes as they are
+	_, body = graphqlRequest(handler, `mutation ($attributes: JSON) { updateCategory(id: 2, input: {name: "Phone", attributes: $attributes}) { attributes } }`, map[string]any{"attributes": map[string]any{"color": "white"}})
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"attributes": map[string]any{"color": "white"}}, body.Data["updateCategory"])
+
+	_, body = graphqlRequest(handler, `mutation { updateCategory(id: 2, input: {name: "Phone", attributes: "white"}) { attributes } }`, nil)
+	assert.Equal(t, "attributes must be an object", body.Errors[0].Message)
+
+	_, body = graphqlRequest(handler, `{ categories(type: "product") { nodes { name } } }`, nil)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"nodes": []any{map[string]any{"name": "Phone"}}}, body.Data["categories"])
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	handler, _ := newGraphQLTester("Gadget")
+
+	response, body := graphqlRequest(handler, `{ categories(first: 1000) { nodes { id name } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "query complexity 3001 exceeds the limit of 2500", body.Errors[0].Message)
+
+	// variables count as well as literals
+	response, _ = graphqlRequest(handler, `query ($first: Int) { categories(first: $first) { nodes { id name } } }`, map[string]any{"first": 1000})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	// and so do the defaults of variables the request leaves out
+	response, body = graphqlRequest(handler, `query ($n: Int = 1000) { categories(first: $n) { nodes { id name } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "query complexity 3001 exceeds the limit of 2500", body.Errors[0].Message)
+	response, _ = graphqlRequest(handler, `query ($n: Int = 1000) { categories(first: $n) { nodes { id name } } }`, map[string]any{"n": 2})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	// every id costs a load, however small the page
+	ids := make([]int, 1001)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	handler.Config.MaxComplexity = 1000
+	response, body = graphqlRequest(handler, `query ($ids: [Int!]) { categories(ids: $ids) { nodes { id } } }`, map[string]any{"ids": ids[:1000]})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "query complexity 1101 exceeds the limit of 1000", body.Errors[0].Message)
+	response, body = graphqlRequest(handler, `query ($ids: [Int!] = [`+strings.Trim(strings.Repeat("1, ", 1000), ", ")+`]) { categories(ids: $ids) { nodes { id } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "query complexity 1101 exceeds the limit of 1000", body.Errors[0].Message)
+	handler.Config.MaxComplexity = 2500
+	_, body = graphqlRequest(handler, `query ($ids: [Int!]) { categories(ids: $ids) { nodes { id } } }`, map[string]any{"ids": ids})
+	assert.Equal(t, "at most 1000 ids are allowed", body.Errors[0].Message)
+	assert.Equal(t, "BAD_USER_INPUT", body.Errors[0].Extensions["code"])
+
+	handler.Config.MaxDepth = 2
+	response, body = graphqlRequest(handler, `{ ...page } fragment page on Query { categories { pageInfo { hasNextPage } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "query depth 3 exceeds the limit of 2", body.Errors[0].Message)
+}
+
+func TestGraphQLCategoriesPageInTheDatabase(t *testing.T) {
+	fake, db := newFakeDB()
+	var queried [][]driver.NamedValue
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		queried = append(queried, args)
+		if strings.HasPrefix(query, "SELECT COUNT(*) ") {
+			return []string{"COUNT(*)"}, [][]driver.Value{{int64(7)}}, nil
+		}
+		return categoryColumns, [][]driver.Value{categoryRow(3, "Gadget", "gadget"), categoryRow(5, "Gizmo", "gizmo")}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	handler, err := gql.NewHandler(categoryService, gql.Config{MaxDepth: 10, MaxComplexity: 2500})
+	assert.NoError(t, err)
+	send := func(query string) graphqlResponse {
+		body, _ := json.Marshal(map[string]any{"query": query})
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request.WithContext(tenant.WithID(request.Context(), "acme")))
+		decoded := graphqlResponse{}
+		json.Unmarshal(recorder.Body.Bytes(), &decoded)
+		return decoded
+	}
+
+	// the filters and the page go into the query, the table is not read as a whole
+	body := send(`{ categories(nameContains: "g_", isActive: true, first: 1, after: "MDoy") { nodes { id } pageInfo { hasNextPage } } }`)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"nodes": []any{map[string]any{"id": float64(3)}}, "pageInfo": map[string]any{"hasNextPage": true}}, body.Data["categories"])
+	assert.Equal(t, []string{
+		"begin read-only", "query SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? AND name LIKE ? AND is_active = ? AND (sort_order > ? OR (sort_order = ? AND id > ?)) ORDER BY sort_order, id LIMIT ?", "commit",
+	}, fake.Events())
+	values := []any{}
+	for _, arg := range queried[0] {
+		values = append(values, arg.Value)
+	}
+	assert.Equal(t, []any{"acme", `%g\_%`, true, int64(0), int64(0), int64(2), int64(2)}, values)
+
+	// totalCount is counted by the database when it is asked for
+	body = send(`{ categories(nameContains: "g_") { totalCount } }`)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"totalCount": float64(7)}, body.Data["categories"])
+	assert.Contains(t, fake.Events(), "query SELECT COUNT(*) FROM category WHERE tenant_id = ? AND name LIKE ?")
+}
+
+func TestGraphQLRequestErrors(t *testing.T) {
+	handler, _ := newGraphQLTester("Gadget")
+
+	response, body := graphqlRequest(handler, `{ category(id: 1) { colour } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Contains(t, body.Errors[0].Message, `Cannot query field "colour"`)
+
+	response, _ = graphqlRequest(handler, `{ category(id: 1) {`, nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	// queries may use GET, mutations may not
+	response, responseBody := negotiationRequest(handler, http.MethodGet, "/graphql?query="+url.QueryEscape(`{ category(id: 1) { name } }`), nil, "", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, string(responseBody), `"name":"Gadget"`)
+
+	response, _ = negotiationRequest(handler, http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { deleteCategory(id: 1) }`), nil, "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestFindByIdsIsOneQuery(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = categoryRows("Gadget")
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+
+	categories, err := categoryService.FindByIds(tenant.WithID(context.Background(), "default"), []int{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Len(t, categories, 1)
+
+	// no ids, no query
+	categories, err = categoryService.FindByIds(tenant.WithID(context.Background(), "default"), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, categories)
+
+	assert.Equal(t, []string{
+		"begin read-only", "query SELECT id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at FROM category WHERE tenant_id = ? AND id IN (?, ?, ?)", "commit",
+		"begin read-only", "commit",
+	}, fake.Events())
+}
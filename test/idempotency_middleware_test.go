@@ -0,0 +1,182 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdempotencyTester(ttl time.Duration) (http.Handler, *fakeCategoryService) {
+	fake := newFakeCategoryService()
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	handler := middleware.NewIdempotencyMiddleware(router, cache.NewLRUCache(100), middleware.IdempotencyConfig{TTL: ttl, Size: 100})
+	return handler, fake
+}
+
+func postCategory(handler http.Handler, idempotencyKey string, body string) (*http.Response, map[string]any) {
+	request := httptest.NewRequest(http.MethodPost, "http://localhost/api/categories", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		request.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(err)
+	}
+	var decoded map[string]any
+	json.Unmarshal(responseBody, &decoded)
+	return response, decoded
+}
+
+func TestIdempotencyKeyReplaysResponse(t *testing.T) {
+	handler, fake := newIdempotencyTester(time.Minute)
+
+	first, firstBody := postCategory(handler, "key-1", `{"name":"Books"}`)
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+	assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+
+	// the retry gets the same category instead of creating another one
+	second, secondBody := postCategory(handler, "key-1", `{"name":"Books"}`)
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Equal(t, "true", second.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, "application/json", second.Header.Get("Content-Type"))
+	assert.Equal(t, firstBody, secondBody)
+	assert.Len(t, fake.categories, 1)
+
+	// a different key is a new request
+	postCategory(handler, "key-2", `{"name":"Books"}`)
+	assert.Len(t, fake.categories, 2)
+
+	// requests without a key are not deduplicated
+	postCategory(handler, "", `{"name":"Books"}`)
+	postCategory(handler, "", `{"name":"Books"}`)
+	assert.Len(t, fake.categories, 4)
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	handler, fake := newIdempotencyTester(time.Minute)
+
+	postCategory(handler, "key-1", `{"name":"Books"}`)
+	response, responseBody := postCategory(handler, "key-1", `{"name":"Games"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	assert.Equal(t, "UNPROCESSABLE ENTITY", responseBody["status"])
+	assert.Len(t, fake.categories, 1)
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	handler, fake := newIdempotencyTester(20 * time.Millisecond)
+
+	postCategory(handler, "key-1", `{"name":"Books"}`)
+	time.Sleep(40 * time.Millisecond)
+
+	// once the key expired the request is handled again, even with another body
+	response, _ := postCategory(handler, "key-1", `{"name":"Games"}`)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Empty(t, response.Header.Get("Idempotent-Replayed"))
+	assert.Len(t, fake.categories, 2)
+}
+
+func TestIdempotencyKeyIsClaimedInTheStore(t *testing.T) {
+	// two instances share the store, like two servers sharing Redis
+	store := cache.NewRedisCache(startRedisStandIn(t), "", 0, 4)
+	defer store.Close()
+
+	started, finish := make(chan struct{}), make(chan struct{})
+	slow := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-finish
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(`{"code":200}`))
+	})
+	first := middleware.NewIdempotencyMiddleware(slow, store, middleware.IdempotencyConfig{TTL: time.Minute, LockTTL: time.Minute})
+	second := middleware.NewIdempotencyMiddleware(http.NotFoundHandler(), store, middleware.IdempotencyConfig{TTL: time.Minute, LockTTL: time.Minute})
+
+	done := make(chan *http.Response)
+	go func() {
+		response, _ := postCategory(first, "key-1", `{"name":"Books"}`)
+		done <- response
+	}()
+	<-started
+
+	// the other instance sees the claim while the first request runs
+	response, responseBody := postCategory(second, "key-1", `{"name":"Books"}`)
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+	assert.Equal(t, "CONFLICT", responseBody["status"])
+	response, _ = postCategory(second, "key-1", `{"name":"Games"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+
+	close(finish)
+	assert.Equal(t, http.StatusOK, (<-done).StatusCode)
+
+	// and replays its response once it is done
+	response, responseBody = postCategory(second, "key-1", `{"name":"Books"}`)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "true", response.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, float64(200), responseBody["code"])
+}
+
+func TestIdempotencyClaimIsReleasedOnServerErrors(t *testing.T) {
+	failures := 1
+	handler := middleware.NewIdempotencyMiddleware(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if failures > 0 {
+			failures--
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.WriteHeader(http.StatusCreated)
+	}), cache.NewLRUCache(100), middleware.IdempotencyConfig{TTL: time.Minute, LockTTL: time.Minute})
+
+	response, _ := postCategory(handler, "key-1", `{"name":"Books"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	// the retry runs again instead of waiting for the claim to expire
+	response, _ = postCategory(handler, "key-1", `{"name":"Books"}`)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Empty(t, response.Header.Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyFingerprintCoversContentHeaders(t *testing.T) {
+	handler, fake := newIdempotencyTester(time.Minute)
+	handler = middleware.NewCompressionMiddleware(handler, middleware.CompressionConfig{MaxDecompressedSize: 1024})
+
+	postCategory(handler, "key-1", `{"name":"Books"}`)
+
+	// the same bytes sent as another media type are another request
+	request := httptest.NewRequest(http.MethodPost, "http://localhost/api/categories", strings.NewReader(`{"name":"Books"}`))
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("Idempotency-Key", "key-1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	// so is the same body sent compressed, although it is decompressed before the fingerprint is taken
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	gzipWriter.Write([]byte(`{"name":"Books"}`))
+	gzipWriter.Close()
+	request = httptest.NewRequest(http.MethodPost, "http://localhost/api/categories", &compressed)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Encoding", "gzip")
+	request.Header.Set("Idempotency-Key", "key-1")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Len(t, fake.categories, 1)
+}
@@ -0,0 +1,211 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/locale"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleParse(t *testing.T) {
+	// every locale is followed by its parents, in order of preference
+	assert.Equal(t, []string{"fr-CA", "fr", "en"}, locale.Parse("fr-CA, en;q=0.5"))
+	assert.Equal(t, []string{"de", "en"}, locale.Parse("en;q=0.2, de"))
+	assert.Equal(t, []string{"es"}, locale.Parse("es, fr;q=0, *"))
+	assert.Empty(t, locale.Parse("not a language tag!"))
+
+	// the default locale ends the chain, the names themselves are in it
+	assert.Equal(t, []string{"fr-CA", "fr", "en"}, locale.Fallbacks([]string{"fr-CA", "fr", "en", "de"}, "en"))
+	assert.Equal(t, []string{"de"}, locale.Fallbacks([]string{"de"}, "en"))
+	assert.Equal(t, []string{"en"}, locale.Fallbacks([]string{"en", "fr"}, "en"))
+	assert.Empty(t, locale.Fallbacks(nil, "en"))
+}
+
+func TestLocalizedValidationMessages(t *testing.T) {
+	validate := app.NewValidator()
+	err := validate.Struct(web.CategoryTranslationSaveRequest{CategoryId: 1, Locale: "not a tag"})
+	validationErrors := err.(validator.ValidationErrors)
+
+	// without Accept-Language the message stays as it always was
+	assert.Equal(t, "invalid fields", locale.ValidationMessage(context.Background(), validationErrors))
+
+	message := locale.ValidationMessage(locale.WithPreferences(context.Background(), locale.Parse("fr-CA")), validationErrors)
+	assert.Contains(t, message, "name est un champ obligatoire")
+	assert.Contains(t, message, "; ")
+
+	message = locale.ValidationMessage(locale.WithPreferences(context.Background(), locale.Parse("ja, de;q=0.8")), validationErrors)
+	assert.Contains(t, message, "name ist ein Pflichtfeld")
+}
+
+func TestCategoryServiceLocalized(t *testing.T) {
+	fake, db := newFakeDB()
+	var query string
+	var args []driver.NamedValue
+	fake.query = func(q string, a []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		query, args = q, a
+		return []string{"category_id", "locale", "name"}, [][]driver.Value{
+			{int64(1), "fr", "Gadget (fr)"},
+			{int64(1), "fr-CA", "Gadget (fr-CA)"},
+			{int64(2), "fr", "Livre"},
+			{int64(3), "en", "Toy (en)"},
+		}, nil
+	}
+	translations := service.NewCategoryTranslationService(repository.NewCategoryTranslationRepository(), repository.NewCategoryRepository(), database.NewTransactionManager(db, nil), validator.New())
+	categories := newFakeCategoryService()
+	categoryService := service.NewCategoryServiceLocalized(categories, translations, "en")
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	for _, name := range []string{"Gadget", "Book", "Toy"} {
+		_, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: name})
+		assert.NoError(t, err)
+	}
+
+	// names are read as written when no locale is preferred
+	responses, err := categoryService.FindAll(ctx, web.CategoryListRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadget", responses[0].Name)
+	assert.Empty(t, fake.Events())
+
+	// a translation in the default locale is used too
+	responses, err = categoryService.FindAll(locale.WithPreferences(ctx, locale.Parse("en, fr")), web.CategoryListRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadget", responses[0].Name)
+	assert.Equal(t, web.CategoryResponse{Id: 3, Name: "Toy (en)", Slug: "toy", Attributes: map[string]any{}, IsActive: true, Locale: "en"}, responses[2])
+
+	// the first preferred locale with a translation wins, the others keep their name
+	responses, err = categoryService.FindAll(locale.WithPreferences(ctx, locale.Parse("fr-CA, en;q=0.5")), web.CategoryListRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []web.CategoryResponse{
+		{Id: 1, Name: "Gadget (fr-CA)", Slug: "gadget", Attributes: map[string]any{}, IsActive: true, Locale: "fr-CA"},
+		{Id: 2, Name: "Livre", Slug: "book", Attributes: map[string]any{}, IsActive: true, Locale: "fr"},
+		{Id: 3, Name: "Toy (en)", Slug: "toy", Attributes: map[string]any{}, IsActive: true, Locale: "en"},
+	}, responses)
+	assert.Equal(t, "SELECT category_translation.category_id, category_translation.locale, category_translation.name FROM category_translation JOIN category ON category.id = category_translation.category_id WHERE category.tenant_id = ? AND category_translation.category_id IN (?, ?, ?) AND category_translation.locale IN (?, ?, ?)", query)
+	values := []any{}
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	assert.Equal(t, []any{"acme", int64(1), int64(2), int64(3), "fr-CA", "fr", "en"}, values)
+
+	response, err := categoryService.FindById(locale.WithPreferences(ctx, locale.Parse("fr")), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, web.CategoryResponse{Id: 2, Name: "Livre", Slug: "book", Attributes: map[string]any{}, IsActive: true, Locale: "fr"}, response)
+}
+
+func TestCategoryTranslationServiceCache(t *testing.T) {
+	fake, db := newFakeDB()
+	var queries []string
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		queries = append(queries, query)
+		return []string{"category_id", "locale", "name"}, [][]driver.Value{{int64(1), "fr", "Gadget (fr)"}}, nil
+	}
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		return fakeResult{rowsAffected: 1}, nil
+	}
+	translations := service.NewCategoryTranslationService(repository.NewCategoryTranslationRepository(), repository.NewCategoryRepository(), database.NewTransactionManager(db, nil), validator.New())
+	stats := &cache.Stats{}
+	categoryCache := cache.NewLRUCache(100)
+	translationCache := service.NewCategoryTranslationServiceCache(translations, categoryCache, time.Minute, stats)
+	categoryService := service.NewCategoryServiceLocalized(service.NewCategoryServiceCache(newFakeCategoryService(), categoryCache, time.Minute, stats), translationCache, "en")
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	_, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Gadget"})
+	assert.NoError(t, err)
+
+	// the translations of the tenant are loaded once, cached reads do not query them again
+	ctx = locale.WithPreferences(ctx, locale.Parse("fr"))
+	for range 3 {
+		response, err := categoryService.FindById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Gadget (fr)", response.Name)
+	}
+	assert.Equal(t, []string{"SELECT category_translation.category_id, category_translation.locale, category_translation.name FROM category_translation JOIN category ON category.id = category_translation.category_id WHERE category.tenant_id = ?"}, queries)
+
+	// deleting a translation drops them
+	err = translationCache.Delete(ctx, 1, "fr")
+	assert.NoError(t, err)
+	_, err = categoryService.FindById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, queries, 2)
+}
+
+func TestCategoryTranslationController(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT category_translation") {
+			return []string{"category_id", "locale", "name"}, [][]driver.Value{{int64(1), "fr", "Gadget"}}, nil
+		}
+		if args[1].Value == int64(1) {
+			return categoryColumns, [][]driver.Value{categoryRow(1, "Gadget", "gadget")}, nil
+		}
+		return categoryColumns, nil, nil
+	}
+	var saved []driver.NamedValue
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		saved = args
+		return fakeResult{rowsAffected: 1}, nil
+	}
+	validate := app.NewValidator()
+	categoryTranslationService := service.NewCategoryTranslationService(repository.NewCategoryTranslationRepository(), repository.NewCategoryRepository(), database.NewTransactionManager(db, nil), validate)
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(nil), controller.NewCategoryTranslationController(categoryTranslationService), controller.NewCategoryAttributeSchemaController(nil)))
+	handler := middleware.NewLocaleMiddleware(router)
+
+	send := func(method, target, body, acceptLanguage string) (*httptest.ResponseRecorder, map[string]any) {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		if acceptLanguage != "" {
+			request.Header.Set("Accept-Language", acceptLanguage)
+		}
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		var responseBody map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &responseBody)
+		return recorder, responseBody
+	}
+
+	// the locale is stored in its canonical form
+	recorder, body := send(http.MethodPut, "/api/categories/1/translations/fr-ca", `{"name": "Gadget"}`, "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, map[string]any{"category_id": float64(1), "locale": "fr-CA", "name": "Gadget"}, body["data"])
+	assert.Equal(t, []any{"fr-CA", "Gadget", "acme", int64(1), "Gadget"}, []any{saved[0].Value, saved[1].Value, saved[2].Value, saved[3].Value, saved[4].Value})
+	assert.Contains(t, recorder.Header().Values("Vary"), "Accept-Language")
+
+	recorder, body = send(http.MethodGet, "/api/categories/1/translations", "", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []any{map[string]any{"category_id": float64(1), "locale": "fr", "name": "Gadget"}}, body["data"])
+
+	recorder, _ = send(http.MethodDelete, "/api/categories/1/translations/fr", "", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// the category has to exist
+	recorder, _ = send(http.MethodGet, "/api/categories/2/translations", "", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder, _ = send(http.MethodPut, "/api/categories/2/translations/fr", `{"name": "Gadget"}`, "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// validation errors are told in the preferred language
+	recorder, body = send(http.MethodPut, "/api/categories/1/translations/fr", `{"name": ""}`, "es")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "name es un campo requerido", body["data"])
+	recorder, body = send(http.MethodPut, "/api/categories/1/translations/not_a_tag!", `{"name": "Gadget"}`, "fr")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, body["data"], "locale")
+}
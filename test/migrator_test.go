@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/migrations"
+	"github.com/rozanlaudzai/go-mysql-restful-api/slug"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	loaded, err := database.LoadMigrations(fstest.MapFS{
+		"0002_add_b.up.sql":    {Data: []byte("ALTER TABLE a ADD b INT;")},
+		"0002_add_b.down.sql":  {Data: []byte("ALTER TABLE a DROP b;")},
+		"0001_create_a.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		"README.md":            {Data: []byte("not a migration")},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, 1, loaded[0].Version)
+	assert.Equal(t, "create_a", loaded[0].Name)
+	assert.Equal(t, "ALTER TABLE a DROP b;", loaded[1].Down)
+
+	_, err = database.LoadMigrations(fstest.MapFS{"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")}})
+	assert.Error(t, err)
+
+	// the embedded migrations load
+	loaded, err = database.LoadMigrations(migrations.FS)
+	assert.NoError(t, err)
+	assert.Equal(t, "create_category", loaded[0].Name)
+
+	// categories that had a slug before it was reserved are renamed by a migration
+	for _, reserved := range slug.Reserved {
+		assert.True(t, slices.ContainsFunc(loaded, func(migration database.Migration) bool {
+			return strings.Contains(migration.Up, "WHERE slug = '"+reserved+"'")
+		}), reserved)
+	}
+}
+
+func TestMigratorUpDownStatus(t *testing.T) {
+	fake, db := newFakeDB()
+	appliedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"version", "applied_at"}, [][]driver.Value{{int64(1), appliedAt}}, nil
+	}
+	migrator := database.NewMigrator(db, []database.Migration{
+		{Version: 1, Name: "create_a", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "create_b", Up: "CREATE TABLE b (\n    id INT\n);\nCREATE INDEX b_id ON b (id);\n", Down: "DROP TABLE b;"},
+	})
+
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, appliedAt, *statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	// only the pending migration runs, one statement at a time
+	done, err := migrator.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	events := fake.Events()
+	assert.Equal(t, []string{
+		"query SELECT version, applied_at FROM schema_migration",
+		"exec CREATE TABLE b (\n    id INT\n)",
+		"exec CREATE INDEX b_id ON b (id)",
+		"exec INSERT INTO schema_migration (version, name, applied_at) VALUES (?, ?, ?)",
+	}, events[len(events)-4:])
+
+	done, err = migrator.Down(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "create_a", done[0].Name)
+	events = fake.Events()
+	assert.Equal(t, []string{
+		"exec DROP TABLE a",
+		"exec DELETE FROM schema_migration WHERE version = ?",
+	}, events[len(events)-2:])
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateAPISpec = flag.Bool("update", false, "rewrite ../apispec.json from the registered routes")
+
+func generatedAPISpec() []byte {
+	routes := app.CategoryRoutes(controller.NewCategoryController(newFakeCategoryService()), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	body, err := openapi.Marshal(app.NewOpenAPIDocument(routes))
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+// TestOpenAPIDrift fails when apispec.json no longer matches the code,
+// run `go test ./test -run TestOpenAPIDrift -update` to regenerate it
+func TestOpenAPIDrift(t *testing.T) {
+	generated := generatedAPISpec()
+	if *updateAPISpec {
+		if err := os.WriteFile("../apispec.json", generated, 0o644); err != nil {
+			panic(err)
+		}
+	}
+
+	checkedIn, err := os.ReadFile("../apispec.json")
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, string(checkedIn), string(generated), "apispec.json is out of date, regenerate it with -update")
+}
+
+func TestOpenAPISchemaFromValidateTags(t *testing.T) {
+	type Item struct {
+		Name     string   `json:"name" validate:"required,min=2,max=20"`
+		Quantity int      `json:"quantity" validate:"gte=1,lt=100"`
+		Kind     string   `json:"kind,omitempty" validate:"omitempty,oneof=book game"`
+		Email    string   `json:"email" validate:"email"`
+		Tags     []string `json:"tags" validate:"max=5,dive,min=1"`
+		Internal string   `json:"-"`
+	}
+
+	document := openapi.Generate(openapi.Document{}, []openapi.Operation{{
+		Method:  http.MethodPost,
+		Path:    "/items/:itemId",
+		ID:      "createItem",
+		Request: Item{},
+		Errors:  []int{http.StatusBadRequest},
+	}})
+
+	schema := document.Components.Schemas["Item"]
+	assert.Equal(t, []string{"name"}, schema.Required)
+	assert.Equal(t, 2, *schema.Properties["name"].MinLength)
+	assert.Equal(t, 20, *schema.Properties["name"].MaxLength)
+	assert.Equal(t, 1.0, *schema.Properties["quantity"].Minimum)
+	assert.Equal(t, 100.0, *schema.Properties["quantity"].Maximum)
+	assert.True(t, schema.Properties["quantity"].ExclusiveMaximum)
+	assert.Equal(t, []any{"book", "game"}, schema.Properties["kind"].Enum)
+	assert.Equal(t, "email", schema.Properties["email"].Format)
+	assert.Equal(t, 5, *schema.Properties["tags"].MaxItems)
+	assert.NotContains(t, schema.Properties, "Internal")
+
+	operation := document.Paths["/items/{itemId}"]["post"]
+	assert.Equal(t, "itemId", operation.Parameters[0].Name)
+	assert.Equal(t, "path", operation.Parameters[0].In)
+	assert.Equal(t, "#/components/schemas/Item", operation.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/responses/BadRequest", operation.Responses["400"].Ref)
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	router := app.NewRouter(controller.NewCategoryController(newFakeCategoryService()), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost/openapi.json", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Equal(t, string(generatedAPISpec()), string(body))
+
+	var document map[string]any
+	assert.Nil(t, json.Unmarshal(body, &document))
+	assert.Equal(t, "3.0.3", document["openapi"])
+
+	request = httptest.NewRequest(http.MethodGet, "http://localhost/docs", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `href="/openapi.json"`)
+
+	// the page loads no script from another origin, the Redoc bundle is embedded
+	assert.NotContains(t, recorder.Body.String(), "https://")
+
+	bundle := httptest.NewRecorder()
+	router.ServeHTTP(bundle, httptest.NewRequest(http.MethodGet, "http://localhost"+openapi.BundlePath, nil))
+	if strings.Contains(recorder.Body.String(), `src="`+openapi.BundlePath+`"`) {
+		assert.Equal(t, http.StatusOK, bundle.Code)
+		assert.Equal(t, "text/javascript; charset=utf-8", bundle.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), `spec-url="/openapi.json"`)
+	} else {
+		assert.Equal(t, http.StatusNotFound, bundle.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/stretchr/testify/assert"
+)
+
+// newOpenAPIValidationTester fails the test on every response not matching apispec.json
+func newOpenAPIValidationTester(t *testing.T, handler http.Handler) *middleware.OpenAPIValidationMiddleware {
+	return newValidationTester(t, handler, middleware.OpenAPIValidationConfig{ValidateRequests: true, ValidateResponses: true})
+}
+
+// newResponseValidationTester fails t on every response apispec.json does not describe, the
+// requests reach the handler unchecked so tests can still send invalid ones to the controllers
+func newResponseValidationTester(t *testing.T, handler http.Handler) http.Handler {
+	return newValidationTester(t, handler, middleware.OpenAPIValidationConfig{ValidateResponses: true})
+}
+
+func newValidationTester(t *testing.T, handler http.Handler, config middleware.OpenAPIValidationConfig) *middleware.OpenAPIValidationMiddleware {
+	spec, err := os.ReadFile("../apispec.json")
+	if err != nil {
+		panic(err)
+	}
+	validationMiddleware, err := middleware.NewOpenAPIValidationMiddleware(handler, spec, config)
+	if err != nil {
+		panic(err)
+	}
+	validationMiddleware.OnResponseError = func(request *http.Request, err error) {
+		t.Errorf("invalid response to %v %v: %v", request.Method, request.URL.Path, err)
+	}
+	return validationMiddleware
+}
+
+func TestOpenAPIValidationAcceptsDocumentedTraffic(t *testing.T) {
+	handler := newOpenAPIValidationTester(t, app.NewRouter(controller.NewCategoryController(newFakeCategoryService()), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	response, _ := negotiationRequest(handler, http.MethodPost, "/api/categories", strings.NewReader(`{"name":"Gadget"}`), "application/json", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	for _, request := range []struct {
+		method     string
+		target     string
+		body       string
+		statusCode int
+	}{
+		{http.MethodGet, "/api/categories", "", http.StatusOK},
+		{http.MethodGet, "/api/categories?is_active=true&created_after=2026-01-02T03:04:05Z", "", http.StatusOK},
+		{http.MethodGet, "/api/categories/1", "", http.StatusOK},
+		{http.MethodPut, "/api/categories/1", `{"name":"Gadgets"}`, http.StatusOK},
+		{http.MethodGet, "/api/categories/404", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/categories/1", "", http.StatusOK},
+	} {
+		contentType := ""
+		if request.body != "" {
+			contentType = "application/json"
+		}
+		response, body := negotiationRequest(handler, request.method, request.target, strings.NewReader(request.body), contentType, "")
+		assert.Equal(t, request.statusCode, response.StatusCode, "%v %v: %s", request.method, request.target, body)
+	}
+
+	// other media types are left to the decoders and not checked against the JSON schemas
+	response, _ = negotiationRequest(handler, http.MethodGet, "/api/categories/404", nil, "", "application/xml")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestOpenAPIValidationRejectsInvalidRequests(t *testing.T) {
+	handler := newOpenAPIValidationTester(t, app.NewRouter(controller.NewCategoryController(newFakeCategoryService()), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	for _, request := range []struct {
+		method  string
+		target  string
+		body    string
+		message string
+	}{
+		{http.MethodGet, "/api/categories/0", "", "path parameter categoryId"},
+		{http.MethodGet, "/api/categories/abc", "", `parameter "categoryId" in path`},
+		{http.MethodPost, "/api/categories", `{"name":""}`, `request body field "name"`},
+		{http.MethodPost, "/api/categories", `{}`, "request body"},
+		{http.MethodPut, "/api/categories/1", `{"name":1}`, `request body field "name"`},
+	} {
+		contentType := ""
+		if request.body != "" {
+			contentType = "application/json"
+		}
+		response, body := negotiationRequest(handler, request.method, request.target, strings.NewReader(request.body), contentType, "")
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, "%v %v", request.method, request.target)
+
+		webResponse := web.WebResponse{}
+		assert.NoError(t, json.Unmarshal(body, &webResponse))
+		assert.Equal(t, "BAD REQUEST", webResponse.Status)
+		assert.Contains(t, webResponse.Data, request.message, "%v %v", request.method, request.target)
+	}
+}
+
+func TestOpenAPIValidationReportsInvalidResponses(t *testing.T) {
+	var reported []string
+	stub := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		if request.Method == http.MethodDelete {
+			writer.WriteHeader(http.StatusTeapot) // status not in the document
+			writer.Write([]byte(`{"code":418,"status":"I'M A TEAPOT","data":""}`))
+			return
+		}
+		writer.Write([]byte(`{"code":200,"status":"OK","data":{"id":"one","name":"Gadget"}}`))
+	})
+	handler := newOpenAPIValidationTester(t, stub)
+	handler.OnResponseError = func(request *http.Request, err error) {
+		reported = append(reported, request.Method)
+	}
+
+	// the response is still sent, validation only reports it
+	response, body := negotiationRequest(handler, http.MethodGet, "/api/categories/1", nil, "", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, string(body), `"id":"one"`)
+
+	response, _ = negotiationRequest(handler, http.MethodDelete, "/api/categories/1", nil, "", "")
+	assert.Equal(t, http.StatusTeapot, response.StatusCode)
+
+	// routes missing from the document pass through unchecked
+	negotiationRequest(handler, http.MethodGet, "/debug/vars", nil, "", "")
+
+	assert.Equal(t, []string{http.MethodGet, http.MethodDelete}, reported)
+}
+
+func TestOpenAPIValidationDisabled(t *testing.T) {
+	spec, err := os.ReadFile("../apispec.json")
+	if err != nil {
+		panic(err)
+	}
+	handler, err := middleware.NewOpenAPIValidationMiddleware(app.NewRouter(controller.NewCategoryController(newFakeCategoryService()), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)), spec, middleware.OpenAPIValidationConfig{})
+	if err != nil {
+		panic(err)
+	}
+
+	// without request validation the id reaches the controller, which fails to parse it
+	response, body := negotiationRequest(handler, http.MethodGet, "/api/categories/abc", nil, "", "")
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	assert.NotContains(t, string(body), "parameter")
+}
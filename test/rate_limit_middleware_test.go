@@ -0,0 +1,147 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitTester(config middleware.RateLimitConfig) http.Handler {
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+	return middleware.NewRateLimitMiddleware(handler, config)
+}
+
+// sendRateLimitedRequest sends the request as the auth middleware would pass it on, with the
+// API key accepted as a principal of the acme tenant. Without a key the request is anonymous.
+func sendRateLimitedRequest(handler http.Handler, method string, path string, apiKey string) *http.Response {
+	request := httptest.NewRequest(method, "http://localhost"+path, nil)
+	if apiKey != "" {
+		request.Header.Set("X-API-Key", apiKey)
+		principal := middleware.Principal{Name: apiKey, Method: "api_key", TenantId: "acme"}
+		request = request.WithContext(tenant.WithID(middleware.WithPrincipal(request.Context(), principal), "acme"))
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Result()
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	handler := newRateLimitTester(middleware.RateLimitConfig{
+		Default: middleware.RateLimit{Rate: 0.001, Burst: 2},
+	})
+
+	for i := 0; i < 2; i++ {
+		response := sendRateLimitedRequest(handler, http.MethodGet, "/api/categories", "key-a")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "2", response.Header.Get("RateLimit-Limit"))
+	}
+
+	response := sendRateLimitedRequest(handler, http.MethodGet, "/api/categories", "key-a")
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, "0", response.Header.Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, response.Header.Get("Retry-After"))
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	var responseBody map[string]any
+	json.Unmarshal(body, &responseBody)
+
+	assert.Equal(t, http.StatusTooManyRequests, int(responseBody["code"].(float64)))
+	assert.Equal(t, "TOO MANY REQUESTS", responseBody["status"])
+
+	// other clients have their own bucket
+	response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories", "key-b")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestRateLimitPerRouteAndKey(t *testing.T) {
+	handler := newRateLimitTester(middleware.RateLimitConfig{
+		Default: middleware.RateLimit{Rate: 0.001, Burst: 5},
+		Routes: map[string]middleware.RateLimit{
+			"DELETE /api/categories/:categoryId": {Rate: 0.001, Burst: 1},
+		},
+		Keys: map[string]middleware.RateLimit{
+			"partner-key": {Rate: 0.001, Burst: 10},
+		},
+	})
+
+	response := sendRateLimitedRequest(handler, http.MethodDelete, "/api/categories/1", "key-a")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = sendRateLimitedRequest(handler, http.MethodDelete, "/api/categories/2", "key-a")
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+
+	// the route limit does not affect other routes
+	response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/1", "key-a")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	// the key limit replaces the default limit
+	for i := 0; i < 10; i++ {
+		response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories", "partner-key")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+	response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories", "partner-key")
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+}
+
+func TestRateLimitUnverifiedKeys(t *testing.T) {
+	handler := newRateLimitTester(middleware.RateLimitConfig{
+		Default: middleware.RateLimit{Rate: 0.001, Burst: 2},
+		Keys: map[string]middleware.RateLimit{
+			"partner-key": {Rate: 0.001, Burst: 10},
+		},
+	})
+
+	// keys nobody has checked do not get buckets of their own, the client IP is limited
+	for _, apiKey := range []string{"random-1", "random-2", "partner-key"} {
+		request := httptest.NewRequest(http.MethodGet, "http://localhost/openapi.json", nil)
+		request.Header.Set("X-API-Key", apiKey)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if apiKey == "partner-key" {
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			continue
+		}
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+}
+
+func TestRateLimitMostSpecificRoute(t *testing.T) {
+	routes := map[string]middleware.RateLimit{
+		"GET /api/categories/*path":           {Rate: 0.001, Burst: 5},
+		"GET /api/categories/:categoryId":     {Rate: 0.001, Burst: 1},
+		"GET /api/categories/by-slug/:slug":   {Rate: 0.001, Burst: 3},
+		"GET /api/categories/:categoryId/x/y": {Rate: 0.001, Burst: 4},
+		"GET /api/categories/tree":            {Rate: 0.001, Burst: 2},
+		"GET /api/categories/:categoryId/:x":  {Rate: 0.001, Burst: 6},
+		"GET /api/categories/by-slug/*path":   {Rate: 0.001, Burst: 7},
+	}
+
+	// maps are iterated in a different order every time, like httprouter a static segment
+	// beats a parameter and a parameter beats a catch-all, however long the patterns are
+	for i := 0; i < 20; i++ {
+		handler := newRateLimitTester(middleware.RateLimitConfig{Routes: routes})
+		response := sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/by-slug/phones", "key-a")
+		assert.Equal(t, "3", response.Header.Get("RateLimit-Limit"))
+		response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/1", "key-a")
+		assert.Equal(t, "1", response.Header.Get("RateLimit-Limit"))
+		response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/tree", "key-a")
+		assert.Equal(t, "2", response.Header.Get("RateLimit-Limit"))
+		response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/by-slug/phones/extra", "key-a")
+		assert.Equal(t, "7", response.Header.Get("RateLimit-Limit"))
+		response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/5/extra", "key-a")
+		assert.Equal(t, "6", response.Header.Get("RateLimit-Limit"))
+		response = sendRateLimitedRequest(handler, http.MethodGet, "/api/categories/1/x/y", "key-a")
+		assert.Equal(t, "4", response.Header.Get("RateLimit-Limit"))
+	}
+}
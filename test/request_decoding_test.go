@@ -0,0 +1,73 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestStrictRequestDecoding(t *testing.T) {
+	fake := newFakeCategoryService()
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+
+	for _, test := range []struct {
+		method      string
+		target      string
+		body        string
+		contentType string
+		statusCode  int
+		message     string
+	}{
+		{http.MethodPost, "/api/categories", `{"name":"Books","colour":"red"}`, "application/json", http.StatusBadRequest, `unknown field "colour"`},
+		{http.MethodPost, "/api/categories", `{"name":"Books"} {"name":"Games"}`, "application/json", http.StatusBadRequest, "request body must contain a single value, found trailing data"},
+		{http.MethodPost, "/api/categories", `{"name":"Books"}garbage`, "application/json", http.StatusBadRequest, "request body must contain a single value, found trailing data"},
+		{http.MethodPost, "/api/categories", `{"name":42}`, "application/json", http.StatusBadRequest, `field "name" must be a string, got number`},
+		{http.MethodPost, "/api/categories", `{"name":`, "application/json", http.StatusBadRequest, "request body is not valid JSON: unexpected end of input"},
+		{http.MethodPost, "/api/categories", ``, "application/json", http.StatusBadRequest, "request body is empty"},
+		{http.MethodPut, "/api/categories/1", `[]`, "application/json", http.StatusBadRequest, "request body must be a JSON object, got array"},
+		{http.MethodPost, "/api/categories", `{"name":"Books"}`, "", http.StatusUnsupportedMediaType, "the Content-Type of the request body is not supported"},
+		{http.MethodPost, "/api/categories", `{"name":"Books"}`, "text/plain", http.StatusUnsupportedMediaType, "the Content-Type of the request body is not supported"},
+	} {
+		response, body := negotiationRequest(router, test.method, test.target, strings.NewReader(test.body), test.contentType, "")
+		assert.Equal(t, test.statusCode, response.StatusCode, test.body)
+		assert.Contains(t, string(body), strings.ReplaceAll(test.message, `"`, `\"`), test.body)
+	}
+	assert.Empty(t, fake.categories)
+
+	// trailing whitespace is fine
+	response, _ := negotiationRequest(router, http.MethodPost, "/api/categories", strings.NewReader("{\"name\":\"Books\"}\n\n"), "application/json; charset=utf-8", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	// binary formats are strict too
+	msgpackBody, err := msgpack.Marshal(map[string]string{"name": "Games", "colour": "red"})
+	assert.Nil(t, err)
+	response, body := negotiationRequest(router, http.MethodPost, "/api/categories", bytes.NewReader(msgpackBody), "application/msgpack", "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Contains(t, string(body), "colour")
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	fake := newFakeCategoryService()
+	router := app.NewRouter(controller.NewCategoryController(fake), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	handler := middleware.NewBodyLimitMiddleware(router, 64)
+
+	response, body := negotiationRequest(handler, http.MethodPost, "/api/categories", strings.NewReader(`{"name":"`+strings.Repeat("a", 100)+`"}`), "application/json", "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+	assert.Contains(t, string(body), "request body must not be larger than 64 bytes")
+
+	// bodies without a declared length are cut off while reading
+	request := strings.NewReader(`{"name":"` + strings.Repeat("a", 100) + `"}`)
+	response, _ = negotiationRequest(handler, http.MethodPost, "/api/categories", struct{ *strings.Reader }{request}, "application/json", "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+	assert.Empty(t, fake.categories)
+
+	response, _ = negotiationRequest(handler, http.MethodPost, "/api/categories", strings.NewReader(`{"name":"Books"}`), "application/json", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
@@ -0,0 +1,232 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/slug"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeSlug(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Electronics":          "electronics",
+		"  Books & Magazines ": "books-magazines",
+		"Crème Brûlée":         "creme-brulee",
+		"Straße":               "strasse",
+		"Ærø Øl":               "aero-ol",
+		"Книги и журналы":      "knigi-i-zhurnaly",
+		"Ελληνικά":             "ellinika",
+		"日本の本":                 "日本の本",
+		"ﬁle №1":               "file-no1",
+		"!!!":                  "category",
+	} {
+		assert.Equal(t, expected, slug.Make(name), name)
+	}
+
+	long := slug.Make(strings.Repeat("ab ", 100))
+	assert.LessOrEqual(t, len([]rune(long)), slug.MaxLength)
+	assert.False(t, strings.HasSuffix(long, "-"))
+}
+
+func TestSlugsOfCreatedAndRenamedCategories(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT category_type, definition, updated_at ") {
+			return attributeSchemaColumns, nil, nil
+		}
+		if strings.HasPrefix(query, "SELECT slug ") {
+			// the LIKE also matches slugs that only start like the suffixed ones
+			return []string{"slug"}, [][]driver.Value{{"gadget"}, {"gadget-2"}, {"gadget-20"}}, nil
+		}
+		return categoryColumns, [][]driver.Value{categoryRow(1, "Gadget", "gadget")}, nil
+	}
+	var executed []string
+	var args [][]driver.NamedValue
+	fake.exec = func(query string, queryArgs []driver.NamedValue) (driver.Result, error) {
+		executed = append(executed, query)
+		args = append(args, queryArgs)
+		return fakeResult{lastInsertId: 4, rowsAffected: 1}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	// a taken slug gets the first free suffix
+	created, err := categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Gadget"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gadget-3", created.Slug)
+	assert.Equal(t, "gadget-3", args[0][2].Value)
+
+	// a slug asked for is normalized, and one that cannot be looked up is refused
+	created, err = categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Gadget", Slug: "Summer Sale!"})
+	assert.NoError(t, err)
+	assert.Equal(t, "summer-sale", created.Slug)
+	_, err = categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Translations", Slug: "translations"})
+	assert.Equal(t, exception.NewConflictError("category slug is reserved"), err)
+
+	// a new name with the same slug keeps it, rather than taking a suffix because of itself
+	executed, args = nil, nil
+	updated, err := categoryService.Update(ctx, web.CategoryUpdateRequest{Id: 1, Name: "GADGET!"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gadget", updated.Slug)
+	assert.Equal(t, []string{"UPDATE category SET name = ?, slug = ?, type = ?, attributes = ?, description = ?, image_url = ?, sort_order = ?, is_active = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"}, executed)
+
+	// a new slug leaves a redirect behind
+	executed, args = nil, nil
+	updated, err = categoryService.Update(ctx, web.CategoryUpdateRequest{Id: 1, Name: "Gadget", Slug: "gizmo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gizmo", updated.Slug)
+	assert.Equal(t, []string{
+		"INSERT INTO category_slug_redirect (tenant_id, slug, category_id) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE category_id = ?",
+		"UPDATE category SET name = ?, slug = ?, type = ?, attributes = ?, description = ?, image_url = ?, sort_order = ?, is_active = ?, updated_at = ? WHERE tenant_id = ? AND id = ?",
+	}, executed)
+	assert.Equal(t, []any{"acme", "gadget", int64(1), int64(1)}, []any{args[0][0].Value, args[0][1].Value, args[0][2].Value, args[0][3].Value})
+
+	// so does a rename
+	executed, args = nil, nil
+	updated, err = categoryService.Update(ctx, web.CategoryUpdateRequest{Id: 1, Name: "Gadgets"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gadgets", updated.Slug)
+	assert.Len(t, executed, 2)
+}
+
+func TestDuplicateSlugIsConflict(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = categoryRows("Gadget")
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		return nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'acme-gizmo' for key 'category.category_tenant_slug'"}
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+
+	_, err := categoryService.Create(tenant.WithID(context.Background(), "acme"), web.CategoryCreateRequest{Name: "Gizmo", Slug: "gizmo"})
+	assert.Equal(t, exception.NewConflictError("category slug already exists"), err)
+}
+
+func TestDerivedSlugTakenConcurrently(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = categoryRows("Gadget")
+	var slugs []any
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		slugs = append(slugs, args[2].Value)
+		// another request committed gizmo and gizmo-2, the snapshot of this one does not show them
+		if len(slugs) <= 2 {
+			return nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'acme-gizmo' for key 'category.category_tenant_slug'"}
+		}
+		return fakeResult{lastInsertId: 2, rowsAffected: 1}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+
+	created, err := categoryService.Create(tenant.WithID(context.Background(), "acme"), web.CategoryCreateRequest{Name: "Gizmo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gizmo-3", created.Slug)
+	assert.Equal(t, []any{"gizmo", "gizmo-2", "gizmo-3"}, slugs)
+
+	// the retries are bounded
+	slugs = nil
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		slugs = append(slugs, args[2].Value)
+		return nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'acme-gizmo' for key 'category.category_tenant_slug'"}
+	}
+	_, err = categoryService.Create(tenant.WithID(context.Background(), "acme"), web.CategoryCreateRequest{Name: "Gizmo"})
+	assert.Equal(t, exception.NewConflictError("category slug already exists"), err)
+	assert.Len(t, slugs, 5)
+}
+
+func TestFindCategoryBySlug(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		found := [][]driver.Value{categoryRow(1, "Électronique", "electronique")}
+		switch {
+		case strings.Contains(query, "category_slug_redirect") && args[2].Value == "electronics":
+			return categoryColumns, found, nil
+		case strings.HasSuffix(query, "slug = ?") && args[1].Value == "electronique", strings.HasSuffix(query, "id = ?"):
+			return categoryColumns, found, nil
+		}
+		return categoryColumns, nil, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	router := newResponseValidationTester(t, app.NewRouter(controller.NewCategoryController(categoryService), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil)))
+
+	send := func(target string) (*httptest.ResponseRecorder, map[string]any) {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request = request.WithContext(tenant.WithID(request.Context(), "acme"))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var body map[string]any
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder, body
+	}
+
+	recorder, body := send("/api/categories/by-slug/electronique")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "electronique", body["data"].(map[string]any)["slug"])
+
+	// an old slug and another spelling of the slug move permanently
+	for _, old := range []string{"electronics", "%C3%89lectronique"} {
+		recorder, body = send("/api/categories/by-slug/" + old)
+		assert.Equal(t, http.StatusMovedPermanently, recorder.Code, old)
+		assert.Equal(t, "/api/categories/by-slug/electronique", recorder.Header().Get("Location"), old)
+		assert.Equal(t, "MOVED PERMANENTLY", body["status"])
+	}
+
+	recorder, _ = send("/api/categories/by-slug/unknown")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// the lookups by slug leave the other routes alone
+	recorder, _ = send("/api/categories/1")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder, body = send("/api/nothing")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "route not found", body["data"])
+	recorder, body = send("/api/categories/1/nothing")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "NOT FOUND", body["status"])
+}
+
+func TestSlugRouterRecoversPanics(t *testing.T) {
+	// the controller has no service, the lookup panics
+	router := app.NewRouter(controller.NewCategoryController(nil), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	request := httptest.NewRequest(http.MethodGet, "/api/categories/by-slug/electronics", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"status":"INTERNAL SERVER ERROR"`)
+
+	request = httptest.NewRequest(http.MethodDelete, "/api/categories/by-slug/electronics", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"status":"METHOD NOT ALLOWED"`)
+}
+
+// TestRoutesBelowCategoryIdAreReserved guards the lookups by slug, a route below
+// /api/categories/:categoryId also matches /api/categories/by-slug/<its first segment>
+func TestRoutesBelowCategoryIdAreReserved(t *testing.T) {
+	routes := app.CategoryRoutes(controller.NewCategoryController(nil), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	children := 0
+	for _, route := range routes {
+		child, ok := strings.CutPrefix(route.Path, "/api/categories/:categoryId/")
+		if !ok {
+			continue
+		}
+		children++
+		segment, _, _ := strings.Cut(child, "/")
+		assert.Contains(t, slug.Reserved, segment, route.Path)
+	}
+	assert.NotZero(t, children)
+}
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/cache"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/rozanlaudzai/go-mysql-restful-api/exception"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/proto/categorypb"
+	"github.com/rozanlaudzai/go-mysql-restful-api/repository"
+	"github.com/rozanlaudzai/go-mysql-restful-api/rpc"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/rozanlaudzai/go-mysql-restful-api/tenant"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const jwtSecret = "0123456789abcdef0123456789abcdef"
+
+// signJWT signs claims with HS256, the way a gateway in front of the API would
+func signJWT(secret string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRepositoryRequiresTenant(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.query = categoryRows("Gadget")
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+
+	_, err := categoryService.FindAll(context.Background(), web.CategoryListRequest{})
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+	_, err = categoryService.FindById(context.Background(), 1)
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+	_, err = categoryService.Create(context.Background(), web.CategoryCreateRequest{Name: "Gadget"})
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+
+	// the repository fails closed, nothing reaches the database
+	for _, event := range fake.Events() {
+		assert.False(t, strings.HasPrefix(event, "query") || strings.HasPrefix(event, "exec"), event)
+	}
+
+	// and the client is told it left the tenant out
+	recorder := httptest.NewRecorder()
+	exception.ErrorHandler(recorder, httptest.NewRequest(http.MethodGet, "/api/categories", nil), err)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "a tenant is required")
+	assert.Equal(t, codes.InvalidArgument, status.Code(rpc.Status(err)))
+}
+
+func TestRepositoryScopesQueriesToTenant(t *testing.T) {
+	fake, db := newFakeDB()
+	var queried [][]driver.NamedValue
+	rows := [][]driver.Value{}
+	fake.query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		queried = append(queried, args)
+		if strings.HasPrefix(query, "SELECT category_type, definition, updated_at ") {
+			return attributeSchemaColumns, nil, nil
+		}
+		if strings.HasPrefix(query, "SELECT slug ") {
+			return []string{"slug"}, nil, nil
+		}
+		return categoryColumns, rows, nil
+	}
+	var executed [][]driver.NamedValue
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		executed = append(executed, args)
+		return fakeResult{lastInsertId: 1, rowsAffected: 0}, nil
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	// a category of another tenant is not found, whatever the id
+	_, err := categoryService.FindById(ctx, 1)
+	assert.IsType(t, exception.NotFoundError{}, err)
+	assert.Equal(t, []any{"acme", int64(1)}, []any{queried[0][0].Value, queried[0][1].Value})
+
+	// nor changed, should it be read in between
+	rows = [][]driver.Value{categoryRow(1, "Gadget", "gadget")}
+	_, err = categoryService.Update(ctx, web.CategoryUpdateRequest{Id: 1, Name: "Gadgets"})
+	assert.IsType(t, exception.NotFoundError{}, err)
+	assert.IsType(t, exception.NotFoundError{}, categoryService.DeleteById(ctx, 1))
+	_, err = categoryService.Create(ctx, web.CategoryCreateRequest{Name: "Gadget"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []any{"acme", "gadget", int64(1)}, []any{executed[0][0].Value, executed[0][1].Value, executed[0][2].Value})
+	assert.Equal(t, []any{"Gadgets", "gadgets", "acme", int64(1)}, []any{executed[1][0].Value, executed[1][1].Value, executed[1][9].Value, executed[1][10].Value})
+	assert.Equal(t, []any{"acme", int64(1)}, []any{executed[2][0].Value, executed[2][1].Value})
+	assert.Equal(t, []any{"acme", "Gadget", "gadget"}, []any{executed[3][0].Value, executed[3][1].Value, executed[3][2].Value})
+	assert.Contains(t, fake.Events(), "query SELECT slug FROM category WHERE tenant_id = ? AND (slug = ? OR slug LIKE ?)")
+	assert.Contains(t, fake.Events(), "exec INSERT INTO category_slug_redirect (tenant_id, slug, category_id) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE category_id = ?")
+	assert.Contains(t, fake.Events(), "exec UPDATE category SET name = ?, slug = ?, type = ?, attributes = ?, description = ?, image_url = ?, sort_order = ?, is_active = ?, updated_at = ? WHERE tenant_id = ? AND id = ?")
+	assert.Contains(t, fake.Events(), "exec DELETE FROM category WHERE tenant_id = ? AND id = ?")
+	assert.Contains(t, fake.Events(), "exec INSERT INTO category (tenant_id, name, slug, type, attributes, description, image_url, sort_order, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+}
+
+func TestDuplicateNameIsConflict(t *testing.T) {
+	fake, db := newFakeDB()
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		return nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'acme-Gadget' for key 'category_tenant_name'"}
+	}
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(), repository.NewCategoryAttributeSchemaRepository(), database.NewTransactionManager(db, nil), validator.New())
+
+	_, err := categoryService.Create(tenant.WithID(context.Background(), "acme"), web.CategoryCreateRequest{Name: "Gadget"})
+	assert.Equal(t, exception.NewConflictError("category name already exists"), err)
+
+	recorder := httptest.NewRecorder()
+	exception.ErrorHandler(recorder, httptest.NewRequest(http.MethodPost, "/api/categories", nil), err)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "category name already exists")
+}
+
+func TestAuthMiddlewareTenants(t *testing.T) {
+	var principal middleware.Principal
+	var tenantId string
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		principal, _ = middleware.PrincipalFromContext(request.Context())
+		tenantId, _ = tenant.FromContext(request.Context())
+	})
+	authMiddleware := middleware.NewAuthMiddleware(handler, "RAHASIA")
+	authMiddleware.Keys = stubAPIKeys{keys: map[string]web.APIKeyResponse{"acme-key": {Name: "acme-bot", TenantId: "acme"}}}
+	authMiddleware.JWT = middleware.NewJWTVerifier(jwtSecret, "tenant_id")
+
+	send := func(headers map[string]string) int {
+		principal, tenantId = middleware.Principal{}, ""
+		request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		authMiddleware.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// the master key names any tenant, the default one when it names none
+	assert.Equal(t, http.StatusOK, send(map[string]string{"X-API-Key": "RAHASIA"}))
+	assert.Equal(t, "default", tenantId)
+	assert.Equal(t, http.StatusOK, send(map[string]string{"X-API-Key": "RAHASIA", "X-Tenant-ID": "globex"}))
+	assert.Equal(t, "globex", tenantId)
+	assert.Equal(t, http.StatusBadRequest, send(map[string]string{"X-API-Key": "RAHASIA", "X-Tenant-ID": "../globex"}))
+
+	// a key tied to a tenant only reaches that tenant
+	assert.Equal(t, http.StatusOK, send(map[string]string{"X-API-Key": "acme-key"}))
+	assert.Equal(t, "acme", tenantId)
+	assert.Equal(t, "acme", principal.TenantId)
+	assert.Equal(t, http.StatusOK, send(map[string]string{"X-API-Key": "acme-key", "X-Tenant-ID": "acme"}))
+	assert.Equal(t, http.StatusForbidden, send(map[string]string{"X-API-Key": "acme-key", "X-Tenant-ID": "globex"}))
+	assert.Empty(t, tenantId)
+
+	// so does a token, through its tenant claim
+	token := signJWT(jwtSecret, map[string]any{"sub": "alice", "tenant_id": "acme", "exp": time.Now().Add(time.Hour).Unix()})
+	assert.Equal(t, http.StatusOK, send(map[string]string{"Authorization": "Bearer " + token}))
+	assert.Equal(t, middleware.Principal{Name: "alice", Method: "jwt", TenantId: "acme"}, principal)
+	assert.Equal(t, http.StatusForbidden, send(map[string]string{"Authorization": "Bearer " + token, "X-Tenant-ID": "globex"}))
+
+	for _, token := range []string{
+		signJWT("another secret of at least 32 bytes", map[string]any{"sub": "alice", "tenant_id": "acme"}),
+		signJWT(jwtSecret, map[string]any{"sub": "alice", "tenant_id": "acme", "exp": time.Now().Add(-time.Hour).Unix()}),
+		signJWT(jwtSecret, map[string]any{"sub": "alice"}),
+		strings.Replace(token, token[:strings.Index(token, ".")], base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)), 1),
+		"not.a.token",
+	} {
+		assert.Equal(t, http.StatusUnauthorized, send(map[string]string{"Authorization": "Bearer " + token}), token)
+	}
+
+	// without a default tenant it has to be named
+	authMiddleware.DefaultTenant = ""
+	assert.Equal(t, http.StatusBadRequest, send(map[string]string{"X-API-Key": "RAHASIA"}))
+	assert.Equal(t, http.StatusOK, send(map[string]string{"X-API-Key": "RAHASIA", "X-Tenant-ID": "acme"}))
+}
+
+func TestCategoryServiceCacheIsPerTenant(t *testing.T) {
+	fake := newFakeCategoryService()
+	categoryService := service.NewCategoryServiceCache(fake, cache.NewLRUCache(100), time.Minute, &cache.Stats{})
+	acme := tenant.WithID(context.Background(), "acme")
+	globex := tenant.WithID(context.Background(), "globex")
+
+	created, err := categoryService.Create(acme, web.CategoryCreateRequest{Name: "Gadget"})
+	assert.NoError(t, err)
+	categoryService.FindById(acme, created.Id)
+	categoryService.FindAll(acme, web.CategoryListRequest{})
+	assert.Equal(t, int64(2), fake.reads.Load())
+
+	// the entries of acme are not served to globex
+	categoryService.FindById(globex, created.Id)
+	categoryService.FindAll(globex, web.CategoryListRequest{})
+	assert.Equal(t, int64(4), fake.reads.Load())
+
+	// and a write of globex leaves them cached
+	_, err = categoryService.Create(globex, web.CategoryCreateRequest{Name: "Book"})
+	assert.NoError(t, err)
+	categoryService.FindById(acme, created.Id)
+	categoryService.FindAll(acme, web.CategoryListRequest{})
+	assert.Equal(t, int64(4), fake.reads.Load())
+}
+
+func TestGRPCTenants(t *testing.T) {
+	categoryClient, _ := newGRPCTester(t)
+	acme := metadata.AppendToOutgoingContext(grpcContext("RAHASIA"), "x-tenant-id", "acme")
+
+	_, err := categoryClient.Get(metadata.AppendToOutgoingContext(grpcContext("RAHASIA"), "x-tenant-id", "Not Valid"), &categorypb.GetCategoryRequest{Id: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// a watcher only gets the events of its tenant
+	ctx, cancel := context.WithTimeout(acme, 5*time.Second)
+	defer cancel()
+	stream, err := categoryClient.Watch(ctx, &categorypb.WatchCategoriesRequest{})
+	assert.NoError(t, err)
+
+	_, err = categoryClient.Create(grpcContext("RAHASIA"), &categorypb.CreateCategoryRequest{Name: "Default"})
+	assert.NoError(t, err)
+	_, err = categoryClient.Create(acme, &categorypb.CreateCategoryRequest{Name: "Acme"})
+	assert.NoError(t, err)
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", event.GetCategory().GetName())
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/controller"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/rozanlaudzai/go-mysql-restful-api/model/web"
+	"github.com/rozanlaudzai/go-mysql-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowCategoryService blocks like a query that never returns until its context is done
+type slowCategoryService struct {
+	service.CategoryService
+}
+
+func (slowCategoryService) FindAll(ctx context.Context, request web.CategoryListRequest) ([]web.CategoryResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestDeadlineExceeded(t *testing.T) {
+	router := app.NewRouter(controller.NewCategoryController(slowCategoryService{}), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+	handler := middleware.NewTimeoutMiddleware(router, middleware.TimeoutConfig{
+		Default: time.Minute,
+		Routes: map[string]time.Duration{
+			"GET /api/categories": 20 * time.Millisecond,
+		},
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost/api/categories", nil)
+	recorder := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(recorder, request)
+	assert.Less(t, time.Since(start), time.Second)
+
+	response := recorder.Result()
+	assert.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	var responseBody map[string]any
+	json.Unmarshal(body, &responseBody)
+
+	assert.Equal(t, http.StatusGatewayTimeout, int(responseBody["code"].(float64)))
+	assert.Equal(t, "GATEWAY TIMEOUT", responseBody["status"])
+}
+
+func TestRequestCancelled(t *testing.T) {
+	router := app.NewRouter(controller.NewCategoryController(slowCategoryService{}), controller.NewCategoryTranslationController(nil), controller.NewCategoryAttributeSchemaController(nil))
+
+	// the client went away before the query finished
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := httptest.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/api/categories", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Result().StatusCode)
+}
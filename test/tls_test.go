@@ -0,0 +1,180 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rozanlaudzai/go-mysql-restful-api/app"
+	"github.com/rozanlaudzai/go-mysql-restful-api/config"
+	"github.com/rozanlaudzai/go-mysql-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPEM     []byte
+	keyPEM      []byte
+}
+
+func newTestCertificate(commonName string, serial int64, parent *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	// without a parent the certificate is a self signed CA
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestCertificate(certificate testCertificate, certFile string, keyFile string, modTime time.Time) {
+	if err := os.WriteFile(certFile, certificate.certPEM, 0o600); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(keyFile, certificate.keyPEM, 0o600); err != nil {
+		panic(err)
+	}
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestMutualTLSPrincipalAndReload(t *testing.T) {
+	directory := t.TempDir()
+	certFile := filepath.Join(directory, "server.crt")
+	keyFile := filepath.Join(directory, "server.key")
+	caFile := filepath.Join(directory, "ca.crt")
+
+	ca := newTestCertificate("test-ca", 1, nil)
+	serverCertificate := newTestCertificate("localhost", 2, &ca)
+	clientCertificate := newTestCertificate("orders.internal", 3, &ca)
+	writeTestCertificate(serverCertificate, certFile, keyFile, time.Now().Add(-time.Minute))
+	if err := os.WriteFile(caFile, ca.certPEM, 0o600); err != nil {
+		panic(err)
+	}
+
+	reloader, err := app.NewCertificateReloader(certFile, keyFile)
+	assert.Nil(t, err)
+	tlsConfig, err := app.NewTLSConfig(config.TLSConfig{
+		ClientCAFile: caFile,
+		ClientAuth:   "verify_if_given",
+	}, reloader)
+	assert.Nil(t, err)
+
+	// echo the principal that authenticated the request
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		principal, _ := middleware.PrincipalFromContext(request.Context())
+		writer.Write([]byte(principal.Method + ":" + principal.Name))
+	})
+	authMiddleware := middleware.NewAuthMiddleware(handler, "test-api-key")
+	authMiddleware.ClientPrincipals = map[string]string{"orders.internal": "orders-service"}
+
+	server := httptest.NewUnstartedServer(authMiddleware)
+	server.TLS = tlsConfig
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	// httptest adds its own certificate, which is only skipped when SNI is sent
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.certificate)
+	clientKeyPair, err := tls.X509KeyPair(clientCertificate.certPEM, clientCertificate.keyPEM)
+	if err != nil {
+		panic(err)
+	}
+	newClient := func(certificates []tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certificates},
+			ForceAttemptHTTP2: true,
+		}}
+	}
+
+	// client certificate
+	response, err := newClient([]tls.Certificate{clientKeyPair}).Get(url)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "client_certificate:orders-service", string(body))
+	assert.Equal(t, 2, response.ProtoMajor)
+	assert.Equal(t, big.NewInt(2), response.TLS.PeerCertificates[0].SerialNumber)
+
+	// no client certificate and no API key
+	response, err = newClient(nil).Get(url)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	// the renewed certificate is served after a reload
+	renewedCertificate := newTestCertificate("localhost", 4, &ca)
+	writeTestCertificate(renewedCertificate, certFile, keyFile, time.Now())
+	reloaded, err := reloader.Reload()
+	assert.Nil(t, err)
+	assert.True(t, reloaded)
+
+	response, err = newClient([]tls.Certificate{clientKeyPair}).Get(url)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, big.NewInt(4), response.TLS.PeerCertificates[0].SerialNumber)
+
+	// nothing changed since the last reload
+	reloaded, err = reloader.Reload()
+	assert.Nil(t, err)
+	assert.False(t, reloaded)
+}
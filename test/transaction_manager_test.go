@@ -0,0 +1,141 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rozanlaudzai/go-mysql-restful-api/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionCommitsAndRollsBack(t *testing.T) {
+	fake, db := newFakeDB()
+	transactionManager := database.NewTransactionManager(db, nil)
+
+	err := transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM category")
+		return err
+	})
+	assert.Nil(t, err)
+
+	failure := errors.New("failure")
+	err = transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	assert.Equal(t, []string{"begin", "exec DELETE FROM category", "commit", "begin", "rollback"}, fake.Events())
+}
+
+func TestTransactionRollsBackOnPanic(t *testing.T) {
+	fake, db := newFakeDB()
+	transactionManager := database.NewTransactionManager(db, nil)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"begin", "rollback"}, fake.Events())
+}
+
+func TestTransactionRetriesDeadlocks(t *testing.T) {
+	fake, db := newFakeDB()
+	deadlocks := 2
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		if deadlocks > 0 {
+			deadlocks--
+			return nil, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		}
+		return driver.RowsAffected(1), nil
+	}
+	transactionManager := database.NewTransactionManager(db, nil)
+	transactionManager.RetryBackoff = time.Millisecond
+
+	attempts := 0
+	err := transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		attempts++
+		_, err := tx.ExecContext(ctx, "UPDATE category SET name = ? WHERE id = ?", "Books", 1)
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, "commit", fake.Events()[len(fake.Events())-1])
+
+	// lock wait timeouts are retried too, until the retries run out
+	fake.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		return nil, &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+	}
+	transactionManager.MaxRetries = 1
+	attempts = 0
+	err = transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		attempts++
+		_, err := tx.ExecContext(ctx, "UPDATE category SET name = ? WHERE id = ?", "Books", 1)
+		return err
+	})
+	assert.True(t, database.IsRetryable(err))
+	assert.Equal(t, 2, attempts)
+
+	// other errors are returned right away
+	attempts = 0
+	err = transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		attempts++
+		return &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	})
+	assert.False(t, database.IsRetryable(err))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestNestedTransactionsUseSavepoints(t *testing.T) {
+	fake, db := newFakeDB()
+	transactionManager := database.NewTransactionManager(db, nil)
+
+	err := transactionManager.Do(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		err := transactionManager.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM category WHERE id = ?", 1)
+			return err
+		})
+		assert.Nil(t, err)
+
+		// a failing inner call only undoes its own work
+		err = transactionManager.Do(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return errors.New("failure")
+		})
+		assert.NotNil(t, err)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{
+		"begin",
+		"exec SAVEPOINT sp_1", "exec DELETE FROM category WHERE id = ?", "exec RELEASE SAVEPOINT sp_1",
+		"exec SAVEPOINT sp_2", "exec ROLLBACK TO SAVEPOINT sp_2",
+		"commit",
+	}, fake.Events())
+}
+
+func TestReadOnlyTransactions(t *testing.T) {
+	fake, db := newFakeDB()
+	transactionManager := database.NewTransactionManager(db, nil)
+	transactionManager.Isolation = sql.LevelSerializable
+
+	err := transactionManager.Read(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		// reads can nest inside reads, writes cannot
+		assert.Nil(t, transactionManager.Read(ctx, func(ctx context.Context, tx *sql.Tx) error { return nil }))
+		return transactionManager.Do(ctx, func(ctx context.Context, tx *sql.Tx) error { return nil })
+	})
+	assert.ErrorIs(t, err, database.ErrReadOnlyTransaction)
+
+	events := fake.Events()
+	assert.Equal(t, "begin read-only serializable", events[0])
+	assert.Equal(t, "rollback", events[len(events)-1])
+	for _, event := range events {
+		assert.False(t, strings.HasPrefix(event, "commit"))
+	}
+}